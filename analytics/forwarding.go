@@ -0,0 +1,537 @@
+// Package analytics aggregates the forwarding history of an lnd node. It only
+// depends on the lightning client of lndclient.
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/lightninglabs/lndclient"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// FlowDirection describes in which direction funds mostly moved through a
+// channel or peer.
+type FlowDirection string
+
+const (
+	// FlowNone is set if nothing was forwarded.
+	FlowNone FlowDirection = "none"
+
+	// FlowInbound is set if more funds arrived than left.
+	FlowInbound FlowDirection = "inbound"
+
+	// FlowOutbound is set if more funds left than arrived.
+	FlowOutbound FlowDirection = "outbound"
+
+	// FlowBalanced is set if as many funds arrived as left.
+	FlowBalanced FlowDirection = "balanced"
+)
+
+// ForwardingAnalyticsRequest holds the bounds of a forwarding analysis.
+type ForwardingAnalyticsRequest struct {
+	// StartTime is the start of the analysis. If zero, the analysis
+	// starts with the first forward.
+	StartTime time.Time
+
+	// EndTime is the end of the analysis. If zero, the current time is
+	// used.
+	EndTime time.Time
+
+	// Interval splits the analysis into consecutive windows of this
+	// length, the last of which may be shorter. If zero, a single window
+	// is used. A start time is required if an interval is set.
+	Interval time.Duration
+}
+
+// FlowStats holds the forwards of a channel or peer.
+type FlowStats struct {
+	// ForwardsIn is the number of forwards that arrived.
+	ForwardsIn uint64
+
+	// ForwardsOut is the number of forwards that left.
+	ForwardsOut uint64
+
+	// VolumeIn is the amount that arrived.
+	VolumeIn lnwire.MilliSatoshi
+
+	// VolumeOut is the amount that left.
+	VolumeOut lnwire.MilliSatoshi
+
+	// FeesEarned is the fees earned by forwards that left, as the fee is
+	// charged by the outgoing channel's policy.
+	FeesEarned lnwire.MilliSatoshi
+}
+
+// Direction returns the direction funds mostly moved in.
+func (f *FlowStats) Direction() FlowDirection {
+	switch {
+	case f.ForwardsIn == 0 && f.ForwardsOut == 0:
+		return FlowNone
+
+	case f.VolumeIn > f.VolumeOut:
+		return FlowInbound
+
+	case f.VolumeOut > f.VolumeIn:
+		return FlowOutbound
+
+	default:
+		return FlowBalanced
+	}
+}
+
+// add adds the incoming or outgoing side of a forward to the stats.
+func (f *FlowStats) add(event *lndclient.ForwardingEvent, outgoing bool) {
+	if outgoing {
+		f.ForwardsOut++
+		f.VolumeOut += event.AmountMsatOut
+		f.FeesEarned += event.FeeMsat
+		return
+	}
+
+	f.ForwardsIn++
+	f.VolumeIn += event.AmountMsatIn
+}
+
+// ChannelFlow holds the forwards of a single channel.
+type ChannelFlow struct {
+	FlowStats
+
+	// ChannelID is the short channel id of the channel.
+	ChannelID uint64
+
+	// Peer is the remote node of the channel. It is zero if the channel
+	// is neither open nor closed, for example while it is closing.
+	Peer route.Vertex
+
+	// PeerAlias is the alias of the remote node, if known.
+	PeerAlias string
+
+	// Closed is set if the channel was closed.
+	Closed bool
+}
+
+// PeerFlow holds the forwards of all channels with a peer.
+type PeerFlow struct {
+	FlowStats
+
+	// Peer is the remote node.
+	Peer route.Vertex
+
+	// PeerAlias is the alias of the remote node, if known.
+	PeerAlias string
+
+	// Channels is the set of channels with the peer that forwarded.
+	Channels []uint64
+}
+
+// ForwardingWindow holds the forwards of a single time window.
+type ForwardingWindow struct {
+	// StartTime is the start of the window.
+	StartTime time.Time
+
+	// EndTime is the end of the window.
+	EndTime time.Time
+
+	// Total holds the forwards of all channels. Every forward is counted
+	// once in each direction.
+	Total FlowStats
+
+	// Channels holds the forwards per channel, sorted by channel id.
+	Channels []*ChannelFlow
+
+	// Peers holds the forwards per peer, sorted by public key.
+	Peers []*PeerFlow
+}
+
+// ForwardingAnalytics holds the result of a forwarding analysis.
+type ForwardingAnalytics struct {
+	// Windows holds the analysis of every time window in order.
+	Windows []*ForwardingWindow
+}
+
+// channelPeer is the remote node of a channel.
+type channelPeer struct {
+	peer   route.Vertex
+	alias  string
+	closed bool
+}
+
+// AnalyzeForwards aggregates our forwarding history per channel and per peer.
+// Channels are joined with our open and closed channels to find their peers,
+// whose aliases are looked up in the graph.
+func AnalyzeForwards(ctx context.Context, lnd lndclient.LightningClient,
+	req ForwardingAnalyticsRequest) (*ForwardingAnalytics, error) {
+
+	if req.Interval < 0 {
+		return nil, errors.New("interval must not be negative")
+	}
+	if req.Interval > 0 && req.StartTime.IsZero() {
+		return nil, errors.New("interval requires a start time")
+	}
+
+	endTime := req.EndTime
+	if endTime.IsZero() {
+		endTime = time.Now()
+	}
+	if !req.StartTime.IsZero() && !req.StartTime.Before(endTime) {
+		return nil, errors.New("start time must be before end time")
+	}
+
+	peers, err := channelPeers(ctx, lnd)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []lndclient.ForwardingEvent
+	iter := lndclient.NewForwardingIterator(lnd, lndclient.HistoryQuery{
+		StartTime: req.StartTime,
+		EndTime:   endTime,
+	})
+	for iter.Next(ctx) {
+		events = append(events, iter.Event())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	startTime := req.StartTime
+	if startTime.IsZero() {
+		startTime = endTime
+		if len(events) > 0 {
+			startTime = events[0].Timestamp
+		}
+	}
+
+	// Create all windows up front, so that windows without forwards are
+	// reported too.
+	windows := []*forwardingWindow{newForwardingWindow(startTime, endTime)}
+	if req.Interval > 0 {
+		windows = windows[:0]
+		for start := startTime; start.Before(endTime); {
+			end := start.Add(req.Interval)
+			if end.After(endTime) {
+				end = endTime
+			}
+
+			windows = append(
+				windows, newForwardingWindow(start, end),
+			)
+			start = end
+		}
+	}
+
+	for i := range events {
+		event := &events[i]
+
+		idx := 0
+		if req.Interval > 0 {
+			idx = int(event.Timestamp.Sub(startTime) / req.Interval)
+
+			// lnd bounds events by the second, so events at the
+			// edges are added to the outer windows.
+			switch {
+			case idx < 0:
+				idx = 0
+
+			case idx >= len(windows):
+				idx = len(windows) - 1
+			}
+		}
+
+		windows[idx].add(event, peers)
+	}
+
+	analytics := &ForwardingAnalytics{
+		Windows: make([]*ForwardingWindow, len(windows)),
+	}
+	for i, window := range windows {
+		analytics.Windows[i] = window.finalize()
+	}
+
+	return analytics, nil
+}
+
+// channelPeers returns the peers of all our open and closed channels along
+// with their aliases.
+func channelPeers(ctx context.Context,
+	lnd lndclient.LightningClient) (map[uint64]*channelPeer, error) {
+
+	open, err := lnd.ListChannels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	closed, err := lnd.ClosedChannels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	peers := make(map[uint64]*channelPeer, len(open)+len(closed))
+	for _, channel := range closed {
+		peers[channel.ChannelID] = &channelPeer{
+			peer:   channel.PubKeyBytes,
+			closed: true,
+		}
+	}
+	for _, channel := range open {
+		peers[channel.ChannelID] = &channelPeer{
+			peer: channel.PubKeyBytes,
+		}
+	}
+
+	// Look up every alias once. Nodes that aren't in our graph anymore
+	// are left without an alias.
+	aliases := make(map[route.Vertex]string)
+	for _, peer := range peers {
+		alias, ok := aliases[peer.peer]
+		if !ok {
+			info, err := lnd.GetNodeInfo(ctx, peer.peer, false)
+			switch {
+			case err != nil:
+				log.Debugf("Unable to look up alias of %v: %v",
+					peer.peer, err)
+
+			case info.Node != nil:
+				alias = info.Alias
+			}
+			aliases[peer.peer] = alias
+		}
+
+		peer.alias = alias
+	}
+
+	return peers, nil
+}
+
+// forwardingWindow aggregates the forwards of a window.
+type forwardingWindow struct {
+	window   *ForwardingWindow
+	channels map[uint64]*ChannelFlow
+	peers    map[route.Vertex]*PeerFlow
+}
+
+// newForwardingWindow creates an empty window.
+func newForwardingWindow(start, end time.Time) *forwardingWindow {
+	return &forwardingWindow{
+		window: &ForwardingWindow{
+			StartTime: start,
+			EndTime:   end,
+		},
+		channels: make(map[uint64]*ChannelFlow),
+		peers:    make(map[route.Vertex]*PeerFlow),
+	}
+}
+
+// add adds a forward to the window.
+func (w *forwardingWindow) add(event *lndclient.ForwardingEvent,
+	peers map[uint64]*channelPeer) {
+
+	w.window.Total.add(event, false)
+	w.window.Total.add(event, true)
+
+	w.addChannel(event, event.ChannelIn, false, peers)
+	w.addChannel(event, event.ChannelOut, true, peers)
+}
+
+// addChannel adds one side of a forward to its channel and peer.
+func (w *forwardingWindow) addChannel(event *lndclient.ForwardingEvent,
+	chanID uint64, outgoing bool, peers map[uint64]*channelPeer) {
+
+	channel, ok := w.channels[chanID]
+	if !ok {
+		channel = &ChannelFlow{ChannelID: chanID}
+		if peer, ok := peers[chanID]; ok {
+			channel.Peer = peer.peer
+			channel.PeerAlias = peer.alias
+			channel.Closed = peer.closed
+		}
+		w.channels[chanID] = channel
+	}
+	channel.add(event, outgoing)
+
+	// Forwards of unknown channels can't be attributed to a peer.
+	if _, ok := peers[chanID]; !ok {
+		return
+	}
+
+	peer, ok := w.peers[channel.Peer]
+	if !ok {
+		peer = &PeerFlow{
+			Peer:      channel.Peer,
+			PeerAlias: channel.PeerAlias,
+		}
+		w.peers[channel.Peer] = peer
+	}
+	peer.add(event, outgoing)
+
+	for _, id := range peer.Channels {
+		if id == chanID {
+			return
+		}
+	}
+	peer.Channels = append(peer.Channels, chanID)
+}
+
+// finalize returns the window with its channels and peers sorted.
+func (w *forwardingWindow) finalize() *ForwardingWindow {
+	for _, channel := range w.channels {
+		w.window.Channels = append(w.window.Channels, channel)
+	}
+	sort.Slice(w.window.Channels, func(i, j int) bool {
+		return w.window.Channels[i].ChannelID <
+			w.window.Channels[j].ChannelID
+	})
+
+	for _, peer := range w.peers {
+		sort.Slice(peer.Channels, func(i, j int) bool {
+			return peer.Channels[i] < peer.Channels[j]
+		})
+		w.window.Peers = append(w.window.Peers, peer)
+	}
+	sort.Slice(w.window.Peers, func(i, j int) bool {
+		return bytes.Compare(
+			w.window.Peers[i].Peer[:], w.window.Peers[j].Peer[:],
+		) < 0
+	})
+
+	return w.window
+}
+
+// forwardingRecord is a single row of an exported analysis.
+type forwardingRecord struct {
+	WindowStart string        `json:"window_start"`
+	WindowEnd   string        `json:"window_end"`
+	Kind        string        `json:"kind"`
+	ChannelID   uint64        `json:"chan_id,omitempty"`
+	Peer        string        `json:"peer,omitempty"`
+	PeerAlias   string        `json:"peer_alias,omitempty"`
+	Closed      bool          `json:"closed,omitempty"`
+	ForwardsIn  uint64        `json:"forwards_in"`
+	ForwardsOut uint64        `json:"forwards_out"`
+	VolumeIn    uint64        `json:"volume_in_msat"`
+	VolumeOut   uint64        `json:"volume_out_msat"`
+	FeesEarned  uint64        `json:"fees_earned_msat"`
+	Direction   FlowDirection `json:"direction"`
+}
+
+// forwardingCSVHeader is the header of exported CSV files.
+var forwardingCSVHeader = []string{
+	"window_start", "window_end", "kind", "chan_id", "peer", "peer_alias",
+	"closed", "forwards_in", "forwards_out", "volume_in_msat",
+	"volume_out_msat", "fees_earned_msat", "direction",
+}
+
+// newForwardingRecord creates a record from the stats provided.
+func newForwardingRecord(window *ForwardingWindow, kind string,
+	stats *FlowStats) *forwardingRecord {
+
+	return &forwardingRecord{
+		WindowStart: window.StartTime.UTC().Format(time.RFC3339),
+		WindowEnd:   window.EndTime.UTC().Format(time.RFC3339),
+		Kind:        kind,
+		ForwardsIn:  stats.ForwardsIn,
+		ForwardsOut: stats.ForwardsOut,
+		VolumeIn:    uint64(stats.VolumeIn),
+		VolumeOut:   uint64(stats.VolumeOut),
+		FeesEarned:  uint64(stats.FeesEarned),
+		Direction:   stats.Direction(),
+	}
+}
+
+// records flattens the analysis into one record per window total, channel
+// and peer.
+func (f *ForwardingAnalytics) records() []*forwardingRecord {
+	var records []*forwardingRecord
+	for _, window := range f.Windows {
+		records = append(records, newForwardingRecord(
+			window, "total", &window.Total,
+		))
+
+		for _, channel := range window.Channels {
+			record := newForwardingRecord(
+				window, "channel", &channel.FlowStats,
+			)
+			record.ChannelID = channel.ChannelID
+			if channel.Peer != (route.Vertex{}) {
+				record.Peer = channel.Peer.String()
+			}
+			record.PeerAlias = channel.PeerAlias
+			record.Closed = channel.Closed
+
+			records = append(records, record)
+		}
+
+		for _, peer := range window.Peers {
+			record := newForwardingRecord(
+				window, "peer", &peer.FlowStats,
+			)
+			record.Peer = peer.Peer.String()
+			record.PeerAlias = peer.PeerAlias
+
+			records = append(records, record)
+		}
+	}
+
+	return records
+}
+
+// WriteJSON writes the analysis as a JSON array with one object per window
+// total, channel and peer.
+func (f *ForwardingAnalytics) WriteJSON(w io.Writer) error {
+	records := f.records()
+	if records == nil {
+		records = []*forwardingRecord{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(records)
+}
+
+// WriteCSV writes the analysis as CSV with one row per window total, channel
+// and peer.
+func (f *ForwardingAnalytics) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(forwardingCSVHeader); err != nil {
+		return err
+	}
+
+	for _, record := range f.records() {
+		var chanID string
+		if record.ChannelID != 0 {
+			chanID = strconv.FormatUint(record.ChannelID, 10)
+		}
+
+		err := writer.Write([]string{
+			record.WindowStart,
+			record.WindowEnd,
+			record.Kind,
+			chanID,
+			record.Peer,
+			record.PeerAlias,
+			strconv.FormatBool(record.Closed),
+			strconv.FormatUint(record.ForwardsIn, 10),
+			strconv.FormatUint(record.ForwardsOut, 10),
+			strconv.FormatUint(record.VolumeIn, 10),
+			strconv.FormatUint(record.VolumeOut, 10),
+			strconv.FormatUint(record.FeesEarned, 10),
+			string(record.Direction),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,185 @@
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lightninglabs/lndclient"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// mockLightning is a lightning client that serves the channels, nodes and
+// forwards it is configured with.
+type mockLightning struct {
+	lndclient.LightningClient
+
+	channels []lndclient.ChannelInfo
+	closed   []lndclient.ClosedChannel
+	forwards []lndclient.ForwardingEvent
+	nodes    map[route.Vertex]*lndclient.NodeInfo
+}
+
+func (m *mockLightning) ListChannels(_ context.Context) (
+	[]lndclient.ChannelInfo, error) {
+
+	return m.channels, nil
+}
+
+func (m *mockLightning) ClosedChannels(_ context.Context) (
+	[]lndclient.ClosedChannel, error) {
+
+	return m.closed, nil
+}
+
+// ForwardingHistory returns the events after the offset. Events are not
+// filtered by time.
+func (m *mockLightning) ForwardingHistory(_ context.Context,
+	req lndclient.ForwardingHistoryRequest) (
+	*lndclient.ForwardingHistoryResponse, error) {
+
+	resp := &lndclient.ForwardingHistoryResponse{
+		LastIndexOffset: req.Offset,
+	}
+	for i := int(req.Offset); i < len(m.forwards); i++ {
+		if req.MaxEvents != 0 &&
+			len(resp.Events) == int(req.MaxEvents) {
+
+			break
+		}
+
+		resp.Events = append(resp.Events, m.forwards[i])
+		resp.LastIndexOffset++
+	}
+
+	return resp, nil
+}
+
+func (m *mockLightning) GetNodeInfo(_ context.Context, pubkey route.Vertex,
+	_ bool) (*lndclient.NodeInfo, error) {
+
+	node, ok := m.nodes[pubkey]
+	if !ok {
+		return nil, errors.New("node not found")
+	}
+
+	return node, nil
+}
+
+// TestAnalyzeForwards tests that forwards are split into windows and
+// aggregated per channel and peer, and that the analysis can be exported.
+func TestAnalyzeForwards(t *testing.T) {
+	start := time.Unix(1000000, 0)
+
+	// We have an open channel with alice and a closed channel with a
+	// node we don't know.
+	aliceKey := route.Vertex{1}
+	lnd := &mockLightning{
+		channels: []lndclient.ChannelInfo{{
+			ChannelID:   1,
+			PubKeyBytes: aliceKey,
+		}},
+		closed: []lndclient.ClosedChannel{{
+			ChannelID:   2,
+			PubKeyBytes: route.Vertex{2},
+		}},
+		nodes: map[route.Vertex]*lndclient.NodeInfo{
+			aliceKey: {
+				Node: &lndclient.Node{
+					PubKey: aliceKey,
+					Alias:  "alice",
+				},
+			},
+		},
+		forwards: []lndclient.ForwardingEvent{{
+			Timestamp:     start.Add(time.Minute),
+			ChannelIn:     1,
+			ChannelOut:    2,
+			AmountMsatIn:  1001000,
+			AmountMsatOut: 1000000,
+			FeeMsat:       1000,
+		}, {
+			Timestamp:     start.Add(2 * time.Minute),
+			ChannelIn:     1,
+			ChannelOut:    3,
+			AmountMsatIn:  2002000,
+			AmountMsatOut: 2000000,
+			FeeMsat:       2000,
+		}, {
+			Timestamp:     start.Add(time.Hour + time.Minute),
+			ChannelIn:     2,
+			ChannelOut:    1,
+			AmountMsatIn:  500500,
+			AmountMsatOut: 500000,
+			FeeMsat:       500,
+		}},
+	}
+
+	analytics, err := AnalyzeForwards(
+		context.Background(), lnd, ForwardingAnalyticsRequest{
+			StartTime: start,
+			EndTime:   start.Add(3 * time.Hour),
+			Interval:  time.Hour,
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(analytics.Windows) != 3 ||
+		len(analytics.Windows[2].Channels) != 0 {
+
+		t.Fatalf("unexpected windows: %v", analytics.Windows)
+	}
+
+	first := analytics.Windows[0]
+	if first.Total.ForwardsOut != 2 || first.Total.FeesEarned != 3000 ||
+		len(first.Channels) != 3 || len(first.Peers) != 2 {
+
+		t.Fatalf("unexpected first window: %+v", first)
+	}
+
+	alice := first.Channels[0]
+	if alice.ChannelID != 1 || alice.PeerAlias != "alice" ||
+		alice.ForwardsIn != 2 || alice.VolumeIn != 3003000 ||
+		alice.Direction() != FlowInbound {
+
+		t.Fatalf("unexpected channel flow: %+v", alice)
+	}
+
+	// The closed channel is joined with its peer, the unknown channel
+	// isn't attributed to a peer.
+	closed := first.Channels[1]
+	if !closed.Closed || closed.Peer != (route.Vertex{2}) ||
+		closed.FeesEarned != 1000 ||
+		closed.Direction() != FlowOutbound {
+
+		t.Fatalf("unexpected closed channel flow: %+v", closed)
+	}
+	if first.Channels[2].Peer != (route.Vertex{}) {
+		t.Fatalf("unknown channel attributed to peer")
+	}
+
+	var buf bytes.Buffer
+	if err := analytics.WriteCSV(&buf); err != nil {
+		t.Fatalf("unable to write csv: %v", err)
+	}
+
+	// Every window has a total row, the first two windows hold the
+	// channels and peers that forwarded.
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("unable to read csv: %v", err)
+	}
+	if len(rows) != 1+3+3+2+2+2 {
+		t.Fatalf("unexpected number of rows: %v", len(rows))
+	}
+
+	buf.Reset()
+	if err := analytics.WriteJSON(&buf); err != nil {
+		t.Fatalf("unable to write json: %v", err)
+	}
+}
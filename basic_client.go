@@ -91,10 +91,12 @@ func NewBasicConn(lndHost, tlsPath, macDir, network string,
 	}
 
 	if macDir == "" {
-		macDir = filepath.Join(
-			defaultLndDir, defaultDataDir, defaultChainSubDir,
-			"bitcoin", network,
-		)
+		var err error
+		macDir, err = defaultMacaroonDir(Network(network))
+		if err != nil {
+			return nil, fmt.Errorf("unsupported network: %v",
+				network)
+		}
 	}
 
 	// Starting with the set of default options, we'll apply any specified
@@ -115,7 +117,11 @@ func NewBasicConn(lndHost, tlsPath, macDir, network string,
 		}
 
 		// Now we append the macaroon credentials to the dial options.
-		cred := macaroons.NewMacaroonCredential(mac)
+		cred, err := macaroons.NewMacaroonCredential(mac)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create macaroon "+
+				"credential: %v", err)
+		}
 		opts = append(opts, grpc.WithPerRPCCredentials(cred))
 		opts = append(opts, grpc.WithDefaultCallOptions(maxMsgRecvSize))
 	}
@@ -9,7 +9,6 @@ import (
 	"github.com/btcsuite/btcd/wire"
 	"github.com/lightningnetwork/lnd/chainntnfs"
 	"github.com/lightningnetwork/lnd/lnrpc/chainrpc"
-	"google.golang.org/grpc"
 )
 
 // ChainNotifierClient exposes base lightning functionality.
@@ -33,9 +32,11 @@ type chainNotifierClient struct {
 	wg sync.WaitGroup
 }
 
-func newChainNotifierClient(conn *grpc.ClientConn, chainMac serializedMacaroon) *chainNotifierClient {
+func newChainNotifierClient(client chainrpc.ChainNotifierClient,
+	chainMac serializedMacaroon) *chainNotifierClient {
+
 	return &chainNotifierClient{
-		client:   chainrpc.NewChainNotifierClient(conn),
+		client:   client,
 		chainMac: chainMac,
 	}
 }
@@ -0,0 +1,395 @@
+package lndclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+const (
+	// defaultAcceptorTimeout is the default time the acceptor has to
+	// decide on a channel open. lnd rejects channels that aren't answered
+	// within 15 seconds by default, so we answer before that.
+	defaultAcceptorTimeout = 10 * time.Second
+
+	// defaultAcceptorRetryDelay is the initial delay before the channel
+	// acceptor service re-establishes a failed stream.
+	defaultAcceptorRetryDelay = time.Second
+
+	// maxAcceptorRetryDelay is the maximum delay between attempts to
+	// re-establish a failed channel acceptor stream.
+	maxAcceptorRetryDelay = time.Minute
+)
+
+// ChannelAcceptRequest holds the parameters of an inbound channel open.
+type ChannelAcceptRequest struct {
+	// Peer is the public key of the node that wants to open the channel.
+	Peer route.Vertex
+
+	// ChainHash is the hash of the genesis block of the channel's chain.
+	ChainHash chainhash.Hash
+
+	// PendingChanID is the pending channel ID of the channel open.
+	PendingChanID [32]byte
+
+	// FundingAmt is the capacity of the channel.
+	FundingAmt btcutil.Amount
+
+	// PushAmt is the amount that is pushed to us.
+	PushAmt lnwire.MilliSatoshi
+
+	// DustLimit is the dust limit of the initiator's commitment.
+	DustLimit btcutil.Amount
+
+	// MaxValueInFlight is the maximum value in flight that the initiator
+	// allows.
+	MaxValueInFlight lnwire.MilliSatoshi
+
+	// ChannelReserve is the reserve that the initiator requires us to
+	// keep.
+	ChannelReserve btcutil.Amount
+
+	// MinHtlc is the smallest HTLC the initiator accepts.
+	MinHtlc lnwire.MilliSatoshi
+
+	// FeePerKw is the initial fee rate of the commitment transaction.
+	FeePerKw chainfee.SatPerKWeight
+
+	// CsvDelay is the delay we have to wait to claim our funds after a
+	// force close.
+	CsvDelay uint32
+
+	// MaxAcceptedHtlcs is the maximum number of HTLCs the initiator
+	// accepts.
+	MaxAcceptedHtlcs uint32
+
+	// ChannelFlags holds the flags of the channel open, for example
+	// whether the channel is announced.
+	ChannelFlags lnwire.FundingFlag
+}
+
+// newChannelAcceptRequest creates a channel accept request from its rpc
+// counterpart.
+func newChannelAcceptRequest(req *lnrpc.ChannelAcceptRequest) (
+	*ChannelAcceptRequest, error) {
+
+	peer, err := route.NewVertexFromBytes(req.NodePubkey)
+	if err != nil {
+		return nil, err
+	}
+
+	chainHash, err := chainhash.NewHash(req.ChainHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(req.PendingChanId) != 32 {
+		return nil, fmt.Errorf("invalid pending channel id length: %v",
+			len(req.PendingChanId))
+	}
+
+	request := &ChannelAcceptRequest{
+		Peer:             peer,
+		ChainHash:        *chainHash,
+		FundingAmt:       btcutil.Amount(req.FundingAmt),
+		PushAmt:          lnwire.MilliSatoshi(req.PushAmt),
+		DustLimit:        btcutil.Amount(req.DustLimit),
+		MaxValueInFlight: lnwire.MilliSatoshi(req.MaxValueInFlight),
+		ChannelReserve:   btcutil.Amount(req.ChannelReserve),
+		MinHtlc:          lnwire.MilliSatoshi(req.MinHtlc),
+		FeePerKw:         chainfee.SatPerKWeight(req.FeePerKw),
+		CsvDelay:         req.CsvDelay,
+		MaxAcceptedHtlcs: req.MaxAcceptedHtlcs,
+		ChannelFlags:     lnwire.FundingFlag(req.ChannelFlags),
+	}
+	copy(request.PendingChanID[:], req.PendingChanId)
+
+	return request, nil
+}
+
+// ChannelAcceptDecision is the answer to an inbound channel open.
+type ChannelAcceptDecision struct {
+	// Accept indicates whether the channel is accepted.
+	Accept bool
+
+	// Reason describes why the channel was rejected.
+	//
+	// NOTE: lnd v0.11 can't send the reason to the peer, it is only
+	// logged.
+	Reason string
+}
+
+// AcceptorFunc decides whether an inbound channel open is accepted. If it
+// returns an error or doesn't decide in time, the channel is rejected.
+type AcceptorFunc func(ctx context.Context,
+	req *ChannelAcceptRequest) (*ChannelAcceptDecision, error)
+
+// ChannelAcceptor registers a channel acceptor with lnd that passes every
+// inbound channel open to the acceptor provided, which has to decide within
+// the timeout. A zero timeout defaults to 10 seconds. The returned error
+// channel receives an error if the stream fails, after which no further
+// channel opens are handled. Note that lnd only allows a single channel
+// acceptor at a time.
+func (s *lightningClient) ChannelAcceptor(ctx context.Context,
+	timeout time.Duration, acceptor AcceptorFunc) (chan error, error) {
+
+	if timeout == 0 {
+		timeout = defaultAcceptorTimeout
+	}
+
+	rpcCtx := s.adminMac.WithMacaroonAuth(ctx)
+	stream, err := s.client.ChannelAcceptor(rpcCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	errChan := make(chan error, 1)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				errChan <- fmt.Errorf("channel acceptor "+
+					"receive failed: %v", err)
+				return
+			}
+
+			accept := decideChannel(ctx, timeout, acceptor, req)
+			err = stream.Send(&lnrpc.ChannelAcceptResponse{
+				Accept:        accept,
+				PendingChanId: req.PendingChanId,
+			})
+			if err != nil {
+				errChan <- fmt.Errorf("channel acceptor "+
+					"send failed: %v", err)
+				return
+			}
+		}
+	}()
+
+	return errChan, nil
+}
+
+// decideChannel asks the acceptor for a decision on a channel open and rejects
+// the channel if the acceptor fails or doesn't decide within the timeout.
+func decideChannel(ctx context.Context, timeout time.Duration,
+	acceptor AcceptorFunc, req *lnrpc.ChannelAcceptRequest) bool {
+
+	request, err := newChannelAcceptRequest(req)
+	if err != nil {
+		log.Errorf("Rejecting invalid channel accept request: %v", err)
+		return false
+	}
+
+	ctxt, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type result struct {
+		decision *ChannelAcceptDecision
+		err      error
+	}
+
+	// The result channel is buffered so that a late acceptor doesn't
+	// block forever.
+	resultChan := make(chan result, 1)
+	go func() {
+		decision, err := acceptor(ctxt, request)
+		resultChan <- result{decision: decision, err: err}
+	}()
+
+	select {
+	case res := <-resultChan:
+		switch {
+		case res.err != nil:
+			log.Errorf("Rejecting channel %x from %v, acceptor "+
+				"failed: %v", request.PendingChanID,
+				request.Peer, res.err)
+			return false
+
+		case res.decision == nil:
+			log.Errorf("Rejecting channel %x from %v, no decision",
+				request.PendingChanID, request.Peer)
+			return false
+
+		case !res.decision.Accept:
+			log.Infof("Rejecting channel %x from %v: %v",
+				request.PendingChanID, request.Peer,
+				res.decision.Reason)
+			return false
+		}
+
+		return true
+
+	case <-ctxt.Done():
+		log.Errorf("Rejecting channel %x from %v, acceptor timed out",
+			request.PendingChanID, request.Peer)
+		return false
+	}
+}
+
+// ChannelAcceptorService keeps a channel acceptor registered with lnd. If the
+// acceptor stream fails, for example because lnd restarted, the stream is
+// re-established with an exponential backoff.
+type ChannelAcceptorService struct {
+	lnd      LightningClient
+	acceptor AcceptorFunc
+	timeout  time.Duration
+}
+
+// NewChannelAcceptorService creates a channel acceptor service that decides on
+// inbound channels with the acceptor provided. A zero timeout defaults to 10
+// seconds.
+func NewChannelAcceptorService(lnd LightningClient, acceptor AcceptorFunc,
+	timeout time.Duration) *ChannelAcceptorService {
+
+	return &ChannelAcceptorService{
+		lnd:      lnd,
+		acceptor: acceptor,
+		timeout:  timeout,
+	}
+}
+
+// Run registers the channel acceptor and keeps it registered until the
+// context is cancelled.
+func (c *ChannelAcceptorService) Run(ctx context.Context) error {
+	retryDelay := defaultAcceptorRetryDelay
+
+	for {
+		errChan, err := c.lnd.ChannelAcceptor(
+			ctx, c.timeout, c.acceptor,
+		)
+		if err == nil {
+			log.Infof("Channel acceptor registered")
+
+			select {
+			case err = <-errChan:
+				// Reset the backoff, the stream was
+				// established successfully.
+				retryDelay = defaultAcceptorRetryDelay
+
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		// A cancelled context fails the stream too, in which case we
+		// don't retry.
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		log.Warnf("Channel acceptor failed, retrying in %v: %v",
+			retryDelay, err)
+
+		select {
+		case <-time.After(retryDelay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		retryDelay *= 2
+		if retryDelay > maxAcceptorRetryDelay {
+			retryDelay = maxAcceptorRetryDelay
+		}
+	}
+}
+
+// ChannelAcceptPolicy holds rules for inbound channels. Channels that violate
+// any of the rules are rejected.
+type ChannelAcceptPolicy struct {
+	// MinChanSize is the minimum capacity of inbound channels.
+	MinChanSize btcutil.Amount
+
+	// Blocklist holds peers that we don't accept channels from.
+	Blocklist []route.Vertex
+
+	// MaxChannelsPerPeer is the maximum number of open and pending
+	// channels we have with a single peer, including the new channel. If
+	// zero, the number of channels isn't limited.
+	MaxChannelsPerPeer int
+}
+
+// Acceptor returns an acceptor that applies the policy. It uses the lightning
+// client provided to count the channels we have with a peer.
+func (p *ChannelAcceptPolicy) Acceptor(lnd LightningClient) AcceptorFunc {
+	blocked := make(map[route.Vertex]struct{}, len(p.Blocklist))
+	for _, peer := range p.Blocklist {
+		blocked[peer] = struct{}{}
+	}
+
+	return func(ctx context.Context, req *ChannelAcceptRequest) (
+		*ChannelAcceptDecision, error) {
+
+		if _, ok := blocked[req.Peer]; ok {
+			return &ChannelAcceptDecision{
+				Reason: "peer is blocked",
+			}, nil
+		}
+
+		if req.FundingAmt < p.MinChanSize {
+			return &ChannelAcceptDecision{
+				Reason: fmt.Sprintf("channel size %v below "+
+					"minimum %v", req.FundingAmt,
+					p.MinChanSize),
+			}, nil
+		}
+
+		if p.MaxChannelsPerPeer == 0 {
+			return &ChannelAcceptDecision{Accept: true}, nil
+		}
+
+		count, err := countPeerChannels(ctx, lnd, req.Peer)
+		if err != nil {
+			return nil, err
+		}
+
+		if count >= p.MaxChannelsPerPeer {
+			return &ChannelAcceptDecision{
+				Reason: fmt.Sprintf("already %v channels with "+
+					"peer", count),
+			}, nil
+		}
+
+		return &ChannelAcceptDecision{Accept: true}, nil
+	}
+}
+
+// countPeerChannels returns the number of open and pending channels that we
+// have with a peer.
+func countPeerChannels(ctx context.Context, lnd LightningClient,
+	peer route.Vertex) (int, error) {
+
+	channels, err := lnd.ListChannels(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list channels: %v", err)
+	}
+
+	pending, err := lnd.PendingChannels(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("pending channels: %v", err)
+	}
+
+	var count int
+	for _, channel := range channels {
+		if channel.PubKeyBytes == peer {
+			count++
+		}
+	}
+	for _, channel := range pending.PendingOpen {
+		if channel.PubKeyBytes == peer {
+			count++
+		}
+	}
+
+	return count, nil
+}
@@ -0,0 +1,209 @@
+package lndclient
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"google.golang.org/grpc"
+)
+
+// mockChannelCounter is a lightning client that reports a fixed set of open
+// and pending channels.
+type mockChannelCounter struct {
+	LightningClient
+
+	channels []ChannelInfo
+	pending  []PendingChannel
+}
+
+func (m *mockChannelCounter) ListChannels(_ context.Context) ([]ChannelInfo,
+	error) {
+
+	return m.channels, nil
+}
+
+func (m *mockChannelCounter) PendingChannels(_ context.Context) (
+	*PendingChannels, error) {
+
+	return &PendingChannels{PendingOpen: m.pending}, nil
+}
+
+// TestChannelAcceptPolicy tests that the policy rejects channels that are too
+// small, come from blocked peers or exceed the channel limit per peer.
+func TestChannelAcceptPolicy(t *testing.T) {
+	peer := route.Vertex{1}
+	blocked := route.Vertex{2}
+
+	lnd := &mockChannelCounter{
+		channels: []ChannelInfo{{PubKeyBytes: peer}},
+		pending:  []PendingChannel{{PubKeyBytes: peer}},
+	}
+
+	policy := &ChannelAcceptPolicy{
+		MinChanSize:        100000,
+		Blocklist:          []route.Vertex{blocked},
+		MaxChannelsPerPeer: 3,
+	}
+	acceptor := policy.Acceptor(lnd)
+
+	tests := []struct {
+		name         string
+		peer         route.Vertex
+		amt          btcutil.Amount
+		existing     int
+		expectAccept bool
+	}{{
+		name:         "accepted",
+		peer:         peer,
+		amt:          200000,
+		expectAccept: true,
+	}, {
+		name: "too small",
+		peer: peer,
+		amt:  50000,
+	}, {
+		name: "blocked",
+		peer: blocked,
+		amt:  200000,
+	}, {
+		name:     "too many channels",
+		peer:     peer,
+		amt:      200000,
+		existing: 1,
+	}}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			lnd.channels = []ChannelInfo{{PubKeyBytes: peer}}
+			for i := 0; i < test.existing; i++ {
+				lnd.channels = append(
+					lnd.channels,
+					ChannelInfo{PubKeyBytes: peer},
+				)
+			}
+
+			decision, err := acceptor(
+				context.Background(), &ChannelAcceptRequest{
+					Peer:       test.peer,
+					FundingAmt: test.amt,
+				},
+			)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if decision.Accept != test.expectAccept {
+				t.Fatalf("expected accept %v, got %v (%v)",
+					test.expectAccept, decision.Accept,
+					decision.Reason)
+			}
+			if !decision.Accept && decision.Reason == "" {
+				t.Fatalf("expected reject reason")
+			}
+		})
+	}
+}
+
+// mockAcceptorStream is a channel acceptor stream that delivers the given
+// requests and records the responses.
+type mockAcceptorStream struct {
+	grpc.ClientStream
+
+	requests  chan *lnrpc.ChannelAcceptRequest
+	responses chan *lnrpc.ChannelAcceptResponse
+}
+
+func (m *mockAcceptorStream) Recv() (*lnrpc.ChannelAcceptRequest, error) {
+	req, ok := <-m.requests
+	if !ok {
+		return nil, io.EOF
+	}
+
+	return req, nil
+}
+
+func (m *mockAcceptorStream) Send(resp *lnrpc.ChannelAcceptResponse) error {
+	m.responses <- resp
+	return nil
+}
+
+// mockAcceptorLightning is a lightning client that returns the given channel
+// acceptor stream.
+type mockAcceptorLightning struct {
+	lnrpc.LightningClient
+
+	stream *mockAcceptorStream
+}
+
+func (m *mockAcceptorLightning) ChannelAcceptor(_ context.Context,
+	_ ...grpc.CallOption) (lnrpc.Lightning_ChannelAcceptorClient, error) {
+
+	return m.stream, nil
+}
+
+// TestChannelAcceptor tests that channel opens are answered with the decision
+// of the acceptor, that slow acceptors lead to a rejection and that stream
+// failures are reported.
+func TestChannelAcceptor(t *testing.T) {
+	stream := &mockAcceptorStream{
+		requests:  make(chan *lnrpc.ChannelAcceptRequest),
+		responses: make(chan *lnrpc.ChannelAcceptResponse),
+	}
+	client := newLightningClient(
+		&mockAcceptorLightning{stream: stream}, nil,
+		&chaincfg.RegressionNetParams, "",
+	)
+
+	acceptor := func(ctx context.Context, req *ChannelAcceptRequest) (
+		*ChannelAcceptDecision, error) {
+
+		// Channels without push amount are decided slowly, so that
+		// the acceptor times out.
+		if req.PushAmt == 0 {
+			<-ctx.Done()
+		}
+
+		return &ChannelAcceptDecision{Accept: true}, nil
+	}
+
+	errChan, err := client.ChannelAcceptor(
+		context.Background(), 50*time.Millisecond, acceptor,
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	peer := route.Vertex{3}
+	chainHash := chaincfg.RegressionNetParams.GenesisHash
+	for _, pushAmt := range []uint64{1000, 0} {
+		pendingChanID := [32]byte{byte(pushAmt)}
+		stream.requests <- &lnrpc.ChannelAcceptRequest{
+			NodePubkey:    peer[:],
+			ChainHash:     chainHash[:],
+			PendingChanId: pendingChanID[:],
+			PushAmt:       pushAmt,
+		}
+
+		resp := <-stream.responses
+		if resp.Accept != (pushAmt != 0) {
+			t.Fatalf("unexpected decision for push amount %v",
+				pushAmt)
+		}
+		if string(resp.PendingChanId) != string(pendingChanID[:]) {
+			t.Fatalf("unexpected pending channel id")
+		}
+	}
+
+	close(stream.requests)
+	if err := <-errChan; err == nil {
+		t.Fatalf("expected stream error")
+	}
+}
@@ -0,0 +1,355 @@
+package lndclient
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// blocksPerDay is the expected number of blocks mined per day.
+const blocksPerDay = 144
+
+// ChannelPnL holds the profit and loss of a single channel over its lifetime.
+type ChannelPnL struct {
+	// ChannelID is the short channel id of the channel.
+	ChannelID uint64
+
+	// ChannelPoint is the funding outpoint of the channel.
+	ChannelPoint string
+
+	// Peer is the remote node of the channel.
+	Peer route.Vertex
+
+	// Capacity is the capacity of the channel.
+	Capacity btcutil.Amount
+
+	// Initiator is set if we opened the channel, in which case we paid
+	// the on chain fees of opening and closing it.
+	Initiator bool
+
+	// Closed is set if the channel was closed.
+	Closed bool
+
+	// OpenHeight is the height the funding transaction confirmed at.
+	OpenHeight uint32
+
+	// CloseHeight is the height the channel closed at, or zero if it is
+	// still open.
+	CloseHeight uint32
+
+	// ClosingTxHash is the hash of the closing transaction, if the
+	// channel was closed.
+	ClosingTxHash string
+
+	// OpenCost is the on chain fee we paid to open the channel. If the
+	// funding transaction opened several channels, its fee is shared
+	// equally between them.
+	OpenCost btcutil.Amount
+
+	// CloseCost is the on chain fee of the closing transaction, if we
+	// opened the channel. Fees of sweeping the outputs of a force close
+	// are not included.
+	CloseCost btcutil.Amount
+
+	// RoutingRevenue is the fees earned by forwards that left through the
+	// channel.
+	RoutingRevenue lnwire.MilliSatoshi
+
+	// RebalanceCost is the fees paid to move funds back into the channel
+	// by paying ourselves. The cost of a rebalance is attributed to the
+	// channel that gained outbound liquidity.
+	RebalanceCost lnwire.MilliSatoshi
+
+	// Profit is the routing revenue minus all costs in millisatoshis.
+	Profit int64
+
+	// CapacityDays is the capacity of the channel in satoshis multiplied
+	// by the number of days it was open, based on 144 blocks per day.
+	CapacityDays float64
+
+	// ROI is the profit per satoshi of capacity per day.
+	ROI float64
+
+	// Incomplete is set if a transaction we paid for couldn't be found
+	// in our wallet, so costs may be missing.
+	Incomplete bool
+}
+
+// ChannelPnLReport holds the profit and loss of all our open and closed
+// channels.
+type ChannelPnLReport struct {
+	// BlockHeight is the height the report was created at.
+	BlockHeight uint32
+
+	// Channels holds the profit and loss of every channel, sorted by
+	// channel id.
+	Channels []*ChannelPnL
+}
+
+// CalculateChannelPnL calculates the profit and loss of all our open and
+// closed channels over their lifetime. On chain costs are taken from our
+// wallet transactions, revenue from our forwarding history and rebalancing
+// costs from payments to ourselves.
+func CalculateChannelPnL(ctx context.Context,
+	lnd LightningClient) (*ChannelPnLReport, error) {
+
+	info, err := lnd.GetInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	channels, err := pnlChannels(ctx, lnd, info.BlockHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := addOnChainCosts(ctx, lnd, channels); err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uint64]*ChannelPnL, len(channels))
+	for _, channel := range channels {
+		byID[channel.ChannelID] = channel
+	}
+
+	forwards := NewForwardingIterator(lnd, HistoryQuery{})
+	for forwards.Next(ctx) {
+		event := forwards.Event()
+		if channel, ok := byID[event.ChannelOut]; ok {
+			channel.RoutingRevenue += event.FeeMsat
+		}
+	}
+	if err := forwards.Err(); err != nil {
+		return nil, err
+	}
+
+	self := route.Vertex(info.IdentityPubkey)
+	payments := NewPaymentIterator(lnd, HistoryQuery{}, false)
+	for payments.Next(ctx) {
+		payment := payments.Payment()
+		if err := addRebalanceCost(&payment, self, byID); err != nil {
+			return nil, err
+		}
+	}
+	if err := payments.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, channel := range channels {
+		channel.Profit = int64(channel.RoutingRevenue) -
+			int64(channel.RebalanceCost) -
+			int64(lnwire.NewMSatFromSatoshis(
+				channel.OpenCost+channel.CloseCost,
+			))
+
+		if channel.CapacityDays > 0 {
+			channel.ROI = float64(channel.Profit) / 1000 /
+				channel.CapacityDays
+		}
+	}
+
+	return &ChannelPnLReport{
+		BlockHeight: info.BlockHeight,
+		Channels:    channels,
+	}, nil
+}
+
+// pnlChannels returns all our open and closed channels, sorted by channel id.
+func pnlChannels(ctx context.Context, lnd LightningClient,
+	height uint32) ([]*ChannelPnL, error) {
+
+	open, err := lnd.ListChannels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	closed, err := lnd.ClosedChannels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var channels []*ChannelPnL
+	for _, channel := range open {
+		channels = append(channels, &ChannelPnL{
+			ChannelID:    channel.ChannelID,
+			ChannelPoint: channel.ChannelPoint,
+			Peer:         channel.PubKeyBytes,
+			Capacity:     channel.Capacity,
+			Initiator:    channel.Initiator,
+		})
+	}
+
+	for _, channel := range closed {
+		pnl := &ChannelPnL{
+			ChannelID:     channel.ChannelID,
+			ChannelPoint:  channel.ChannelPoint,
+			Peer:          channel.PubKeyBytes,
+			Capacity:      channel.Capacity,
+			Initiator:     channel.OpenInitiator == InitiatorLocal,
+			Closed:        true,
+			CloseHeight:   channel.CloseHeight,
+			ClosingTxHash: channel.ClosingTxHash,
+		}
+
+		// Channels closed before lnd recorded the initiator may have
+		// costs we don't know about.
+		if channel.OpenInitiator == InitiatorUnrecorded {
+			pnl.Incomplete = true
+		}
+
+		channels = append(channels, pnl)
+	}
+
+	for _, channel := range channels {
+		// Channels that never confirmed have no short channel id.
+		if channel.ChannelID == 0 {
+			continue
+		}
+
+		channel.OpenHeight = lnwire.NewShortChanIDFromInt(
+			channel.ChannelID,
+		).BlockHeight
+
+		end := height
+		if channel.Closed {
+			end = channel.CloseHeight
+		}
+		if end > channel.OpenHeight {
+			days := float64(end-channel.OpenHeight) / blocksPerDay
+			channel.CapacityDays = float64(channel.Capacity) * days
+		}
+	}
+
+	sort.Slice(channels, func(i, j int) bool {
+		return channels[i].ChannelID < channels[j].ChannelID
+	})
+
+	return channels, nil
+}
+
+// addOnChainCosts adds the fees of the funding and closing transactions of
+// the channels we opened.
+func addOnChainCosts(ctx context.Context, lnd LightningClient,
+	channels []*ChannelPnL) error {
+
+	// We need the transactions of channels opened at any height, so we
+	// query all of them at once rather than paging through every block.
+	walletTxs, err := lnd.ListTransactions(ctx, 0, -1)
+	if err != nil {
+		return err
+	}
+
+	txs := make(map[string]*Transaction, len(walletTxs))
+	for i := range walletTxs {
+		txs[walletTxs[i].TxHash] = &walletTxs[i]
+	}
+
+	// A funding transaction may open several channels, so we count the
+	// channels of every funding transaction first.
+	points := make(map[*ChannelPnL]*wire.OutPoint)
+	fundingCount := make(map[chainhash.Hash]int)
+	for _, channel := range channels {
+		if !channel.Initiator {
+			continue
+		}
+
+		point, err := NewOutpointFromStr(channel.ChannelPoint)
+		if err != nil {
+			return fmt.Errorf("channel %v: %v", channel.ChannelID,
+				err)
+		}
+
+		points[channel] = point
+		fundingCount[point.Hash]++
+	}
+
+	for _, channel := range channels {
+		point, ok := points[channel]
+		if !ok {
+			continue
+		}
+
+		funding, ok := txs[point.Hash.String()]
+		if ok {
+			channel.OpenCost = funding.Fee /
+				btcutil.Amount(fundingCount[point.Hash])
+		} else {
+			channel.Incomplete = true
+		}
+
+		if !channel.Closed {
+			continue
+		}
+
+		fee, ok := closingFee(
+			txs[channel.ClosingTxHash], point, channel.Capacity,
+		)
+		if !ok {
+			channel.Incomplete = true
+			continue
+		}
+		channel.CloseCost = fee
+	}
+
+	return nil
+}
+
+// closingFee returns the fee of a closing transaction, which spends the
+// funding output only. If the transaction is unknown or spends other inputs,
+// false is returned.
+func closingFee(tx *Transaction, point *wire.OutPoint,
+	capacity btcutil.Amount) (btcutil.Amount, bool) {
+
+	if tx == nil || tx.Tx == nil || len(tx.Tx.TxIn) != 1 ||
+		tx.Tx.TxIn[0].PreviousOutPoint != *point {
+
+		return 0, false
+	}
+
+	fee := capacity
+	for _, out := range tx.Tx.TxOut {
+		fee -= btcutil.Amount(out.Value)
+	}
+
+	return fee, fee >= 0
+}
+
+// addRebalanceCost adds the fees of a payment to ourselves to the channel it
+// returned through.
+func addRebalanceCost(payment *Payment, self route.Vertex,
+	channels map[uint64]*ChannelPnL) error {
+
+	for _, htlc := range payment.Htlcs {
+		if htlc.Status != lnrpc.HTLCAttempt_SUCCEEDED ||
+			htlc.Route == nil || len(htlc.Route.Hops) == 0 {
+
+			continue
+		}
+
+		lastHop := htlc.Route.Hops[len(htlc.Route.Hops)-1]
+		dest, err := route.NewVertexFromStr(lastHop.PubKey)
+		if err != nil {
+			return fmt.Errorf("payment %v: %v", payment.Hash, err)
+		}
+		if dest != self {
+			continue
+		}
+
+		channel, ok := channels[lastHop.ChanId]
+		if !ok {
+			continue
+		}
+		channel.RebalanceCost += lnwire.MilliSatoshi(
+			htlc.Route.TotalFeesMsat,
+		)
+	}
+
+	return nil
+}
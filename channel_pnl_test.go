@@ -0,0 +1,135 @@
+package lndclient
+
+import (
+	"context"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+var (
+	pnlSelf     = route.Vertex{9}
+	pnlOther    = route.Vertex{3}
+	pnlOpenID   = lnwire.ShortChannelID{BlockHeight: 100}.ToUint64()
+	pnlClosedID = lnwire.ShortChannelID{
+		BlockHeight: 100, TxPosition: 1,
+	}.ToUint64()
+)
+
+// pnlHtlc returns a successful htlc that paid the destination through the
+// channel provided as its last hop.
+func pnlHtlc(dest route.Vertex, chanID uint64,
+	feeMsat int64) []*lnrpc.HTLCAttempt {
+
+	return []*lnrpc.HTLCAttempt{{
+		Status: lnrpc.HTLCAttempt_SUCCEEDED,
+		Route: &lnrpc.Route{
+			TotalFeesMsat: feeMsat,
+			Hops: []*lnrpc.Hop{{
+				ChanId: chanID,
+				PubKey: dest.String(),
+			}},
+		},
+	}}
+}
+
+// newPnLLightning returns a lightning client with an open and a closed
+// channel that we both funded in the same transaction.
+func newPnLLightning() *mockLightning {
+	fundingTx := chainhash.Hash{1}
+
+	closingTx := wire.NewMsgTx(2)
+	closingTx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Hash: fundingTx, Index: 1},
+	})
+	closingTx.AddTxOut(&wire.TxOut{Value: 499000})
+
+	return &mockLightning{
+		info: Info{
+			BlockHeight:    100 + 10*blocksPerDay,
+			IdentityPubkey: pnlSelf,
+		},
+		channels: []ChannelInfo{{
+			ChannelID:    pnlOpenID,
+			ChannelPoint: fundingTx.String() + ":0",
+			PubKeyBytes:  route.Vertex{1},
+			Capacity:     1000000,
+			Initiator:    true,
+		}},
+		closed: []ClosedChannel{{
+			ChannelID:     pnlClosedID,
+			ChannelPoint:  fundingTx.String() + ":1",
+			ClosingTxHash: "closing",
+			CloseHeight:   100 + 5*blocksPerDay,
+			OpenInitiator: InitiatorLocal,
+			PubKeyBytes:   route.Vertex{2},
+			Capacity:      500000,
+		}},
+		txs: []Transaction{{
+			TxHash: fundingTx.String(),
+			Fee:    2000,
+		}, {
+			Tx:     closingTx,
+			TxHash: "closing",
+		}},
+		forwards: []ForwardingEvent{{
+			ChannelIn:  pnlClosedID,
+			ChannelOut: pnlOpenID,
+			FeeMsat:    5000000,
+		}},
+
+		// A rebalance into the open channel and a payment to someone
+		// else.
+		payments: []Payment{{
+			SequenceNumber: 1,
+			Htlcs:          pnlHtlc(pnlSelf, pnlOpenID, 300000),
+		}, {
+			SequenceNumber: 2,
+			Htlcs:          pnlHtlc(pnlOther, pnlOpenID, 100000),
+		}},
+	}
+}
+
+// TestCalculateChannelPnL tests that on chain costs, routing revenue and
+// rebalancing costs are attributed to the right channels.
+func TestCalculateChannelPnL(t *testing.T) {
+	lnd := newPnLLightning()
+
+	report, err := CalculateChannelPnL(context.Background(), lnd)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(report.Channels) != 2 {
+		t.Fatalf("expected 2 channels, got %v", len(report.Channels))
+	}
+
+	// The funding fee is shared between both channels.
+	open := report.Channels[0]
+	if open.ChannelID != pnlOpenID || open.OpenCost != 1000 ||
+		open.RoutingRevenue != 5000000 ||
+		open.RebalanceCost != 300000 || open.Profit != 3700000 ||
+		open.CapacityDays != 10000000 || open.Incomplete {
+
+		t.Fatalf("unexpected open channel: %+v", open)
+	}
+	if open.ROI != 3700.0/10000000 {
+		t.Fatalf("unexpected roi: %v", open.ROI)
+	}
+
+	closed := report.Channels[1]
+	if !closed.Closed || closed.OpenCost != 1000 ||
+		closed.CloseCost != 1000 || closed.Profit != -2000000 ||
+		closed.CapacityDays != 2500000 || closed.Incomplete {
+
+		t.Fatalf("unexpected closed channel: %+v", closed)
+	}
+
+	// All wallet transactions are queried at once.
+	if len(lnd.txRanges) != 1 || lnd.txRanges[0] != [2]int32{0, -1} {
+		t.Fatalf("unexpected transaction queries: %v", lnd.txRanges)
+	}
+}
@@ -0,0 +1,119 @@
+// Command lndclient-doctor diagnoses connection problems between lndclient
+// and an lnd node. It walks through every step that is needed to connect and
+// reports precisely which of them failed and why.
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/lightninglabs/lndclient"
+)
+
+func main() {
+	var (
+		lndAddress = flag.String(
+			"rpcserver", "localhost:10009", "host:port of lnd's "+
+				"gRPC or REST interface",
+		)
+		network = flag.String(
+			"network", string(lndclient.NetworkMainnet), "the "+
+				"network lnd is expected to run on",
+		)
+		macaroonDir = flag.String(
+			"macaroondir", "", "directory of lnd's macaroons, "+
+				"defaults to the network's default directory",
+		)
+		macaroonPath = flag.String(
+			"macaroonpath", "", "path of a custom macaroon that "+
+				"is used for all subservers",
+		)
+		tlsPath = flag.String(
+			"tlscertpath", "", "path of lnd's TLS certificate",
+		)
+		transport = flag.String(
+			"transport", string(lndclient.TransportGrpc), "the "+
+				"transport to use, either grpc or rest",
+		)
+		signetChallenge = flag.String(
+			"signetchallenge", "", "hex encoded challenge of a "+
+				"custom signet",
+		)
+		jsonOutput = flag.Bool(
+			"json", false, "print the report as JSON",
+		)
+	)
+	flag.Parse()
+
+	challenge, err := hex.DecodeString(*signetChallenge)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid signet challenge: %v\n", err)
+		os.Exit(2)
+	}
+
+	report := lndclient.RunDiagnostics(
+		context.Background(), &lndclient.LndServicesConfig{
+			LndAddress:         *lndAddress,
+			Network:            lndclient.Network(*network),
+			MacaroonDir:        *macaroonDir,
+			CustomMacaroonPath: *macaroonPath,
+			TLSPath:            *tlsPath,
+			Transport:          lndclient.Transport(*transport),
+			SigNetChallenge:    challenge,
+		},
+	)
+
+	if *jsonOutput {
+		err = printJSON(report)
+	} else {
+		printReport(report)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to print report: %v\n", err)
+		os.Exit(2)
+	}
+
+	if report.FirstFailure() != nil {
+		os.Exit(1)
+	}
+}
+
+// printJSON prints the report as indented JSON.
+func printJSON(report *lndclient.DiagnosticReport) error {
+	reportJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(reportJSON))
+	return nil
+}
+
+// printReport prints the report in a human readable form, one line per step,
+// followed by a summary that names the first failed step.
+func printReport(report *lndclient.DiagnosticReport) {
+	for _, step := range report.Steps {
+		switch step.Status {
+		case lndclient.DiagnosticPassed:
+			fmt.Printf("[ OK ] %s: %s\n", step.Name, step.Details)
+
+		case lndclient.DiagnosticFailed:
+			fmt.Printf("[FAIL] %s: %s\n", step.Name, step.Error)
+
+		case lndclient.DiagnosticSkipped:
+			fmt.Printf("[SKIP] %s: %s\n", step.Name, step.Error)
+		}
+	}
+
+	fmt.Println()
+	if failed := report.FirstFailure(); failed != nil {
+		fmt.Printf("Connection failed at step '%s': %s\n", failed.Name,
+			failed.Error)
+		return
+	}
+	fmt.Println("All checks passed, lndclient can connect to lnd")
+}
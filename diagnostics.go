@@ -0,0 +1,530 @@
+package lndclient
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/chainrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/invoicesrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/signrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/verrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/walletrpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	macaroon "gopkg.in/macaroon.v2"
+)
+
+// DiagnosticStatus is the outcome of a single diagnostic step.
+type DiagnosticStatus string
+
+const (
+	// DiagnosticPassed means the step was executed successfully.
+	DiagnosticPassed DiagnosticStatus = "passed"
+
+	// DiagnosticFailed means the step was executed and failed.
+	DiagnosticFailed DiagnosticStatus = "failed"
+
+	// DiagnosticSkipped means the step was not executed because a step it
+	// depends on did not pass.
+	DiagnosticSkipped DiagnosticStatus = "skipped"
+)
+
+// DiagnosticStep is the result of a single step of the connection
+// diagnostics.
+type DiagnosticStep struct {
+	// Name is the name of the step.
+	Name string `json:"name"`
+
+	// Status is the outcome of the step.
+	Status DiagnosticStatus `json:"status"`
+
+	// Details is a human readable description of what the step found out,
+	// if it passed.
+	Details string `json:"details,omitempty"`
+
+	// Error is the reason the step failed or was skipped.
+	Error string `json:"error,omitempty"`
+}
+
+// DiagnosticReport is the result of all steps of the connection diagnostics,
+// in the order they were executed.
+type DiagnosticReport struct {
+	// Steps is the list of all diagnostic steps.
+	Steps []*DiagnosticStep `json:"steps"`
+}
+
+// FirstFailure returns the first step that failed or nil if no step failed.
+func (r *DiagnosticReport) FirstFailure() *DiagnosticStep {
+	for _, step := range r.Steps {
+		if step.Status == DiagnosticFailed {
+			return step
+		}
+	}
+
+	return nil
+}
+
+// diagnostics is a helper that executes diagnostic steps and records their
+// results in a report.
+type diagnostics struct {
+	report *DiagnosticReport
+}
+
+// step executes fn as the step with the given name and adds its result to the
+// report. If any of the steps it depends on didn't pass, fn isn't executed and
+// the step is marked as skipped.
+func (d *diagnostics) step(name string, deps []*DiagnosticStep,
+	fn func() (string, error)) *DiagnosticStep {
+
+	step := &DiagnosticStep{
+		Name: name,
+	}
+	d.report.Steps = append(d.report.Steps, step)
+
+	for _, dep := range deps {
+		if dep.Status != DiagnosticPassed {
+			step.Status = DiagnosticSkipped
+			step.Error = fmt.Sprintf("step '%s' did not pass",
+				dep.Name)
+			return step
+		}
+	}
+
+	details, err := fn()
+	if err != nil {
+		step.Status = DiagnosticFailed
+		step.Error = err.Error()
+		return step
+	}
+
+	step.Status = DiagnosticPassed
+	step.Details = details
+	return step
+}
+
+// diagnosticMacaroon is a macaroon that is checked by the diagnostics,
+// together with the subserver that needs it.
+type diagnosticMacaroon struct {
+	subserver string
+	filename  string
+	mac       serializedMacaroon
+	step      *DiagnosticStep
+}
+
+// RunDiagnostics walks through all steps that are needed to connect to lnd
+// with the given configuration and reports the outcome of each of them. In
+// contrast to NewLndServices, it doesn't stop at the first error but runs all
+// steps that don't depend on the failed one, so the report shows precisely
+// which parts of the setup work and which don't. The configuration is not
+// modified.
+func RunDiagnostics(ctx context.Context,
+	lndCfg *LndServicesConfig) *DiagnosticReport {
+
+	d := &diagnostics{
+		report: &DiagnosticReport{},
+	}
+	cfg := *lndCfg
+
+	var (
+		macaroonDir string
+		chainParams *chaincfg.Params
+	)
+	configStep := d.step("configuration", nil, func() (string, error) {
+		var err error
+		macaroonDir, err = cfg.setDefaults()
+		if err != nil {
+			return "", err
+		}
+
+		chainParams, err = cfg.chainParams()
+		if err != nil {
+			return "", fmt.Errorf("unable to get chain params for "+
+				"network %v: %v", cfg.Network, err)
+		}
+
+		transport := cfg.Transport
+		if transport == "" {
+			transport = TransportGrpc
+		}
+
+		return fmt.Sprintf("connecting to %v using %v on network %v",
+			cfg.LndAddress, transport, cfg.Network), nil
+	})
+
+	var rawConn net.Conn
+	dialStep := d.step("dial", []*DiagnosticStep{configStep},
+		func() (string, error) {
+			ctxt, cancel := context.WithTimeout(ctx, rpcTimeout)
+			defer cancel()
+
+			var err error
+			rawConn, err = cfg.Dialer(ctxt, cfg.LndAddress)
+			if err != nil {
+				return "", err
+			}
+
+			return fmt.Sprintf("connected to %v",
+				rawConn.RemoteAddr()), nil
+		},
+	)
+
+	tlsStep := d.step("tls handshake", []*DiagnosticStep{dialStep},
+		func() (string, error) {
+			defer rawConn.Close()
+
+			return diagnoseTLS(rawConn, &cfg)
+		},
+	)
+
+	// Load all macaroons one by one, so we know exactly which of them is
+	// missing. If a custom macaroon is used, it's used for all subservers.
+	macaroons := []*diagnosticMacaroon{
+		{subserver: "readonly", filename: defaultReadonlyFilename},
+		{subserver: "lightning", filename: defaultAdminMacaroonFilename},
+		{
+			subserver: "chainnotifier",
+			filename:  defaultChainMacaroonFilename,
+		},
+		{subserver: "signer", filename: defaultSignerFilename},
+		{
+			subserver: "walletkit",
+			filename:  defaultWalletKitMacaroonFilename,
+		},
+		{subserver: "invoices", filename: defaultInvoiceMacaroonFilename},
+		{subserver: "router", filename: defaultRouterMacaroonFilename},
+	}
+	for _, m := range macaroons {
+		m := m
+
+		name := fmt.Sprintf("macaroon %s", m.filename)
+		if cfg.CustomMacaroonPath != "" {
+			name = fmt.Sprintf("macaroon for %s", m.subserver)
+		}
+
+		m.step = d.step(name, []*DiagnosticStep{configStep},
+			func() (string, error) {
+				path := cfg.CustomMacaroonPath
+				if path == "" {
+					path = filepath.Join(
+						macaroonDir, m.filename,
+					)
+				}
+
+				var err error
+				m.mac, err = diagnoseMacaroon(path)
+				if err != nil {
+					return "", err
+				}
+
+				return fmt.Sprintf("loaded %v", path), nil
+			},
+		)
+	}
+	readonlyMac := macaroons[0]
+
+	// The RPC connection itself doesn't do any I/O yet, that's what the
+	// dial and TLS steps were for. Errors creating it are reported in the
+	// first step that uses it.
+	var (
+		conn    *lndConn
+		connErr error
+	)
+	if tlsStep.Status == DiagnosticPassed {
+		conn, connErr = getLndConn(&cfg)
+		if connErr == nil {
+			defer func() {
+				_ = conn.close()
+			}()
+		}
+	}
+
+	var info *Info
+	infoStep := d.step(
+		"getinfo", []*DiagnosticStep{tlsStep, readonlyMac.step},
+		func() (string, error) {
+			if connErr != nil {
+				return "", connErr
+			}
+
+			client := newLightningClient(
+				conn.lightning, conn.router, chainParams,
+				readonlyMac.mac,
+			)
+
+			var err error
+			info, err = client.GetInfo(ctx)
+			if err != nil {
+				return "", err
+			}
+
+			return fmt.Sprintf("node %x (%v) at height %d",
+				info.IdentityPubkey, info.Alias,
+				info.BlockHeight), nil
+		},
+	)
+
+	d.step("network", []*DiagnosticStep{infoStep}, func() (string, error) {
+		if string(cfg.Network) != info.Network {
+			return "", fmt.Errorf("network mismatch, expected "+
+				"'%s' but lnd is running on '%s'", cfg.Network,
+				info.Network)
+		}
+
+		return fmt.Sprintf("lnd is running on %v", info.Network), nil
+	})
+
+	d.step("version", []*DiagnosticStep{tlsStep, readonlyMac.step},
+		func() (string, error) {
+			if connErr != nil {
+				return "", connErr
+			}
+
+			client := newVersionerClient(
+				conn.versioner, readonlyMac.mac,
+			)
+
+			return diagnoseVersion(ctx, client, cfg.CheckVersion)
+		},
+	)
+
+	d.step("chain sync", []*DiagnosticStep{infoStep},
+		func() (string, error) {
+			if !info.SyncedToChain {
+				return "", fmt.Errorf("lnd is not synced to "+
+					"its chain backend, best block at "+
+					"height %d is from %v",
+					info.BlockHeight,
+					info.BestHeaderTimeStamp)
+			}
+
+			return fmt.Sprintf("synced to chain at height %d",
+				info.BlockHeight), nil
+		},
+	)
+
+	// Finally, make sure each subserver is reachable with its macaroon.
+	for _, m := range macaroons[1:] {
+		m := m
+
+		d.step(fmt.Sprintf("subserver %s", m.subserver),
+			[]*DiagnosticStep{tlsStep, m.step},
+			func() (string, error) {
+				if connErr != nil {
+					return "", connErr
+				}
+
+				ctxt, cancel := context.WithTimeout(
+					m.mac.WithMacaroonAuth(ctx), rpcTimeout,
+				)
+				defer cancel()
+
+				err := probeSubserver(ctxt, conn, m.subserver)
+				if err != nil {
+					return "", err
+				}
+
+				return fmt.Sprintf("%s subserver is reachable",
+					m.subserver), nil
+			},
+		)
+	}
+
+	return d.report
+}
+
+// diagnoseTLS does a TLS handshake over the given connection using lnd's TLS
+// certificate and returns a description of the certificate lnd presented.
+func diagnoseTLS(rawConn net.Conn, cfg *LndServicesConfig) (string, error) {
+	tlsPath := cfg.TLSPath
+	if tlsPath == "" {
+		tlsPath = defaultTLSCertPath
+	}
+
+	certBytes, err := ioutil.ReadFile(tlsPath)
+	if err != nil {
+		return "", fmt.Errorf("unable to read TLS certificate: %v", err)
+	}
+	certPool := x509.NewCertPool()
+	if !certPool.AppendCertsFromPEM(certBytes) {
+		return "", fmt.Errorf("%v is not a valid PEM encoded TLS "+
+			"certificate", tlsPath)
+	}
+
+	// The certificate must be valid for the host name we connect to,
+	// which is the address without the port.
+	host := cfg.LndAddress
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	tlsConn := tls.Client(rawConn, &tls.Config{
+		RootCAs:    certPool,
+		ServerName: host,
+	})
+	if err := tlsConn.SetDeadline(time.Now().Add(rpcTimeout)); err != nil {
+		return "", err
+	}
+	if err := tlsConn.Handshake(); err != nil {
+		return "", fmt.Errorf("TLS handshake with certificate %v "+
+			"failed: %v", tlsPath, err)
+	}
+
+	peerCerts := tlsConn.ConnectionState().PeerCertificates
+	if len(peerCerts) == 0 {
+		return "", errors.New("lnd didn't present a TLS certificate")
+	}
+
+	return fmt.Sprintf("certificate valid for %v, expires %v",
+		strings.Join(peerCerts[0].DNSNames, ","),
+		peerCerts[0].NotAfter), nil
+}
+
+// diagnoseMacaroon loads the macaroon file at the given path and makes sure it
+// contains a valid macaroon.
+func diagnoseMacaroon(path string) (serializedMacaroon, error) {
+	macBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	mac := &macaroon.Macaroon{}
+	if err := mac.UnmarshalBinary(macBytes); err != nil {
+		return "", fmt.Errorf("%v is not a valid macaroon: %v", path,
+			err)
+	}
+
+	return newSerializedMacaroon(path)
+}
+
+// diagnoseVersion queries lnd's version and describes precisely why it isn't
+// compatible with the expected version, if it isn't.
+func diagnoseVersion(ctx context.Context, client VersionerClient,
+	expected *verrpc.Version) (string, error) {
+
+	version, err := client.GetVersion(ctx)
+	if err != nil {
+		if status.Code(err) == codes.Unimplemented {
+			return "", ErrVersionCheckNotImplemented
+		}
+		return "", err
+	}
+
+	err = assertVersionCompatible(version, expected)
+	if err != nil {
+		return "", fmt.Errorf("lnd %v is too old, at least %v is "+
+			"required", VersionStringShort(version),
+			VersionStringShort(expected))
+	}
+
+	tagMap := make(map[string]struct{})
+	for _, tag := range version.BuildTags {
+		tagMap[tag] = struct{}{}
+	}
+	var missing []string
+	for _, required := range expected.BuildTags {
+		if _, ok := tagMap[required]; !ok {
+			missing = append(missing, required)
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("lnd %v is missing the build tags %v",
+			VersionString(version), strings.Join(missing, ","))
+	}
+
+	return VersionString(version), nil
+}
+
+// probeSubserver sends a harmless request to the given subserver to find out
+// if it is reachable with the macaroon in the context. The requests are
+// intentionally invalid so they don't have any side effects. An error other
+// than the ones for a missing subserver, a rejected macaroon or an unreachable
+// node means the subserver received and answered our request.
+func probeSubserver(ctx context.Context, conn *lndConn,
+	subserver string) error {
+
+	var err error
+	switch subserver {
+	case "lightning":
+		_, err = conn.lightning.GetInfo(ctx, &lnrpc.GetInfoRequest{})
+
+	case "chainnotifier":
+		// Zero confirmations are rejected as soon as the notification
+		// is registered, so the stream returns an error right away.
+		var stream chainrpc.ChainNotifier_RegisterConfirmationsNtfnClient
+		stream, err = conn.chainNotifier.RegisterConfirmationsNtfn(
+			ctx, &chainrpc.ConfRequest{},
+		)
+		if err == nil {
+			_, err = stream.Recv()
+		}
+
+	case "signer":
+		_, err = conn.signer.VerifyMessage(
+			ctx, &signrpc.VerifyMessageReq{},
+		)
+
+	case "walletkit":
+		_, err = conn.walletKit.PendingSweeps(
+			ctx, &walletrpc.PendingSweepsRequest{},
+		)
+
+	case "invoices":
+		_, err = conn.invoices.CancelInvoice(
+			ctx, &invoicesrpc.CancelInvoiceMsg{},
+		)
+
+	case "router":
+		_, err = conn.router.QueryProbability(
+			ctx, &routerrpc.QueryProbabilityRequest{},
+		)
+
+	default:
+		return fmt.Errorf("unknown subserver %v", subserver)
+	}
+
+	switch status.Code(err) {
+	case codes.Unimplemented:
+		return fmt.Errorf("subserver not available, lnd might have "+
+			"been built without the required build tag: %v", err)
+
+	case codes.Unauthenticated, codes.PermissionDenied:
+		return fmt.Errorf("macaroon rejected: %v", err)
+
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Canceled:
+		return err
+	}
+
+	// lnd doesn't use a specific status code for macaroon errors, so we
+	// need to look at the message.
+	if err != nil && isMacaroonErr(err) {
+		return fmt.Errorf("macaroon rejected: %v", err)
+	}
+
+	return nil
+}
+
+// isMacaroonErr returns true if the error was returned by lnd because the
+// macaroon was missing, invalid or didn't grant the permissions needed.
+func isMacaroonErr(err error) bool {
+	msg := status.Convert(err).Message()
+	for _, macErr := range []string{
+		"macaroon", "verification failed", "permission denied",
+	} {
+		if strings.Contains(msg, macErr) {
+			return true
+		}
+	}
+
+	return false
+}
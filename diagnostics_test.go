@@ -0,0 +1,68 @@
+package lndclient
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+)
+
+// TestRunDiagnosticsUnreachable makes sure the diagnostics report the dial
+// step as the failure if lnd isn't reachable, skip all steps that depend on
+// the connection and still check the macaroons.
+func TestRunDiagnosticsUnreachable(t *testing.T) {
+	// Reserve a port and close the listener again, so we know nothing is
+	// listening on it.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	addr := listener.Addr().String()
+	if err := listener.Close(); err != nil {
+		t.Fatalf("unable to close listener: %v", err)
+	}
+
+	macDir, err := ioutil.TempDir("", "lndclient")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(macDir)
+
+	cfg := &LndServicesConfig{
+		LndAddress:  addr,
+		Network:     NetworkRegtest,
+		MacaroonDir: macDir,
+	}
+	report := RunDiagnostics(context.Background(), cfg)
+
+	failed := report.FirstFailure()
+	if failed == nil || failed.Name != "dial" {
+		t.Fatalf("expected dial step to fail, got %v", failed)
+	}
+
+	// The configuration must not have been modified.
+	if cfg.Dialer != nil || cfg.CheckVersion != nil {
+		t.Fatalf("configuration was modified")
+	}
+
+	for _, step := range report.Steps {
+		switch step.Name {
+		case "configuration":
+			if step.Status != DiagnosticPassed {
+				t.Fatalf("expected configuration to pass: %v",
+					step.Error)
+			}
+
+		case "macaroon readonly.macaroon", "macaroon admin.macaroon":
+			if step.Status != DiagnosticFailed {
+				t.Fatalf("expected %v to fail", step.Name)
+			}
+
+		case "tls handshake", "getinfo", "version", "subserver router":
+			if step.Status != DiagnosticSkipped {
+				t.Fatalf("expected %v to be skipped", step.Name)
+			}
+		}
+	}
+}
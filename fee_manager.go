@@ -0,0 +1,558 @@
+package lndclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+const (
+	// defaultFeeWindow is the default period of forwarding history that
+	// fee strategies are given.
+	defaultFeeWindow = 7 * 24 * time.Hour
+
+	// defaultFeeUpdateInterval is the default minimum time between two
+	// fee updates of a channel.
+	defaultFeeUpdateInterval = 6 * time.Hour
+
+	// forwardingPageSize is the number of forwarding events that are
+	// queried at once.
+	forwardingPageSize = 10000
+)
+
+// FeePolicy is the routing fee of a channel.
+type FeePolicy struct {
+	// BaseFeeMsat is the fee charged per forwarded HTLC.
+	BaseFeeMsat int64
+
+	// FeeRatePpm is the proportional fee in parts per million of the
+	// forwarded amount.
+	FeeRatePpm int64
+}
+
+// String returns a human readable representation of the fee policy.
+func (f FeePolicy) String() string {
+	return fmt.Sprintf("base=%v msat, rate=%v ppm", f.BaseFeeMsat,
+		f.FeeRatePpm)
+}
+
+// ForwardingStats summarizes the forwards through a channel.
+type ForwardingStats struct {
+	// Count is the number of HTLCs forwarded out through the channel.
+	Count int
+
+	// VolumeOut is the amount forwarded out through the channel.
+	VolumeOut lnwire.MilliSatoshi
+
+	// VolumeIn is the amount that entered through the channel to be
+	// forwarded elsewhere.
+	VolumeIn lnwire.MilliSatoshi
+
+	// FeesEarned is the fees earned by forwarding out through the
+	// channel.
+	FeesEarned lnwire.MilliSatoshi
+}
+
+// ChannelFeeState is the input of a fee strategy for a single channel.
+type ChannelFeeState struct {
+	// Channel is the channel to set the fee for.
+	Channel ChannelInfo
+
+	// Policy is our current routing policy of the channel.
+	Policy *RoutingPolicy
+
+	// Forwards summarizes the forwards through the channel within the
+	// window of the fee manager.
+	Forwards ForwardingStats
+
+	// Window is the period that the forwarding stats cover.
+	Window time.Duration
+}
+
+// currentFee returns the current fee policy of the channel.
+func (c *ChannelFeeState) currentFee() FeePolicy {
+	return FeePolicy{
+		BaseFeeMsat: c.Policy.FeeBaseMsat,
+		FeeRatePpm:  c.Policy.FeeRateMilliMsat,
+	}
+}
+
+// FeeStrategy returns the fee policy for a channel. It may return nil to keep
+// the current policy of the channel.
+type FeeStrategy func(ctx context.Context,
+	state *ChannelFeeState) (*FeePolicy, error)
+
+// BalanceRatioStrategy sets the fee rate from the share of the channel
+// capacity that is on our side. The fee rate follows a curve from maxPpm for
+// an empty channel to minPpm for a full channel. An exponent of one gives a
+// linear curve, higher exponents keep fees low until the channel is nearly
+// depleted, lower exponents raise them early. The exponent must be positive.
+// The base fee is set to baseFeeMsat.
+func BalanceRatioStrategy(baseFeeMsat, minPpm, maxPpm int64,
+	exponent float64) (FeeStrategy, error) {
+
+	if exponent <= 0 {
+		return nil, fmt.Errorf("exponent must be positive, got %v",
+			exponent)
+	}
+
+	return func(_ context.Context, state *ChannelFeeState) (*FeePolicy,
+		error) {
+
+		if state.Channel.Capacity == 0 {
+			return nil, nil
+		}
+
+		depletion := 1 - float64(state.Channel.LocalBalance)/
+			float64(state.Channel.Capacity)
+		if depletion < 0 {
+			depletion = 0
+		}
+
+		curve := math.Pow(depletion, exponent)
+
+		return &FeePolicy{
+			BaseFeeMsat: baseFeeMsat,
+			FeeRatePpm: minPpm + int64(
+				float64(maxPpm-minPpm)*curve,
+			),
+		}, nil
+	}, nil
+}
+
+// ForwardVolumeStrategy raises the fee rate of channels that forwarded more
+// than the target volume within the window and lowers it for channels that
+// didn't forward at all, by stepPpm within the bounds provided. The base fee
+// is kept.
+func ForwardVolumeStrategy(target lnwire.MilliSatoshi, stepPpm, minPpm,
+	maxPpm int64) FeeStrategy {
+
+	return func(_ context.Context, state *ChannelFeeState) (*FeePolicy,
+		error) {
+
+		fee := state.currentFee()
+		switch {
+		case state.Forwards.VolumeOut > target:
+			fee.FeeRatePpm += stepPpm
+
+		case state.Forwards.Count == 0:
+			fee.FeeRatePpm -= stepPpm
+
+		default:
+			return nil, nil
+		}
+
+		if fee.FeeRatePpm < minPpm {
+			fee.FeeRatePpm = minPpm
+		}
+		if fee.FeeRatePpm > maxPpm {
+			fee.FeeRatePpm = maxPpm
+		}
+
+		return &fee, nil
+	}
+}
+
+// CompetitorFeeStrategy sets the fee rate to the median rate that other nodes
+// charge for forwarding to the same peer, as reported by their channel
+// policies, plus offsetPpm. The base fee is kept. Channels to peers without
+// other channels keep their policy.
+//
+// The policies are read with a single GetNodeInfo call that includes the
+// channels of the peer, rather than with GetChanInfo for every channel of the
+// peer, which would take hundreds of calls for well connected peers.
+func CompetitorFeeStrategy(lnd LightningClient,
+	offsetPpm int64) FeeStrategy {
+
+	return func(ctx context.Context, state *ChannelFeeState) (*FeePolicy,
+		error) {
+
+		peer := state.Channel.PubKeyBytes
+		info, err := lnd.GetNodeInfo(ctx, peer, true)
+		if err != nil {
+			return nil, err
+		}
+
+		var rates []int64
+		for _, edge := range info.Channels {
+			if edge.ChannelId == state.Channel.ChannelID {
+				continue
+			}
+
+			// The competitor's policy is the one of the node on
+			// the other end of the peer's channel.
+			policy := edge.Node1Policy
+			if edge.Node1 == peer {
+				policy = edge.Node2Policy
+			}
+			if policy == nil || policy.Disabled {
+				continue
+			}
+
+			rates = append(rates, policy.FeeRateMilliMsat)
+		}
+
+		if len(rates) == 0 {
+			return nil, nil
+		}
+
+		sort.Slice(rates, func(i, j int) bool {
+			return rates[i] < rates[j]
+		})
+
+		fee := state.currentFee()
+		fee.FeeRatePpm = rates[len(rates)/2] + offsetPpm
+		if fee.FeeRatePpm < 0 {
+			fee.FeeRatePpm = 0
+		}
+
+		return &fee, nil
+	}
+}
+
+// FeeManagerConfig holds the configuration of a fee manager.
+type FeeManagerConfig struct {
+	// Lnd is the client used to read channels and update policies.
+	Lnd LightningClient
+
+	// Strategy sets the fee of every channel.
+	Strategy FeeStrategy
+
+	// Window is the period of forwarding history that the strategy is
+	// given. If zero, seven days are used.
+	Window time.Duration
+
+	// MinUpdateInterval is the minimum time between two updates of a
+	// channel. If zero, six hours are used.
+	MinUpdateInterval time.Duration
+
+	// MaxStepPpm limits the change of the fee rate in a single update. If
+	// zero, the change is not limited.
+	MaxStepPpm int64
+
+	// MinChangePpm is the smallest change of the fee rate that is applied,
+	// which avoids gossiping insignificant updates. Base fee changes are
+	// always applied.
+	MinChangePpm int64
+
+	// DryRun reports the updates that would be applied without applying
+	// them.
+	DryRun bool
+}
+
+// FeeUpdate is the result of the fee manager for a single channel.
+type FeeUpdate struct {
+	// ChannelID is the short channel ID of the channel.
+	ChannelID uint64
+
+	// Peer is the remote node of the channel.
+	Peer route.Vertex
+
+	// Old is the fee policy before the update.
+	Old FeePolicy
+
+	// New is the fee policy set by the strategy.
+	New FeePolicy
+
+	// Skipped holds the reason the update was not applied, if any.
+	Skipped string
+
+	// Applied indicates that the update was applied.
+	Applied bool
+
+	// Err holds the error that prevented the update.
+	Err error
+}
+
+// FeeManagerReport lists the fee updates of a run of the fee manager.
+type FeeManagerReport struct {
+	// Time is the time of the run.
+	Time time.Time
+
+	// DryRun indicates that no updates were applied.
+	DryRun bool
+
+	// Updates holds one entry per channel, ordered by channel ID.
+	Updates []*FeeUpdate
+}
+
+// FeeManager adjusts the routing fees of all channels with a strategy. Changes
+// are rate limited per channel and bounded in size.
+type FeeManager struct {
+	cfg FeeManagerConfig
+
+	lastUpdate map[uint64]time.Time
+	mtx        sync.Mutex
+}
+
+// NewFeeManager creates a new fee manager.
+func NewFeeManager(cfg FeeManagerConfig) (*FeeManager, error) {
+	if cfg.Strategy == nil {
+		return nil, errors.New("fee strategy required")
+	}
+
+	if cfg.Window == 0 {
+		cfg.Window = defaultFeeWindow
+	}
+	if cfg.MinUpdateInterval == 0 {
+		cfg.MinUpdateInterval = defaultFeeUpdateInterval
+	}
+
+	return &FeeManager{
+		cfg:        cfg,
+		lastUpdate: make(map[uint64]time.Time),
+	}, nil
+}
+
+// Run updates fees at the interval provided until the context is cancelled.
+func (m *FeeManager) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := m.Update(ctx)
+		if err != nil {
+			log.Errorf("Fee update failed: %v", err)
+		} else {
+			log.Infof("Fee update: %v channels updated",
+				report.applied())
+		}
+
+		select {
+		case <-ticker.C:
+
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// applied returns the number of updates that were applied.
+func (r *FeeManagerReport) applied() int {
+	var applied int
+	for _, update := range r.Updates {
+		if update.Applied {
+			applied++
+		}
+	}
+
+	return applied
+}
+
+// Update runs the strategy for every channel and applies the resulting fee
+// changes, unless the manager runs dry.
+func (m *FeeManager) Update(ctx context.Context) (*FeeManagerReport, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	now := time.Now()
+
+	channels, err := m.cfg.Lnd.ListChannels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := forwardingStats(ctx, m.cfg.Lnd, now.Add(-m.cfg.Window),
+		now)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &FeeManagerReport{
+		Time:   now,
+		DryRun: m.cfg.DryRun,
+	}
+
+	var (
+		policyUpdates []ChannelPolicyUpdate
+		pending       []*FeeUpdate
+	)
+	for _, channel := range channels {
+		update, policyUpdate := m.channelUpdate(
+			ctx, now, channel, stats[channel.ChannelID],
+		)
+		report.Updates = append(report.Updates, update)
+
+		if policyUpdate != nil {
+			policyUpdates = append(policyUpdates, *policyUpdate)
+			pending = append(pending, update)
+		}
+	}
+
+	sort.Slice(report.Updates, func(i, j int) bool {
+		return report.Updates[i].ChannelID < report.Updates[j].ChannelID
+	})
+
+	if m.cfg.DryRun || len(policyUpdates) == 0 {
+		return report, nil
+	}
+
+	// UpdateChanPolicies reports updates that lnd didn't apply as failed,
+	// so only channels whose policy changed are rate limited.
+	results := m.cfg.Lnd.UpdateChanPolicies(ctx, policyUpdates)
+	for i, result := range results {
+		if result.Err != nil {
+			pending[i].Err = result.Err
+			continue
+		}
+
+		pending[i].Applied = true
+		m.lastUpdate[pending[i].ChannelID] = now
+	}
+
+	return report, nil
+}
+
+// channelUpdate runs the strategy for a channel. The policy update to apply is
+// returned along with the update, or nil if the fee is not changed.
+func (m *FeeManager) channelUpdate(ctx context.Context, now time.Time,
+	channel ChannelInfo, stats ForwardingStats) (*FeeUpdate,
+	*ChannelPolicyUpdate) {
+
+	update := &FeeUpdate{
+		ChannelID: channel.ChannelID,
+		Peer:      channel.PubKeyBytes,
+	}
+
+	policy, err := ourPolicy(ctx, m.cfg.Lnd, channel)
+	if err != nil {
+		update.Err = err
+		return update, nil
+	}
+
+	state := &ChannelFeeState{
+		Channel:  channel,
+		Policy:   policy,
+		Forwards: stats,
+		Window:   m.cfg.Window,
+	}
+	update.Old = state.currentFee()
+	update.New = update.Old
+
+	fee, err := m.cfg.Strategy(ctx, state)
+	if err != nil {
+		update.Err = err
+		return update, nil
+	}
+	if fee == nil {
+		update.Skipped = "kept by strategy"
+		return update, nil
+	}
+
+	update.New = m.limitStep(update.Old, *fee)
+
+	change := update.New.FeeRatePpm - update.Old.FeeRatePpm
+	if change < 0 {
+		change = -change
+	}
+
+	switch {
+	case update.New == update.Old:
+		update.Skipped = "unchanged"
+		return update, nil
+
+	case update.New.BaseFeeMsat == update.Old.BaseFeeMsat &&
+		change < m.cfg.MinChangePpm:
+
+		update.Skipped = fmt.Sprintf("change of %v ppm below minimum",
+			change)
+		return update, nil
+
+	case now.Sub(m.lastUpdate[channel.ChannelID]) <
+		m.cfg.MinUpdateInterval:
+
+		update.Skipped = "rate limited"
+		return update, nil
+	}
+
+	point, err := NewOutpointFromStr(channel.ChannelPoint)
+	if err != nil {
+		update.Err = err
+		return update, nil
+	}
+
+	return update, &ChannelPolicyUpdate{
+		ChannelPoint: *point,
+		Policy: PolicyUpdateRequest{
+			BaseFeeMsat:   update.New.BaseFeeMsat,
+			FeeRate:       float64(update.New.FeeRatePpm) / 1e6,
+			TimeLockDelta: policy.TimeLockDelta,
+		},
+	}
+}
+
+// limitStep bounds the change of the fee rate to the maximum step.
+func (m *FeeManager) limitStep(old, fee FeePolicy) FeePolicy {
+	if m.cfg.MaxStepPpm == 0 {
+		return fee
+	}
+
+	if fee.FeeRatePpm > old.FeeRatePpm+m.cfg.MaxStepPpm {
+		fee.FeeRatePpm = old.FeeRatePpm + m.cfg.MaxStepPpm
+	}
+	if fee.FeeRatePpm < old.FeeRatePpm-m.cfg.MaxStepPpm {
+		fee.FeeRatePpm = old.FeeRatePpm - m.cfg.MaxStepPpm
+	}
+
+	return fee
+}
+
+// ourPolicy returns our routing policy of a channel.
+func ourPolicy(ctx context.Context, lnd LightningClient,
+	channel ChannelInfo) (*RoutingPolicy, error) {
+
+	edge, err := lnd.GetChanInfo(ctx, channel.ChannelID)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := edge.Node1Policy
+	if edge.Node1 == channel.PubKeyBytes {
+		policy = edge.Node2Policy
+	}
+	if policy == nil {
+		return nil, fmt.Errorf("no policy for channel %v",
+			channel.ChannelID)
+	}
+
+	return policy, nil
+}
+
+// forwardingStats summarizes the forwarding history between the start and end
+// time per channel.
+func forwardingStats(ctx context.Context, lnd LightningClient, start,
+	end time.Time) (map[uint64]ForwardingStats, error) {
+
+	stats := make(map[uint64]ForwardingStats)
+
+	iter := NewForwardingIterator(lnd, HistoryQuery{
+		PageSize:  forwardingPageSize,
+		StartTime: start,
+		EndTime:   end,
+	})
+	for iter.Next(ctx) {
+		event := iter.Event()
+
+		out := stats[event.ChannelOut]
+		out.Count++
+		out.VolumeOut += event.AmountMsatOut
+		out.FeesEarned += event.FeeMsat
+		stats[event.ChannelOut] = out
+
+		in := stats[event.ChannelIn]
+		in.VolumeIn += event.AmountMsatIn
+		stats[event.ChannelIn] = in
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
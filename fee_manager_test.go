@@ -0,0 +1,173 @@
+package lndclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// TestFeeManager tests that fees follow the strategy within the step limit,
+// that dry runs don't apply updates and that updates are rate limited.
+func TestFeeManager(t *testing.T) {
+	peer := route.Vertex{1}
+	point := "0000000000000000000000000000000000000000000000000000000000000001:0"
+
+	policy := &RoutingPolicy{
+		FeeBaseMsat:      1000,
+		FeeRateMilliMsat: 100,
+		TimeLockDelta:    40,
+	}
+
+	lnd := &mockLightning{
+		channels: []ChannelInfo{{
+			ChannelID:    2,
+			ChannelPoint: point,
+			PubKeyBytes:  peer,
+			Capacity:     1000000,
+			LocalBalance: 250000,
+		}},
+		forwards: []ForwardingEvent{{
+			ChannelIn:     1,
+			ChannelOut:    2,
+			AmountMsatIn:  1001000,
+			AmountMsatOut: 1000000,
+			FeeMsat:       1000,
+		}},
+		edges: map[uint64]*ChannelEdge{
+			2: {
+				ChannelId: 2,
+				Node1:     peer,
+				Node2:     route.Vertex{2},
+				Node1Policy: &RoutingPolicy{
+					FeeRateMilliMsat: 5000,
+				},
+				Node2Policy: policy,
+			},
+		},
+	}
+
+	strategy, err := BalanceRatioStrategy(1000, 0, 1000, 1)
+	if err != nil {
+		t.Fatalf("unable to create strategy: %v", err)
+	}
+
+	cfg := FeeManagerConfig{
+		Lnd:        lnd,
+		Strategy:   strategy,
+		MaxStepPpm: 500,
+		DryRun:     true,
+	}
+	manager, err := NewFeeManager(cfg)
+	if err != nil {
+		t.Fatalf("unable to create fee manager: %v", err)
+	}
+
+	// The channel is 75% depleted, so the strategy asks for 750 ppm, which
+	// is limited to a step of 500 ppm.
+	report, err := manager.Update(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(report.Updates) != 1 || report.Updates[0].New.FeeRatePpm != 600 ||
+		report.Updates[0].Applied || len(lnd.updates) != 0 {
+
+		t.Fatalf("unexpected dry run report: %v", report.Updates[0])
+	}
+
+	cfg.DryRun = false
+	manager, err = NewFeeManager(cfg)
+	if err != nil {
+		t.Fatalf("unable to create fee manager: %v", err)
+	}
+
+	// Updates that lnd didn't apply are reported and don't count towards
+	// the rate limit.
+	lnd.updateErr = errors.New("policy not applied")
+	report, err = manager.Update(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Updates[0].Applied || report.Updates[0].Err == nil {
+		t.Fatalf("expected failed update: %v", report.Updates[0])
+	}
+	lnd.updateErr = nil
+	lnd.updates = nil
+
+	report, err = manager.Update(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !report.Updates[0].Applied || len(lnd.updates) != 1 {
+		t.Fatalf("update not applied: %v", report.Updates[0])
+	}
+
+	update := lnd.updates[0]
+	if update.Policy.FeeRate != 0.0006 || update.Policy.BaseFeeMsat != 1000 ||
+		update.Policy.TimeLockDelta != 40 {
+
+		t.Fatalf("unexpected policy update: %v", update.Policy)
+	}
+
+	// A second run right away is rate limited.
+	policy.FeeRateMilliMsat = 600
+	report, err = manager.Update(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Updates[0].Skipped != "rate limited" || len(lnd.updates) != 1 {
+		t.Fatalf("expected rate limited update: %v", report.Updates[0])
+	}
+}
+
+// TestForwardVolumeStrategy tests that busy channels get more expensive and
+// idle channels get cheaper.
+func TestForwardVolumeStrategy(t *testing.T) {
+	strategy := ForwardVolumeStrategy(500000, 50, 10, 1000)
+
+	state := &ChannelFeeState{
+		Policy: &RoutingPolicy{FeeRateMilliMsat: 100},
+		Forwards: ForwardingStats{
+			Count:     1,
+			VolumeOut: 1000000,
+		},
+	}
+	fee, err := strategy(context.Background(), state)
+	if err != nil || fee.FeeRatePpm != 150 {
+		t.Fatalf("expected raised fee, got %v: %v", fee, err)
+	}
+
+	state.Forwards = ForwardingStats{}
+	fee, err = strategy(context.Background(), state)
+	if err != nil || fee.FeeRatePpm != 50 {
+		t.Fatalf("expected lowered fee, got %v: %v", fee, err)
+	}
+}
+
+// TestBalanceRatioStrategy tests that the fee curve follows fractional
+// exponents and that exponents that aren't positive are rejected.
+func TestBalanceRatioStrategy(t *testing.T) {
+	if _, err := BalanceRatioStrategy(0, 0, 1000, 0); err == nil {
+		t.Fatalf("expected zero exponent to be rejected")
+	}
+
+	strategy, err := BalanceRatioStrategy(0, 0, 1000, 0.5)
+	if err != nil {
+		t.Fatalf("unable to create strategy: %v", err)
+	}
+
+	// The channel is 75% depleted, so the square root gives 866 ppm.
+	fee, err := strategy(context.Background(), &ChannelFeeState{
+		Channel: ChannelInfo{
+			Capacity:     1000000,
+			LocalBalance: 250000,
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fee.FeeRatePpm != 866 {
+		t.Fatalf("expected 866 ppm, got %v", fee.FeeRatePpm)
+	}
+}
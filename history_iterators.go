@@ -0,0 +1,490 @@
+package lndclient
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// defaultHistoryPageSize is the default number of items that iterators query
+// at once.
+const defaultHistoryPageSize = 1000
+
+// errIteratorDone is set once an iterator reached the end of its bounds.
+var errIteratorDone = errors.New("iterator done")
+
+// HistoryQuery holds the bounds of a history iterator. Zero values leave a
+// bound open.
+type HistoryQuery struct {
+	// PageSize is the number of items queried at once. If zero, 1000 is
+	// used. For transactions it is the number of blocks, and if zero all
+	// blocks are queried at once.
+	PageSize uint64
+
+	// StartTime is the earliest time of items to return.
+	StartTime time.Time
+
+	// EndTime is the latest time of items to return.
+	EndTime time.Time
+
+	// StartIndex is the index after which iteration starts. Passing the
+	// cursor of an iterator resumes after the last item it returned.
+	StartIndex uint64
+
+	// EndIndex is the last index of items to return.
+	EndIndex uint64
+}
+
+// pageSize returns the page size of the query.
+func (q *HistoryQuery) pageSize(defaultSize uint64) uint64 {
+	if q.PageSize == 0 {
+		return defaultSize
+	}
+
+	return q.PageSize
+}
+
+// beforeStart returns whether the time provided is before the start time.
+func (q *HistoryQuery) beforeStart(t time.Time) bool {
+	return !q.StartTime.IsZero() && t.Before(q.StartTime)
+}
+
+// afterEnd returns whether the time provided is after the end time.
+func (q *HistoryQuery) afterEnd(t time.Time) bool {
+	return !q.EndTime.IsZero() && t.After(q.EndTime)
+}
+
+// pastEndIndex returns whether the index provided is after the end index.
+func (q *HistoryQuery) pastEndIndex(index uint64) bool {
+	return q.EndIndex != 0 && index > q.EndIndex
+}
+
+// ForwardingIterator pages lazily through the forwarding history. Forwarding
+// events are only indexed by their position within the time bounds, so a
+// cursor only resumes an iterator with the same start time.
+type ForwardingIterator struct {
+	lnd   LightningClient
+	query HistoryQuery
+
+	page    []ForwardingEvent
+	offset  uint64
+	current ForwardingEvent
+	err     error
+}
+
+// NewForwardingIterator creates an iterator over the forwarding history.
+func NewForwardingIterator(lnd LightningClient,
+	query HistoryQuery) *ForwardingIterator {
+
+	return &ForwardingIterator{
+		lnd:    lnd,
+		query:  query,
+		offset: query.StartIndex,
+	}
+}
+
+// Next advances to the next forwarding event. It returns false once all
+// events were returned or an error occurred, which is reported by Err.
+func (f *ForwardingIterator) Next(ctx context.Context) bool {
+	if f.err != nil {
+		return false
+	}
+
+	if len(f.page) == 0 {
+		if err := ctx.Err(); err != nil {
+			f.err = err
+			return false
+		}
+
+		pageSize := f.query.pageSize(defaultHistoryPageSize)
+
+		// lnd takes unix timestamps and requires an end time, so we
+		// fill in open bounds.
+		startTime := f.query.StartTime
+		if startTime.IsZero() {
+			startTime = time.Unix(0, 0)
+		}
+
+		endTime := f.query.EndTime
+		if endTime.IsZero() {
+			endTime = time.Now()
+		}
+
+		resp, err := f.lnd.ForwardingHistory(
+			ctx, ForwardingHistoryRequest{
+				StartTime: startTime,
+				EndTime:   endTime,
+				Offset:    uint32(f.offset),
+				MaxEvents: uint32(pageSize),
+			},
+		)
+		if err != nil {
+			f.err = err
+			return false
+		}
+
+		if len(resp.Events) == 0 {
+			f.err = errIteratorDone
+			return false
+		}
+		f.page = resp.Events
+	}
+
+	if f.query.pastEndIndex(f.offset + 1) {
+		f.err = errIteratorDone
+		return false
+	}
+
+	f.current = f.page[0]
+	f.page = f.page[1:]
+	f.offset++
+
+	return true
+}
+
+// Event returns the current forwarding event.
+func (f *ForwardingIterator) Event() ForwardingEvent {
+	return f.current
+}
+
+// Cursor returns the index of the current event, from which a new iterator
+// can resume.
+func (f *ForwardingIterator) Cursor() uint64 {
+	return f.offset
+}
+
+// Err returns the error that stopped the iterator, if any.
+func (f *ForwardingIterator) Err() error {
+	if f.err == errIteratorDone {
+		return nil
+	}
+
+	return f.err
+}
+
+// InvoiceIterator pages lazily through invoices in the order they were added.
+// The cursor is the add index of the current invoice.
+type InvoiceIterator struct {
+	lnd         LightningClient
+	query       HistoryQuery
+	pendingOnly bool
+
+	page    []Invoice
+	offset  uint64
+	current Invoice
+	err     error
+}
+
+// NewInvoiceIterator creates an iterator over our invoices. If pendingOnly is
+// set, only invoices that are not settled or canceled are returned.
+func NewInvoiceIterator(lnd LightningClient, query HistoryQuery,
+	pendingOnly bool) *InvoiceIterator {
+
+	return &InvoiceIterator{
+		lnd:         lnd,
+		query:       query,
+		pendingOnly: pendingOnly,
+		offset:      query.StartIndex,
+	}
+}
+
+// Next advances to the next invoice. It returns false once all invoices were
+// returned or an error occurred, which is reported by Err.
+func (i *InvoiceIterator) Next(ctx context.Context) bool {
+	for i.err == nil {
+		if len(i.page) == 0 {
+			if err := ctx.Err(); err != nil {
+				i.err = err
+				return false
+			}
+
+			resp, err := i.lnd.ListInvoices(
+				ctx, ListInvoicesRequest{
+					Offset: i.offset,
+					MaxInvoices: i.query.pageSize(
+						defaultHistoryPageSize,
+					),
+					PendingOnly: i.pendingOnly,
+				},
+			)
+			if err != nil {
+				i.err = err
+				return false
+			}
+
+			if len(resp.Invoices) == 0 {
+				i.err = errIteratorDone
+				return false
+			}
+			i.page = resp.Invoices
+		}
+
+		invoice := i.page[0]
+		i.page = i.page[1:]
+
+		// Invoices are returned in the order they were added, so we
+		// are done once we passed the end bounds.
+		if i.query.pastEndIndex(invoice.AddIndex) ||
+			i.query.afterEnd(invoice.CreationDate) {
+
+			i.err = errIteratorDone
+			return false
+		}
+
+		i.offset = invoice.AddIndex
+		if i.query.beforeStart(invoice.CreationDate) {
+			continue
+		}
+
+		i.current = invoice
+		return true
+	}
+
+	return false
+}
+
+// Invoice returns the current invoice.
+func (i *InvoiceIterator) Invoice() Invoice {
+	return i.current
+}
+
+// Cursor returns the add index of the current invoice, from which a new
+// iterator can resume.
+func (i *InvoiceIterator) Cursor() uint64 {
+	return i.offset
+}
+
+// Err returns the error that stopped the iterator, if any.
+func (i *InvoiceIterator) Err() error {
+	if i.err == errIteratorDone {
+		return nil
+	}
+
+	return i.err
+}
+
+// PaymentIterator pages lazily through payments in the order they were sent.
+// The cursor is the sequence number of the current payment.
+type PaymentIterator struct {
+	lnd               LightningClient
+	query             HistoryQuery
+	includeIncomplete bool
+
+	page    []Payment
+	offset  uint64
+	current Payment
+	err     error
+}
+
+// NewPaymentIterator creates an iterator over our payments. If
+// includeIncomplete is set, payments that are in flight or failed are
+// returned too.
+func NewPaymentIterator(lnd LightningClient, query HistoryQuery,
+	includeIncomplete bool) *PaymentIterator {
+
+	return &PaymentIterator{
+		lnd:               lnd,
+		query:             query,
+		includeIncomplete: includeIncomplete,
+		offset:            query.StartIndex,
+	}
+}
+
+// Next advances to the next payment. It returns false once all payments were
+// returned or an error occurred, which is reported by Err.
+func (p *PaymentIterator) Next(ctx context.Context) bool {
+	for p.err == nil {
+		if len(p.page) == 0 {
+			if err := ctx.Err(); err != nil {
+				p.err = err
+				return false
+			}
+
+			resp, err := p.lnd.ListPayments(
+				ctx, ListPaymentsRequest{
+					Offset: p.offset,
+					MaxPayments: p.query.pageSize(
+						defaultHistoryPageSize,
+					),
+					IncludeIncomplete: p.includeIncomplete,
+				},
+			)
+			if err != nil {
+				p.err = err
+				return false
+			}
+
+			if len(resp.Payments) == 0 {
+				p.err = errIteratorDone
+				return false
+			}
+			p.page = resp.Payments
+		}
+
+		payment := p.page[0]
+		p.page = p.page[1:]
+
+		// Payments are returned in the order they were created, so
+		// we are done once we passed the end bounds.
+		if p.query.pastEndIndex(payment.SequenceNumber) ||
+			p.query.afterEnd(payment.Timestamp) {
+
+			p.err = errIteratorDone
+			return false
+		}
+
+		p.offset = payment.SequenceNumber
+		if p.query.beforeStart(payment.Timestamp) {
+			continue
+		}
+
+		p.current = payment
+		return true
+	}
+
+	return false
+}
+
+// Payment returns the current payment.
+func (p *PaymentIterator) Payment() Payment {
+	return p.current
+}
+
+// Cursor returns the sequence number of the current payment, from which a new
+// iterator can resume.
+func (p *PaymentIterator) Cursor() uint64 {
+	return p.offset
+}
+
+// Err returns the error that stopped the iterator, if any.
+func (p *PaymentIterator) Err() error {
+	if p.err == errIteratorDone {
+		return nil
+	}
+
+	return p.err
+}
+
+// TransactionIterator pages lazily through confirmed on chain transactions by
+// block height. lnd doesn't paginate transactions, so every page covers a
+// range of blocks and the cursor is the last height that was fully returned.
+// Without a page size, the whole range is a single page, which is cheaper than
+// many small ranges unless the wallet holds a lot of transactions. The start
+// and end index of the query are block heights. Unconfirmed transactions are
+// not returned, as they can't be resumed from.
+type TransactionIterator struct {
+	lnd   LightningClient
+	query HistoryQuery
+
+	page      []Transaction
+	height    uint64
+	endHeight uint64
+	current   Transaction
+	cursor    uint64
+	err       error
+}
+
+// NewTransactionIterator creates an iterator over our confirmed on chain
+// transactions.
+func NewTransactionIterator(lnd LightningClient,
+	query HistoryQuery) *TransactionIterator {
+
+	return &TransactionIterator{
+		lnd:    lnd,
+		query:  query,
+		height: query.StartIndex,
+		cursor: query.StartIndex,
+	}
+}
+
+// Next advances to the next transaction. It returns false once all
+// transactions were returned or an error occurred, which is reported by Err.
+func (t *TransactionIterator) Next(ctx context.Context) bool {
+	for t.err == nil {
+		if len(t.page) > 0 {
+			tx := t.page[0]
+			t.page = t.page[1:]
+
+			// Transactions within a block range are not ordered
+			// by time, so we filter without stopping.
+			if t.query.beforeStart(tx.Timestamp) ||
+				t.query.afterEnd(tx.Timestamp) {
+
+				continue
+			}
+
+			t.current = tx
+			return true
+		}
+
+		// The previous page was returned in full.
+		t.cursor = t.height
+
+		if err := t.nextPage(ctx); err != nil {
+			t.err = err
+			return false
+		}
+	}
+
+	return false
+}
+
+// nextPage queries the transactions of the next range of blocks. It returns
+// errIteratorDone once the end height was passed.
+func (t *TransactionIterator) nextPage(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Without an end index, we stop at the current height.
+	if t.endHeight == 0 {
+		t.endHeight = t.query.EndIndex
+		if t.endHeight == 0 {
+			info, err := t.lnd.GetInfo(ctx)
+			if err != nil {
+				return err
+			}
+			t.endHeight = uint64(info.BlockHeight)
+		}
+	}
+
+	if t.height >= t.endHeight {
+		return errIteratorDone
+	}
+
+	start := t.height + 1
+	end := t.endHeight
+	if t.query.PageSize != 0 && t.height+t.query.PageSize < end {
+		end = t.height + t.query.PageSize
+	}
+
+	txs, err := t.lnd.ListTransactions(ctx, int32(start), int32(end))
+	if err != nil {
+		return err
+	}
+
+	t.page = txs
+	t.height = end
+
+	return nil
+}
+
+// Transaction returns the current transaction.
+func (t *TransactionIterator) Transaction() Transaction {
+	return t.current
+}
+
+// Cursor returns the last block height whose transactions were all returned,
+// from which a new iterator can resume.
+func (t *TransactionIterator) Cursor() uint64 {
+	return t.cursor
+}
+
+// Err returns the error that stopped the iterator, if any.
+func (t *TransactionIterator) Err() error {
+	if t.err == errIteratorDone {
+		return nil
+	}
+
+	return t.err
+}
@@ -0,0 +1,252 @@
+package lndclient
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// newHistoryLightning returns a lightning client with invoices, forwarding
+// events and payments with indexes 1 to 5, created one hour apart, and
+// transactions at heights 2, 5, 8 and 10, ten minutes apart.
+func newHistoryLightning() *mockLightning {
+	lnd := &mockLightning{
+		info: Info{BlockHeight: 10},
+	}
+
+	for i := uint64(1); i <= 5; i++ {
+		created := time.Unix(int64(i)*3600, 0)
+
+		lnd.invoices = append(lnd.invoices, Invoice{
+			AddIndex:     i,
+			CreationDate: created,
+		})
+		lnd.forwards = append(lnd.forwards, ForwardingEvent{
+			Timestamp:  created,
+			ChannelOut: i,
+		})
+		lnd.payments = append(lnd.payments, Payment{
+			SequenceNumber: i,
+			Timestamp:      created,
+		})
+	}
+
+	for _, height := range []int32{2, 5, 8, 10} {
+		lnd.txs = append(lnd.txs, Transaction{
+			BlockHeight: height,
+			Timestamp:   time.Unix(int64(height)*600, 0),
+		})
+	}
+
+	return lnd
+}
+
+// TestInvoiceIterator tests that the invoice iterator pages through invoices
+// within its bounds and that it can be resumed from its cursor.
+func TestInvoiceIterator(t *testing.T) {
+	ctx := context.Background()
+	lnd := newHistoryLightning()
+
+	query := HistoryQuery{
+		PageSize:  2,
+		StartTime: time.Unix(2*3600, 0),
+		EndIndex:  4,
+	}
+	iter := NewInvoiceIterator(lnd, query, true)
+
+	var indexes []uint64
+	for iter.Next(ctx) {
+		indexes = append(indexes, iter.Invoice().AddIndex)
+
+		// Stop after the first invoice, so that we can resume.
+		if len(indexes) == 1 {
+			break
+		}
+	}
+
+	query.StartIndex = iter.Cursor()
+	iter = NewInvoiceIterator(lnd, query, true)
+	for iter.Next(ctx) {
+		indexes = append(indexes, iter.Invoice().AddIndex)
+	}
+	if err := iter.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(indexes) != 3 || indexes[0] != 2 || indexes[1] != 3 ||
+		indexes[2] != 4 {
+
+		t.Fatalf("unexpected invoices: %v", indexes)
+	}
+
+	for _, req := range lnd.invoiceRequests {
+		if !req.PendingOnly || req.MaxInvoices != 2 {
+			t.Fatalf("unexpected request: %+v", req)
+		}
+	}
+
+	// A canceled context stops the iterator.
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	iter = NewInvoiceIterator(lnd, HistoryQuery{}, false)
+	if iter.Next(canceled) || iter.Err() != context.Canceled {
+		t.Fatalf("expected canceled iterator, got: %v", iter.Err())
+	}
+}
+
+// TestForwardingIterator tests that the forwarding iterator pages through
+// events up to its end index, that it fills in open time bounds and that it
+// can be resumed from its cursor.
+func TestForwardingIterator(t *testing.T) {
+	ctx := context.Background()
+	lnd := newHistoryLightning()
+
+	query := HistoryQuery{
+		PageSize: 2,
+		EndIndex: 4,
+	}
+	iter := NewForwardingIterator(lnd, query)
+
+	var channels []uint64
+	for iter.Next(ctx) {
+		channels = append(channels, iter.Event().ChannelOut)
+
+		// Stop after the first event, so that we can resume.
+		if len(channels) == 1 {
+			break
+		}
+	}
+
+	query.StartIndex = iter.Cursor()
+	iter = NewForwardingIterator(lnd, query)
+	for iter.Next(ctx) {
+		channels = append(channels, iter.Event().ChannelOut)
+	}
+	if err := iter.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(channels) != 4 || channels[0] != 1 || channels[1] != 2 ||
+		channels[2] != 3 || channels[3] != 4 {
+
+		t.Fatalf("unexpected events: %v", channels)
+	}
+
+	for _, req := range lnd.forwardRequests {
+		if !req.StartTime.Equal(time.Unix(0, 0)) ||
+			req.EndTime.IsZero() || req.MaxEvents != 2 {
+
+			t.Fatalf("unexpected request: %+v", req)
+		}
+	}
+}
+
+// TestPaymentIterator tests that the payment iterator pages through payments
+// within its time bounds.
+func TestPaymentIterator(t *testing.T) {
+	ctx := context.Background()
+	lnd := newHistoryLightning()
+
+	iter := NewPaymentIterator(lnd, HistoryQuery{
+		PageSize:  2,
+		StartTime: time.Unix(2*3600, 0),
+		EndTime:   time.Unix(4*3600, 0),
+	}, true)
+
+	var indexes []uint64
+	for iter.Next(ctx) {
+		indexes = append(indexes, iter.Payment().SequenceNumber)
+	}
+	if err := iter.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(indexes) != 3 || indexes[0] != 2 || indexes[1] != 3 ||
+		indexes[2] != 4 || iter.Cursor() != 4 {
+
+		t.Fatalf("unexpected payments: %v, cursor %v", indexes,
+			iter.Cursor())
+	}
+
+	for _, req := range lnd.paymentRequests {
+		if !req.IncludeIncomplete || req.MaxPayments != 2 {
+			t.Fatalf("unexpected request: %+v", req)
+		}
+	}
+}
+
+// TestTransactionIterator tests that the transaction iterator pages through
+// block ranges up to the current height, filters by time, that its cursor
+// only advances once a range was returned in full and that it queries all
+// blocks at once without a page size.
+func TestTransactionIterator(t *testing.T) {
+	ctx := context.Background()
+	lnd := newHistoryLightning()
+
+	query := HistoryQuery{
+		PageSize:  4,
+		StartTime: time.Unix(3*600, 0),
+	}
+	iter := NewTransactionIterator(lnd, query)
+
+	var heights []int32
+	for iter.Next(ctx) {
+		heights = append(heights, iter.Transaction().BlockHeight)
+	}
+	if err := iter.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(heights) != 3 || heights[0] != 5 || heights[1] != 8 ||
+		heights[2] != 10 || iter.Cursor() != 10 {
+
+		t.Fatalf("unexpected transactions: %v, cursor %v", heights,
+			iter.Cursor())
+	}
+
+	if len(lnd.txRanges) != 3 || lnd.txRanges[0] != [2]int32{1, 4} ||
+		lnd.txRanges[1] != [2]int32{5, 8} ||
+		lnd.txRanges[2] != [2]int32{9, 10} {
+
+		t.Fatalf("unexpected ranges: %v", lnd.txRanges)
+	}
+
+	// The cursor stays at the end of the last range that was returned
+	// in full while transactions of the next range are returned.
+	iter = NewTransactionIterator(lnd, query)
+	if !iter.Next(ctx) || iter.Transaction().BlockHeight != 5 ||
+		iter.Cursor() != 4 {
+
+		t.Fatalf("unexpected cursor: %v", iter.Cursor())
+	}
+
+	query.StartIndex = iter.Cursor()
+	query.EndIndex = 8
+	iter = NewTransactionIterator(lnd, query)
+
+	heights = nil
+	for iter.Next(ctx) {
+		heights = append(heights, iter.Transaction().BlockHeight)
+	}
+	if len(heights) != 2 || heights[0] != 5 || heights[1] != 8 {
+		t.Fatalf("unexpected resumed transactions: %v", heights)
+	}
+
+	// Without a page size, all blocks up to the current height are
+	// queried at once.
+	lnd.txRanges = nil
+	iter = NewTransactionIterator(lnd, HistoryQuery{})
+
+	heights = nil
+	for iter.Next(ctx) {
+		heights = append(heights, iter.Transaction().BlockHeight)
+	}
+	if len(heights) != 4 || iter.Cursor() != 10 {
+		t.Fatalf("unexpected transactions: %v, cursor %v", heights,
+			iter.Cursor())
+	}
+	if len(lnd.txRanges) != 1 || lnd.txRanges[0] != [2]int32{1, 10} {
+		t.Fatalf("unexpected ranges: %v", lnd.txRanges)
+	}
+}
@@ -0,0 +1,322 @@
+package lndclient
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+// HtlcEventType indicates whether an HTLC event belongs to a payment we sent,
+// a payment we received or a forward.
+type HtlcEventType uint8
+
+const (
+	// HtlcEventTypeUnknown is set for events of an unknown type.
+	HtlcEventTypeUnknown HtlcEventType = iota
+
+	// HtlcEventTypeSend is set for HTLCs of payments that we send.
+	HtlcEventTypeSend
+
+	// HtlcEventTypeReceive is set for HTLCs of payments that we receive.
+	HtlcEventTypeReceive
+
+	// HtlcEventTypeForward is set for HTLCs that we forward.
+	HtlcEventTypeForward
+)
+
+// String returns a string representation of the event type.
+func (h HtlcEventType) String() string {
+	switch h {
+	case HtlcEventTypeSend:
+		return "send"
+
+	case HtlcEventTypeReceive:
+		return "receive"
+
+	case HtlcEventTypeForward:
+		return "forward"
+
+	default:
+		return "unknown"
+	}
+}
+
+// newHtlcEventType converts an rpc event type.
+func newHtlcEventType(eventType routerrpc.HtlcEvent_EventType) HtlcEventType {
+	switch eventType {
+	case routerrpc.HtlcEvent_SEND:
+		return HtlcEventTypeSend
+
+	case routerrpc.HtlcEvent_RECEIVE:
+		return HtlcEventTypeReceive
+
+	case routerrpc.HtlcEvent_FORWARD:
+		return HtlcEventTypeForward
+
+	default:
+		return HtlcEventTypeUnknown
+	}
+}
+
+// HtlcKey identifies an HTLC by its incoming and outgoing circuit. The
+// incoming circuit is zero for HTLCs we send, the outgoing circuit is zero for
+// HTLCs we receive.
+type HtlcKey struct {
+	// Incoming is the circuit of the incoming HTLC.
+	Incoming CircuitKey
+
+	// Outgoing is the circuit of the outgoing HTLC.
+	Outgoing CircuitKey
+}
+
+// String returns a human readable representation of the HTLC key.
+func (h HtlcKey) String() string {
+	return fmt.Sprintf("%v -> %v", h.Incoming, h.Outgoing)
+}
+
+// HtlcInfo holds the amounts and timelocks of an HTLC.
+type HtlcInfo struct {
+	// IncomingTimelock is the expiry height of the incoming HTLC.
+	IncomingTimelock uint32
+
+	// OutgoingTimelock is the expiry height of the outgoing HTLC.
+	OutgoingTimelock uint32
+
+	// IncomingAmt is the amount of the incoming HTLC.
+	IncomingAmt lnwire.MilliSatoshi
+
+	// OutgoingAmt is the amount of the outgoing HTLC.
+	OutgoingAmt lnwire.MilliSatoshi
+}
+
+// newHtlcInfo converts rpc HTLC info, which may be nil.
+func newHtlcInfo(info *routerrpc.HtlcInfo) HtlcInfo {
+	if info == nil {
+		return HtlcInfo{}
+	}
+
+	return HtlcInfo{
+		IncomingTimelock: info.IncomingTimelock,
+		OutgoingTimelock: info.OutgoingTimelock,
+		IncomingAmt:      lnwire.MilliSatoshi(info.IncomingAmtMsat),
+		OutgoingAmt:      lnwire.MilliSatoshi(info.OutgoingAmtMsat),
+	}
+}
+
+// HtlcEventDetail is implemented by the details of the different HTLC events:
+// *ForwardEvent, *ForwardFailEvent, *SettleEvent and *LinkFailEvent.
+type HtlcEventDetail interface {
+	htlcEventDetail()
+}
+
+// ForwardEvent indicates that an HTLC was forwarded to the outgoing channel.
+type ForwardEvent struct {
+	// Info holds the amounts and timelocks of the HTLC.
+	Info HtlcInfo
+}
+
+func (f *ForwardEvent) htlcEventDetail() {}
+
+// ForwardFailEvent indicates that a forwarded HTLC was failed by a downstream
+// node. The failure reason is encrypted, so it isn't known to us.
+type ForwardFailEvent struct{}
+
+func (f *ForwardFailEvent) htlcEventDetail() {}
+
+// SettleEvent indicates that an HTLC was settled.
+type SettleEvent struct{}
+
+func (s *SettleEvent) htlcEventDetail() {}
+
+// LinkFailEvent indicates that an HTLC was failed by our node before it was
+// forwarded.
+type LinkFailEvent struct {
+	// Info holds the amounts and timelocks of the HTLC.
+	Info HtlcInfo
+
+	// WireFailure is the failure that was sent back to the sender.
+	WireFailure lnrpc.Failure_FailureCode
+
+	// FailureDetail is the internal reason for the failure, which adds
+	// detail to the wire failure.
+	FailureDetail routerrpc.FailureDetail
+
+	// FailureString is a human readable description of the failure.
+	FailureString string
+}
+
+func (l *LinkFailEvent) htlcEventDetail() {}
+
+// Reason returns a human readable description of the failure.
+func (l *LinkFailEvent) Reason() string {
+	reason := l.WireFailure.String()
+	if l.FailureDetail != routerrpc.FailureDetail_NO_DETAIL &&
+		l.FailureDetail != routerrpc.FailureDetail_UNKNOWN {
+
+		reason += fmt.Sprintf(" (%v)", l.FailureDetail)
+	}
+	if l.FailureString != "" {
+		reason += ": " + l.FailureString
+	}
+
+	return reason
+}
+
+// HtlcEvent is an event of an HTLC that we send, receive or forward.
+type HtlcEvent struct {
+	// Key identifies the HTLC.
+	Key HtlcKey
+
+	// Timestamp is the time the event occurred.
+	Timestamp time.Time
+
+	// EventType is the type of payment the HTLC belongs to.
+	EventType HtlcEventType
+
+	// Event holds the details of the event.
+	Event HtlcEventDetail
+}
+
+// newHtlcEvent converts an rpc HTLC event. It returns nil for events that we
+// don't know about.
+func newHtlcEvent(event *routerrpc.HtlcEvent) *HtlcEvent {
+	var detail HtlcEventDetail
+	switch e := event.Event.(type) {
+	case *routerrpc.HtlcEvent_ForwardEvent:
+		detail = &ForwardEvent{
+			Info: newHtlcInfo(e.ForwardEvent.Info),
+		}
+
+	case *routerrpc.HtlcEvent_ForwardFailEvent:
+		detail = &ForwardFailEvent{}
+
+	case *routerrpc.HtlcEvent_SettleEvent:
+		detail = &SettleEvent{}
+
+	case *routerrpc.HtlcEvent_LinkFailEvent:
+		detail = &LinkFailEvent{
+			Info:          newHtlcInfo(e.LinkFailEvent.Info),
+			WireFailure:   e.LinkFailEvent.WireFailure,
+			FailureDetail: e.LinkFailEvent.FailureDetail,
+			FailureString: e.LinkFailEvent.FailureString,
+		}
+
+	default:
+		return nil
+	}
+
+	return &HtlcEvent{
+		Key: HtlcKey{
+			Incoming: CircuitKey{
+				ChanID: lnwire.NewShortChanIDFromInt(
+					event.IncomingChannelId,
+				),
+				HtlcID: event.IncomingHtlcId,
+			},
+			Outgoing: CircuitKey{
+				ChanID: lnwire.NewShortChanIDFromInt(
+					event.OutgoingChannelId,
+				),
+				HtlcID: event.OutgoingHtlcId,
+			},
+		},
+		Timestamp: time.Unix(0, int64(event.TimestampNs)),
+		EventType: newHtlcEventType(event.EventType),
+		Event:     detail,
+	}
+}
+
+// ResolvedHtlc is an HTLC that was settled or failed.
+type ResolvedHtlc struct {
+	// Key identifies the HTLC.
+	Key HtlcKey
+
+	// EventType is the type of payment the HTLC belongs to.
+	EventType HtlcEventType
+
+	// Forward is the event of the HTLC being forwarded. It is nil if the
+	// HTLC failed before it was forwarded, or if the forward happened
+	// before the correlator was started.
+	Forward *HtlcEvent
+
+	// Resolution is the settle or fail event of the HTLC.
+	Resolution *HtlcEvent
+
+	// Settled is true if the HTLC was settled.
+	Settled bool
+}
+
+// Duration returns the time between the forward and the resolution of the
+// HTLC. It is zero if the forward is unknown.
+func (r *ResolvedHtlc) Duration() time.Duration {
+	if r.Forward == nil {
+		return 0
+	}
+
+	return r.Resolution.Timestamp.Sub(r.Forward.Timestamp)
+}
+
+// HtlcCorrelator matches forward events with the settle or fail events that
+// resolve them, based on their HTLC key.
+type HtlcCorrelator struct {
+	pending map[HtlcKey]*HtlcEvent
+	mtx     sync.Mutex
+}
+
+// NewHtlcCorrelator creates a new HTLC correlator.
+func NewHtlcCorrelator() *HtlcCorrelator {
+	return &HtlcCorrelator{
+		pending: make(map[HtlcKey]*HtlcEvent),
+	}
+}
+
+// AddEvent adds an HTLC event to the correlator. Forward events are held until
+// they are resolved. If the event resolves an HTLC, the resolved HTLC is
+// returned, otherwise nil.
+func (c *HtlcCorrelator) AddEvent(event *HtlcEvent) *ResolvedHtlc {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	var settled bool
+	switch event.Event.(type) {
+	case *ForwardEvent:
+		c.pending[event.Key] = event
+		return nil
+
+	case *SettleEvent:
+		settled = true
+
+	case *ForwardFailEvent, *LinkFailEvent:
+
+	default:
+		return nil
+	}
+
+	forward := c.pending[event.Key]
+	delete(c.pending, event.Key)
+
+	return &ResolvedHtlc{
+		Key:        event.Key,
+		EventType:  event.EventType,
+		Forward:    forward,
+		Resolution: event,
+		Settled:    settled,
+	}
+}
+
+// Pending returns the forward events of all HTLCs that are not resolved yet.
+func (c *HtlcCorrelator) Pending() []*HtlcEvent {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	pending := make([]*HtlcEvent, 0, len(c.pending))
+	for _, event := range c.pending {
+		pending = append(pending, event)
+	}
+
+	return pending
+}
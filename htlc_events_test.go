@@ -0,0 +1,122 @@
+package lndclient
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+)
+
+// TestHtlcCorrelator tests that forward events are converted into typed
+// events and matched with the events that resolve them.
+func TestHtlcCorrelator(t *testing.T) {
+	start := time.Unix(1000, 0)
+
+	newEvent := func(htlcID uint64, offset time.Duration,
+		event interface{}) *routerrpc.HtlcEvent {
+
+		rpcEvent := &routerrpc.HtlcEvent{
+			IncomingChannelId: 1,
+			OutgoingChannelId: 2,
+			IncomingHtlcId:    htlcID,
+			OutgoingHtlcId:    htlcID + 10,
+			TimestampNs:       uint64(start.Add(offset).UnixNano()),
+			EventType:         routerrpc.HtlcEvent_FORWARD,
+		}
+
+		switch e := event.(type) {
+		case *routerrpc.ForwardEvent:
+			rpcEvent.Event = &routerrpc.HtlcEvent_ForwardEvent{
+				ForwardEvent: e,
+			}
+
+		case *routerrpc.SettleEvent:
+			rpcEvent.Event = &routerrpc.HtlcEvent_SettleEvent{
+				SettleEvent: e,
+			}
+
+		case *routerrpc.ForwardFailEvent:
+			rpcEvent.Event = &routerrpc.HtlcEvent_ForwardFailEvent{
+				ForwardFailEvent: e,
+			}
+
+		case *routerrpc.LinkFailEvent:
+			rpcEvent.Event = &routerrpc.HtlcEvent_LinkFailEvent{
+				LinkFailEvent: e,
+			}
+		}
+
+		return rpcEvent
+	}
+
+	forward := &routerrpc.ForwardEvent{
+		Info: &routerrpc.HtlcInfo{
+			IncomingAmtMsat: 1100,
+			OutgoingAmtMsat: 1000,
+		},
+	}
+
+	correlator := NewHtlcCorrelator()
+	add := func(rpcEvent *routerrpc.HtlcEvent) *ResolvedHtlc {
+		event := newHtlcEvent(rpcEvent)
+		if event == nil {
+			t.Fatalf("unexpected unknown event")
+		}
+		return correlator.AddEvent(event)
+	}
+
+	// Two HTLCs are forwarded, they are pending until resolved.
+	if add(newEvent(1, 0, forward)) != nil {
+		t.Fatalf("forward must not resolve htlc")
+	}
+	if add(newEvent(2, 0, forward)) != nil {
+		t.Fatalf("forward must not resolve htlc")
+	}
+	if len(correlator.Pending()) != 2 {
+		t.Fatalf("expected 2 pending htlcs")
+	}
+
+	settled := add(newEvent(1, time.Second, &routerrpc.SettleEvent{}))
+	if settled == nil || !settled.Settled || settled.Forward == nil {
+		t.Fatalf("expected settled forward, got %v", settled)
+	}
+	if settled.Key.Incoming.ChanID.ToUint64() != 1 ||
+		settled.Key.Outgoing.HtlcID != 11 {
+
+		t.Fatalf("unexpected htlc key: %v", settled.Key)
+	}
+	if settled.Duration() != time.Second {
+		t.Fatalf("expected 1s duration, got %v", settled.Duration())
+	}
+
+	info := settled.Forward.Event.(*ForwardEvent).Info
+	if info.IncomingAmt != 1100 || info.OutgoingAmt != 1000 {
+		t.Fatalf("unexpected htlc info: %v", info)
+	}
+	if settled.EventType != HtlcEventTypeForward {
+		t.Fatalf("unexpected event type: %v", settled.EventType)
+	}
+
+	failed := add(newEvent(2, time.Second, &routerrpc.ForwardFailEvent{}))
+	if failed == nil || failed.Settled || failed.Forward == nil {
+		t.Fatalf("expected failed forward, got %v", failed)
+	}
+	if len(correlator.Pending()) != 0 {
+		t.Fatalf("expected no pending htlcs")
+	}
+
+	// Link failures resolve HTLCs that were never forwarded.
+	linkFail := add(newEvent(3, 0, &routerrpc.LinkFailEvent{
+		WireFailure:   lnrpc.Failure_TEMPORARY_CHANNEL_FAILURE,
+		FailureDetail: routerrpc.FailureDetail_INSUFFICIENT_BALANCE,
+	}))
+	if linkFail == nil || linkFail.Forward != nil {
+		t.Fatalf("expected link failure without forward")
+	}
+
+	reason := linkFail.Resolution.Event.(*LinkFailEvent).Reason()
+	if reason != "TEMPORARY_CHANNEL_FAILURE (INSUFFICIENT_BALANCE)" {
+		t.Fatalf("unexpected failure reason: %v", reason)
+	}
+}
@@ -0,0 +1,343 @@
+package lndclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+const (
+	// defaultInterceptTimeout is the default time the intercept handler
+	// has to decide on an HTLC before the default action is applied.
+	defaultInterceptTimeout = 30 * time.Second
+)
+
+// CircuitKey identifies an HTLC by the channel it was added to and its index
+// in that channel.
+type CircuitKey struct {
+	// ChanID is the short channel ID of the channel.
+	ChanID lnwire.ShortChannelID
+
+	// HtlcID is the index of the HTLC in the channel.
+	HtlcID uint64
+}
+
+// String returns a human readable representation of the circuit key.
+func (c CircuitKey) String() string {
+	return fmt.Sprintf("%v:%v", c.ChanID, c.HtlcID)
+}
+
+// InterceptedHtlc is an HTLC that is held by lnd until the interceptor decides
+// what to do with it.
+type InterceptedHtlc struct {
+	// IncomingCircuitKey identifies the incoming HTLC.
+	IncomingCircuitKey CircuitKey
+
+	// IncomingAmount is the amount of the incoming HTLC.
+	IncomingAmount lnwire.MilliSatoshi
+
+	// IncomingExpiry is the expiry height of the incoming HTLC.
+	IncomingExpiry uint32
+
+	// PaymentHash is the payment hash of the HTLC.
+	PaymentHash lntypes.Hash
+
+	// OutgoingChannelID is the channel that the sender requested the HTLC
+	// to be forwarded through. lnd may still pick a different channel to
+	// the same peer.
+	OutgoingChannelID lnwire.ShortChannelID
+
+	// OutgoingAmount is the amount to be forwarded.
+	OutgoingAmount lnwire.MilliSatoshi
+
+	// OutgoingExpiry is the expiry height of the outgoing HTLC.
+	OutgoingExpiry uint32
+
+	// CustomRecords holds the custom TLV records of the onion payload
+	// that is addressed to us.
+	CustomRecords map[uint64][]byte
+}
+
+// InterceptorAction is the action taken on an intercepted HTLC.
+type InterceptorAction uint8
+
+const (
+	// InterceptorActionResume forwards the HTLC as usual.
+	InterceptorActionResume InterceptorAction = iota
+
+	// InterceptorActionFail fails the HTLC back to the sender.
+	InterceptorActionFail
+
+	// InterceptorActionSettle settles the HTLC with a preimage.
+	InterceptorActionSettle
+)
+
+// String returns a string representation of the action.
+func (a InterceptorAction) String() string {
+	switch a {
+	case InterceptorActionResume:
+		return "resume"
+
+	case InterceptorActionFail:
+		return "fail"
+
+	case InterceptorActionSettle:
+		return "settle"
+
+	default:
+		return fmt.Sprintf("unknown action: %d", a)
+	}
+}
+
+// rpcAction returns the rpc counterpart of the action.
+func (a InterceptorAction) rpcAction() (routerrpc.ResolveHoldForwardAction,
+	error) {
+
+	switch a {
+	case InterceptorActionResume:
+		return routerrpc.ResolveHoldForwardAction_RESUME, nil
+
+	case InterceptorActionFail:
+		return routerrpc.ResolveHoldForwardAction_FAIL, nil
+
+	case InterceptorActionSettle:
+		return routerrpc.ResolveHoldForwardAction_SETTLE, nil
+
+	default:
+		return 0, fmt.Errorf("unknown interceptor action: %v", a)
+	}
+}
+
+// InterceptedHtlcResponse is the decision on an intercepted HTLC.
+type InterceptedHtlcResponse struct {
+	// Action is the action that is taken on the HTLC.
+	Action InterceptorAction
+
+	// Preimage is the preimage that settles the HTLC. It is required for
+	// InterceptorActionSettle.
+	Preimage *lntypes.Preimage
+}
+
+// HtlcInterceptHandler decides on an intercepted HTLC. Handlers are called
+// concurrently for HTLCs that are intercepted at the same time and should
+// return once the context is done, after which the default action is applied.
+type HtlcInterceptHandler func(ctx context.Context,
+	htlc InterceptedHtlc) (*InterceptedHtlcResponse, error)
+
+// newInterceptedHtlc creates an intercepted HTLC from its rpc counterpart.
+func newInterceptedHtlc(req *routerrpc.ForwardHtlcInterceptRequest) (
+	InterceptedHtlc, error) {
+
+	hash, err := lntypes.MakeHash(req.PaymentHash)
+	if err != nil {
+		return InterceptedHtlc{}, err
+	}
+
+	return InterceptedHtlc{
+		IncomingCircuitKey: newCircuitKey(req.IncomingCircuitKey),
+		IncomingAmount:     lnwire.MilliSatoshi(req.IncomingAmountMsat),
+		IncomingExpiry:     req.IncomingExpiry,
+		PaymentHash:        hash,
+		OutgoingChannelID: lnwire.NewShortChanIDFromInt(
+			req.OutgoingRequestedChanId,
+		),
+		OutgoingAmount: lnwire.MilliSatoshi(req.OutgoingAmountMsat),
+		OutgoingExpiry: req.OutgoingExpiry,
+		CustomRecords:  req.CustomRecords,
+	}, nil
+}
+
+// newCircuitKey creates a circuit key from its rpc counterpart.
+func newCircuitKey(key *routerrpc.CircuitKey) CircuitKey {
+	if key == nil {
+		return CircuitKey{}
+	}
+
+	return CircuitKey{
+		ChanID: lnwire.NewShortChanIDFromInt(key.ChanId),
+		HtlcID: key.HtlcId,
+	}
+}
+
+// InterceptHtlcs registers an HTLC interceptor with lnd that holds every
+// forwarded HTLC until the handler provided decides whether it is resumed,
+// failed or settled. Every HTLC is handled in its own goroutine, so slow
+// decisions don't hold up other HTLCs. If the handler fails or doesn't decide
+// within the timeout, the default action is applied, which must not be
+// InterceptorActionSettle. A zero timeout defaults to 30 seconds. The returned
+// error channel receives an error if the interceptor stream fails, after which
+// no further HTLCs are intercepted. Note that lnd only allows a single
+// interceptor at a time.
+func (r *routerClient) InterceptHtlcs(ctx context.Context,
+	timeout time.Duration, defaultAction InterceptorAction,
+	handler HtlcInterceptHandler) (chan error, error) {
+
+	// The default action is applied without a preimage, so it can't
+	// settle.
+	if _, err := defaultAction.rpcAction(); err != nil {
+		return nil, err
+	}
+	if defaultAction == InterceptorActionSettle {
+		return nil, errors.New("default action can't be settle")
+	}
+
+	if timeout == 0 {
+		timeout = defaultInterceptTimeout
+	}
+
+	stream, err := r.client.HtlcInterceptor(
+		r.routerKitMac.WithMacaroonAuth(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Buffer our error channel by 1 so we don't need to worry about the
+	// client not listening or shutting down when we send an error.
+	errChan := make(chan error, 1)
+	sendErr := func(err error) {
+		select {
+		case errChan <- err:
+		default:
+		}
+	}
+
+	// Responses may be sent from multiple goroutines, but the stream
+	// doesn't allow concurrent sends.
+	var sendMtx sync.Mutex
+	send := func(resp *routerrpc.ForwardHtlcInterceptResponse) {
+		sendMtx.Lock()
+		defer sendMtx.Unlock()
+
+		if err := stream.Send(resp); err != nil {
+			sendErr(fmt.Errorf("interceptor send failed: %v", err))
+		}
+	}
+
+	go func() {
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				sendErr(fmt.Errorf("interceptor receive "+
+					"failed: %v", err))
+				return
+			}
+
+			go func() {
+				action, preimage := decideHtlc(
+					ctx, timeout, defaultAction, handler,
+					req,
+				)
+				send(newInterceptResponse(
+					req, action, preimage,
+				))
+			}()
+		}
+	}()
+
+	return errChan, nil
+}
+
+// newInterceptResponse creates the response to an intercepted HTLC.
+func newInterceptResponse(req *routerrpc.ForwardHtlcInterceptRequest,
+	action InterceptorAction,
+	preimage *lntypes.Preimage) *routerrpc.ForwardHtlcInterceptResponse {
+
+	// Unknown actions can't be passed on to lnd, so we fail the HTLC
+	// rather than sending a zero action, which would settle it.
+	rpcAction, err := action.rpcAction()
+	if err != nil {
+		log.Errorf("Intercepted htlc %v: %v, failing htlc",
+			newCircuitKey(req.IncomingCircuitKey), err)
+
+		rpcAction = routerrpc.ResolveHoldForwardAction_FAIL
+		preimage = nil
+	}
+
+	resp := &routerrpc.ForwardHtlcInterceptResponse{
+		IncomingCircuitKey: req.IncomingCircuitKey,
+		Action:             rpcAction,
+	}
+	if preimage != nil {
+		resp.Preimage = preimage[:]
+	}
+
+	return resp
+}
+
+// decideHtlc asks the handler for a decision on an intercepted HTLC. The
+// default action is returned if the handler fails, returns an invalid decision
+// or doesn't decide within the timeout.
+func decideHtlc(ctx context.Context, timeout time.Duration,
+	defaultAction InterceptorAction, handler HtlcInterceptHandler,
+	req *routerrpc.ForwardHtlcInterceptRequest) (InterceptorAction,
+	*lntypes.Preimage) {
+
+	htlc, err := newInterceptedHtlc(req)
+	if err != nil {
+		log.Errorf("Invalid intercepted htlc %v: %v",
+			newCircuitKey(req.IncomingCircuitKey), err)
+		return defaultAction, nil
+	}
+
+	ctxt, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type result struct {
+		resp *InterceptedHtlcResponse
+		err  error
+	}
+
+	// The result channel is buffered so that a late handler doesn't block
+	// forever.
+	resultChan := make(chan result, 1)
+	go func() {
+		resp, err := handler(ctxt, htlc)
+		resultChan <- result{resp: resp, err: err}
+	}()
+
+	select {
+	case res := <-resultChan:
+		switch {
+		case res.err != nil:
+			log.Errorf("Intercepted htlc %v: handler failed, "+
+				"applying default action %v: %v",
+				htlc.IncomingCircuitKey, defaultAction,
+				res.err)
+
+		case res.resp == nil:
+			log.Errorf("Intercepted htlc %v: no decision, "+
+				"applying default action %v",
+				htlc.IncomingCircuitKey, defaultAction)
+
+		case res.resp.Action > InterceptorActionSettle:
+			log.Errorf("Intercepted htlc %v: unknown action %v, "+
+				"applying default action %v",
+				htlc.IncomingCircuitKey, res.resp.Action,
+				defaultAction)
+
+		case res.resp.Action == InterceptorActionSettle &&
+			res.resp.Preimage == nil:
+
+			log.Errorf("Intercepted htlc %v: settle without "+
+				"preimage, applying default action %v",
+				htlc.IncomingCircuitKey, defaultAction)
+
+		default:
+			return res.resp.Action, res.resp.Preimage
+		}
+
+	case <-ctxt.Done():
+		log.Warnf("Intercepted htlc %v: handler timed out, applying "+
+			"default action %v", htlc.IncomingCircuitKey,
+			defaultAction)
+	}
+
+	return defaultAction, nil
+}
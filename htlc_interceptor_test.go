@@ -0,0 +1,162 @@
+package lndclient
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"google.golang.org/grpc"
+)
+
+// mockInterceptStream is an interceptor stream that delivers the given
+// requests and records the responses.
+type mockInterceptStream struct {
+	grpc.ClientStream
+
+	requests  chan *routerrpc.ForwardHtlcInterceptRequest
+	responses chan *routerrpc.ForwardHtlcInterceptResponse
+}
+
+func (m *mockInterceptStream) Recv() (
+	*routerrpc.ForwardHtlcInterceptRequest, error) {
+
+	req, ok := <-m.requests
+	if !ok {
+		return nil, io.EOF
+	}
+
+	return req, nil
+}
+
+func (m *mockInterceptStream) Send(
+	resp *routerrpc.ForwardHtlcInterceptResponse) error {
+
+	m.responses <- resp
+	return nil
+}
+
+// mockInterceptRouter is a router that returns the given interceptor stream.
+type mockInterceptRouter struct {
+	routerrpc.RouterClient
+
+	stream *mockInterceptStream
+}
+
+func (m *mockInterceptRouter) HtlcInterceptor(_ context.Context,
+	_ ...grpc.CallOption) (routerrpc.Router_HtlcInterceptorClient, error) {
+
+	return m.stream, nil
+}
+
+// TestInterceptHtlcs tests that intercepted HTLCs are passed to the handler
+// with their typed fields, that decisions can be made asynchronously and that
+// the default action is applied if the handler doesn't decide in time.
+func TestInterceptHtlcs(t *testing.T) {
+	stream := &mockInterceptStream{
+		requests:  make(chan *routerrpc.ForwardHtlcInterceptRequest),
+		responses: make(chan *routerrpc.ForwardHtlcInterceptResponse),
+	}
+	client := newRouterClient(
+		&mockInterceptRouter{stream: stream}, nil, "",
+	)
+
+	preimage := lntypes.Preimage{1}
+	settleHash := preimage.Hash()
+	slowHash := lntypes.Hash{2}
+
+	handler := func(ctx context.Context, htlc InterceptedHtlc) (
+		*InterceptedHtlcResponse, error) {
+
+		if htlc.PaymentHash == slowHash {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+
+		if htlc.OutgoingChannelID.ToUint64() != 99 ||
+			htlc.IncomingAmount != 1100 ||
+			string(htlc.CustomRecords[65537]) != "data" {
+
+			return &InterceptedHtlcResponse{
+				Action: InterceptorActionResume,
+			}, nil
+		}
+
+		return &InterceptedHtlcResponse{
+			Action:   InterceptorActionSettle,
+			Preimage: &preimage,
+		}, nil
+	}
+
+	// Settling isn't allowed as default action.
+	_, err := client.InterceptHtlcs(
+		context.Background(), 0, InterceptorActionSettle, handler,
+	)
+	if err == nil {
+		t.Fatalf("expected error for settle default action")
+	}
+
+	// Unknown default actions are rejected too.
+	_, err = client.InterceptHtlcs(
+		context.Background(), 0, InterceptorAction(7), handler,
+	)
+	if err == nil {
+		t.Fatalf("expected error for unknown default action")
+	}
+
+	// An unknown action that slips through fails the HTLC instead of
+	// sending the zero action, which settles.
+	resp := newInterceptResponse(
+		&routerrpc.ForwardHtlcInterceptRequest{}, InterceptorAction(7),
+		nil,
+	)
+	if resp.Action != routerrpc.ResolveHoldForwardAction_FAIL {
+		t.Fatalf("expected fail for unknown action, got %v",
+			resp.Action)
+	}
+
+	errChan, err := client.InterceptHtlcs(
+		context.Background(), 50*time.Millisecond,
+		InterceptorActionFail, handler,
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The slow HTLC is intercepted first, but must not hold up the
+	// decision on the second one.
+	stream.requests <- &routerrpc.ForwardHtlcInterceptRequest{
+		IncomingCircuitKey: &routerrpc.CircuitKey{ChanId: 1, HtlcId: 1},
+		PaymentHash:        slowHash[:],
+	}
+	stream.requests <- &routerrpc.ForwardHtlcInterceptRequest{
+		IncomingCircuitKey:      &routerrpc.CircuitKey{ChanId: 1, HtlcId: 2},
+		IncomingAmountMsat:      1100,
+		PaymentHash:             settleHash[:],
+		OutgoingRequestedChanId: 99,
+		OutgoingAmountMsat:      1000,
+		CustomRecords:           map[uint64][]byte{65537: []byte("data")},
+	}
+
+	resp = <-stream.responses
+	if resp.IncomingCircuitKey.HtlcId != 2 ||
+		resp.Action != routerrpc.ResolveHoldForwardAction_SETTLE ||
+		string(resp.Preimage) != string(preimage[:]) {
+
+		t.Fatalf("expected settle of second htlc, got %v", resp)
+	}
+
+	resp = <-stream.responses
+	if resp.IncomingCircuitKey.HtlcId != 1 ||
+		resp.Action != routerrpc.ResolveHoldForwardAction_FAIL {
+
+		t.Fatalf("expected default fail of first htlc, got %v", resp)
+	}
+
+	close(stream.requests)
+	if err := <-errChan; err == nil {
+		t.Fatalf("expected stream error")
+	}
+}
@@ -5,12 +5,13 @@ import (
 	"errors"
 	"sync"
 
+	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcutil"
 	"github.com/lightningnetwork/lnd/channeldb"
 	"github.com/lightningnetwork/lnd/lnrpc"
 	"github.com/lightningnetwork/lnd/lnrpc/invoicesrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/verrpc"
 	"github.com/lightningnetwork/lnd/lntypes"
-	"google.golang.org/grpc"
 )
 
 // InvoicesClient exposes invoice functionality.
@@ -22,8 +23,10 @@ type InvoicesClient interface {
 
 	CancelInvoice(ctx context.Context, hash lntypes.Hash) error
 
-	AddHoldInvoice(ctx context.Context, in *invoicesrpc.AddInvoiceData) (
-		string, error)
+	// AddHoldInvoice adds a new hold invoice for the hash of the request,
+	// which is settled once the preimage is passed to SettleInvoice.
+	AddHoldInvoice(ctx context.Context, in *AddInvoiceRequest) (
+		*AddInvoiceResult, error)
 }
 
 // InvoiceUpdate contains a state update for an invoice.
@@ -34,13 +37,20 @@ type InvoiceUpdate struct {
 
 type invoicesClient struct {
 	client     invoicesrpc.InvoicesClient
+	params     *chaincfg.Params
 	invoiceMac serializedMacaroon
 	wg         sync.WaitGroup
+
+	// version is the version of the connected lnd, nil if unknown.
+	version *verrpc.Version
 }
 
-func newInvoicesClient(conn *grpc.ClientConn, invoiceMac serializedMacaroon) *invoicesClient {
+func newInvoicesClient(client invoicesrpc.InvoicesClient,
+	params *chaincfg.Params, invoiceMac serializedMacaroon) *invoicesClient {
+
 	return &invoicesClient{
-		client:     invoicesrpc.NewInvoicesClient(conn),
+		client:     client,
+		params:     params,
 		invoiceMac: invoiceMac,
 	}
 }
@@ -125,27 +135,49 @@ func (s *invoicesClient) SubscribeSingleInvoice(ctx context.Context,
 	return updateChan, errChan, nil
 }
 
+// AddHoldInvoice adds a new hold invoice for the hash of the request, which is
+// settled once the preimage is passed to SettleInvoice.
 func (s *invoicesClient) AddHoldInvoice(ctx context.Context,
-	in *invoicesrpc.AddInvoiceData) (string, error) {
+	in *AddInvoiceRequest) (*AddInvoiceResult, error) {
 
 	rpcCtx, cancel := context.WithTimeout(ctx, rpcTimeout)
 	defer cancel()
 
+	if in.Hash == nil {
+		return nil, errors.New("hash required for hold invoice")
+	}
+	if in.Preimage != nil {
+		return nil, errors.New("preimage must not be set for hold " +
+			"invoice")
+	}
+	if err := checkInvoiceRouteHints(s.version, in); err != nil {
+		return nil, err
+	}
+
+	routeHints, err := marshallRouteHints(in.RouteHints)
+	if err != nil {
+		return nil, err
+	}
+
 	rpcIn := &invoicesrpc.AddHoldInvoiceRequest{
-		Memo:       in.Memo,
-		Hash:       in.Hash[:],
-		Value:      int64(in.Value.ToSatoshis()),
-		Expiry:     in.Expiry,
-		CltvExpiry: in.CltvExpiry,
-		Private:    true,
+		Memo:            in.Memo,
+		Hash:            in.Hash[:],
+		ValueMsat:       int64(in.Value),
+		DescriptionHash: in.DescriptionHash,
+		Expiry:          in.Expiry,
+		FallbackAddr:    in.FallbackAddr,
+		CltvExpiry:      in.CltvExpiry,
+		RouteHints:      routeHints,
+		Private:         !in.DisablePrivateHints,
 	}
 
 	rpcCtx = s.invoiceMac.WithMacaroonAuth(rpcCtx)
 	resp, err := s.client.AddHoldInvoice(rpcCtx, rpcIn)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return resp.PaymentRequest, nil
+
+	return newAddInvoiceResult(*in.Hash, resp.PaymentRequest, 0, s.params)
 }
 
 func fromRPCInvoiceState(state lnrpc.Invoice_InvoiceState) (
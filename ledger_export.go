@@ -0,0 +1,699 @@
+package lndclient
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// Ledger accounts that entries are posted to.
+const (
+	// AccountOnChain holds the funds of our on chain wallet.
+	AccountOnChain = "Assets:Bitcoin:OnChain"
+
+	// AccountLightning holds our balance in channels.
+	AccountLightning = "Assets:Bitcoin:Lightning"
+
+	// AccountExternal is the counterpart of funds moved between our
+	// wallet and the outside world, which are neither income nor
+	// expenses.
+	AccountExternal = "Equity:Bitcoin:External"
+
+	// AccountRoutingIncome holds the fees earned by forwarding.
+	AccountRoutingIncome = "Income:Bitcoin:Routing"
+
+	// AccountInvoiceIncome holds the amounts received by invoices.
+	AccountInvoiceIncome = "Income:Bitcoin:Invoices"
+
+	// AccountPayments holds the amounts of our payments.
+	AccountPayments = "Expenses:Bitcoin:Payments"
+
+	// AccountPaymentFees holds the routing fees of our payments.
+	AccountPaymentFees = "Expenses:Bitcoin:PaymentFees"
+
+	// AccountChainFees holds the on chain fees we paid.
+	AccountChainFees = "Expenses:Bitcoin:ChainFees"
+)
+
+// ledgerCommodity is the commodity amounts are exported in.
+const ledgerCommodity = "SAT"
+
+// LedgerEntryType describes the activity that created a ledger entry.
+type LedgerEntryType string
+
+const (
+	// LedgerDeposit is an on chain transaction that received funds from
+	// outside our wallet.
+	LedgerDeposit LedgerEntryType = "deposit"
+
+	// LedgerWithdrawal is an on chain transaction that sent funds to
+	// outside our wallet.
+	LedgerWithdrawal LedgerEntryType = "withdrawal"
+
+	// LedgerChannelOpen is a funding transaction of channels we opened.
+	LedgerChannelOpen LedgerEntryType = "channel_open"
+
+	// LedgerChannelClose is a closing transaction that returned our
+	// channel balance to our wallet.
+	LedgerChannelClose LedgerEntryType = "channel_close"
+
+	// LedgerSweep is a transaction that swept the outputs of a closing
+	// transaction into our wallet.
+	LedgerSweep LedgerEntryType = "sweep"
+
+	// LedgerPayment is a payment to another node.
+	LedgerPayment LedgerEntryType = "payment"
+
+	// LedgerRebalance is a payment to ourselves.
+	LedgerRebalance LedgerEntryType = "rebalance"
+
+	// LedgerInvoice is a settled invoice.
+	LedgerInvoice LedgerEntryType = "invoice"
+
+	// LedgerRoutingFee is the fee earned by a forward.
+	LedgerRoutingFee LedgerEntryType = "routing_fee"
+)
+
+// LedgerPosting moves an amount into or out of an account.
+type LedgerPosting struct {
+	// Account is the account the amount is posted to.
+	Account string `json:"account"`
+
+	// AmountMsat is the amount posted in millisatoshis. It is negative
+	// for credits.
+	AmountMsat int64 `json:"amount_msat"`
+}
+
+// LedgerEntry is a single balanced transaction of the ledger.
+type LedgerEntry struct {
+	// Timestamp is the time of the activity.
+	Timestamp time.Time `json:"timestamp"`
+
+	// Type is the activity that created the entry.
+	Type LedgerEntryType `json:"type"`
+
+	// Reference identifies the activity, such as a transaction or payment
+	// hash.
+	Reference string `json:"reference"`
+
+	// Description describes the activity.
+	Description string `json:"description"`
+
+	// Internal is set if funds only moved between our own accounts, so
+	// the entry is neither income nor a payment to others.
+	Internal bool `json:"internal"`
+
+	// Postings holds the postings of the entry, which sum up to zero.
+	Postings []LedgerPosting `json:"postings"`
+}
+
+// post adds a posting to the entry if the amount is not zero.
+func (e *LedgerEntry) post(account string, amount int64) {
+	if amount == 0 {
+		return
+	}
+
+	e.Postings = append(e.Postings, LedgerPosting{
+		Account:    account,
+		AmountMsat: amount,
+	})
+}
+
+// LedgerRequest holds the bounds of a ledger export.
+type LedgerRequest struct {
+	// StartTime is the earliest time of activity to export. If zero, all
+	// activity is exported.
+	StartTime time.Time
+
+	// EndTime is the latest time of activity to export. If zero, all
+	// activity up to now is exported.
+	EndTime time.Time
+}
+
+// contains returns whether the time provided is within the bounds.
+func (r *LedgerRequest) contains(t time.Time) bool {
+	return (r.StartTime.IsZero() || !t.Before(r.StartTime)) &&
+		(r.EndTime.IsZero() || !t.After(r.EndTime))
+}
+
+// Ledger is a chronological double entry ledger of our node.
+type Ledger struct {
+	// Entries holds the entries of the ledger, sorted by time.
+	Entries []*LedgerEntry
+}
+
+// ExportLedger creates a double entry ledger of our on chain transactions,
+// payments, settled invoices and routing fees. Funding transactions of the
+// channels we opened, closing transactions and sweeps of force closes are
+// classified as internal transfers between our on chain wallet and our
+// channels, as are payments to ourselves apart from their fees.
+//
+// NOTE: Channels opened by our peers don't show up in our wallet, so funds
+// pushed to us on opening are not part of the ledger. The commitment
+// transaction of a local force close doesn't pay to our wallet either, so if
+// we opened the channel, its fee is never booked as a chain fee and remains
+// part of our channel balance.
+func ExportLedger(ctx context.Context, lnd LightningClient,
+	req LedgerRequest) (*Ledger, error) {
+
+	info, err := lnd.GetInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	self := route.Vertex(info.IdentityPubkey)
+
+	ledger := &Ledger{}
+	query := HistoryQuery{
+		StartTime: req.StartTime,
+		EndTime:   req.EndTime,
+	}
+
+	if err := ledger.addOnChain(ctx, lnd, query); err != nil {
+		return nil, err
+	}
+
+	rebalances, err := ledger.addPayments(ctx, lnd, query, self)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ledger.addInvoices(ctx, lnd, req, rebalances); err != nil {
+		return nil, err
+	}
+
+	forwards := NewForwardingIterator(lnd, query)
+	for forwards.Next(ctx) {
+		event := forwards.Event()
+		if event.FeeMsat == 0 {
+			continue
+		}
+
+		entry := &LedgerEntry{
+			Timestamp: event.Timestamp,
+			Type:      LedgerRoutingFee,
+			Reference: fmt.Sprintf("%v:%v", event.ChannelIn,
+				event.ChannelOut),
+			Description: fmt.Sprintf("Forward from %v to %v",
+				event.ChannelIn, event.ChannelOut),
+		}
+		entry.post(AccountLightning, int64(event.FeeMsat))
+		entry.post(AccountRoutingIncome, -int64(event.FeeMsat))
+
+		ledger.Entries = append(ledger.Entries, entry)
+	}
+	if err := forwards.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(ledger.Entries, func(i, j int) bool {
+		return ledger.Entries[i].Timestamp.Before(
+			ledger.Entries[j].Timestamp,
+		)
+	})
+
+	return ledger, nil
+}
+
+// addOnChain adds our confirmed on chain transactions to the ledger.
+func (l *Ledger) addOnChain(ctx context.Context, lnd LightningClient,
+	query HistoryQuery) error {
+
+	open, err := lnd.ListChannels(ctx)
+	if err != nil {
+		return err
+	}
+
+	closed, err := lnd.ClosedChannels(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Collect the capacity we funded per funding transaction, and the
+	// closing transactions along with the channel they closed.
+	funded := make(map[string]btcutil.Amount)
+	fund := func(point string, capacity btcutil.Amount) error {
+		outpoint, err := NewOutpointFromStr(point)
+		if err != nil {
+			return err
+		}
+
+		funded[outpoint.Hash.String()] += capacity
+		return nil
+	}
+
+	for _, channel := range open {
+		if !channel.Initiator {
+			continue
+		}
+
+		err := fund(channel.ChannelPoint, channel.Capacity)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Sweeps of htlcs that were resolved through a second level
+	// transaction don't spend the closing transaction, so we collect the
+	// sweeps lnd reported for our force closes.
+	closes := make(map[string]*ClosedChannel)
+	sweeps := make(map[string]bool)
+	for i, channel := range closed {
+		closes[channel.ClosingTxHash] = &closed[i]
+
+		if channel.CloseType != CloseTypeCooperative {
+			for _, resolution := range channel.Resolutions {
+				if resolution.SweepTxid != "" {
+					sweeps[resolution.SweepTxid] = true
+				}
+			}
+		}
+
+		if channel.OpenInitiator != InitiatorLocal {
+			continue
+		}
+
+		err := fund(channel.ChannelPoint, channel.Capacity)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Transactions aren't indexed by time, so we query all of them at
+	// once rather than paging through every block.
+	txs, err := lnd.ListTransactions(ctx, 0, -1)
+	if err != nil {
+		return err
+	}
+
+	for i := range txs {
+		tx := &txs[i]
+		if tx.Confirmations == 0 || query.beforeStart(tx.Timestamp) ||
+			query.afterEnd(tx.Timestamp) {
+
+			continue
+		}
+
+		entry, err := onChainEntry(tx, funded, closes, sweeps)
+		if err != nil {
+			return err
+		}
+		l.Entries = append(l.Entries, entry)
+	}
+
+	return nil
+}
+
+// onChainEntry classifies an on chain transaction and creates its entry.
+func onChainEntry(tx *Transaction, funded map[string]btcutil.Amount,
+	closes map[string]*ClosedChannel,
+	sweeps map[string]bool) (*LedgerEntry, error) {
+
+	entry := &LedgerEntry{
+		Timestamp: tx.Timestamp,
+		Reference: tx.TxHash,
+	}
+
+	amount := int64(lnwire.NewMSatFromSatoshis(tx.Amount))
+	fee := int64(lnwire.NewMSatFromSatoshis(tx.Fee))
+
+	switch {
+	// Funds moved from our wallet into channels. Anything else the
+	// transaction sent left our wallet.
+	case funded[tx.TxHash] > 0:
+		capacity := int64(lnwire.NewMSatFromSatoshis(
+			funded[tx.TxHash],
+		))
+
+		entry.Type = LedgerChannelOpen
+		entry.Description = "Channel open"
+		entry.Internal = true
+		entry.post(AccountOnChain, amount)
+		entry.post(AccountLightning, capacity)
+		entry.post(AccountChainFees, fee)
+		entry.post(AccountExternal, -amount-capacity-fee)
+
+	// Our channel balance returned to our wallet. If we opened the
+	// channel, we paid the closing fee from our balance.
+	case closes[tx.TxHash] != nil:
+		channel := closes[tx.TxHash]
+
+		var closeFee int64
+		if channel.OpenInitiator == InitiatorLocal {
+			point, err := NewOutpointFromStr(channel.ChannelPoint)
+			if err != nil {
+				return nil, err
+			}
+
+			fee, ok := closingFee(tx, point, channel.Capacity)
+			if ok {
+				closeFee = int64(
+					lnwire.NewMSatFromSatoshis(fee),
+				)
+			}
+		}
+
+		entry.Type = LedgerChannelClose
+		entry.Description = fmt.Sprintf("Channel close %v",
+			channel.ChannelID)
+		entry.Internal = true
+		entry.post(AccountOnChain, amount)
+		entry.post(AccountChainFees, closeFee)
+		entry.post(AccountLightning, -amount-closeFee)
+
+	case isSweep(tx, closes, sweeps):
+		entry.Type = LedgerSweep
+		entry.Description = "Sweep of channel close"
+		entry.Internal = true
+		entry.post(AccountOnChain, amount)
+		entry.post(AccountChainFees, fee)
+		entry.post(AccountLightning, -amount-fee)
+
+	case amount >= 0:
+		entry.Type = LedgerDeposit
+		entry.Description = "On chain deposit"
+		entry.post(AccountOnChain, amount)
+		entry.post(AccountExternal, -amount)
+
+	default:
+		entry.Type = LedgerWithdrawal
+		entry.Description = "On chain withdrawal"
+		entry.post(AccountOnChain, amount)
+		entry.post(AccountChainFees, fee)
+		entry.post(AccountExternal, -amount-fee)
+	}
+
+	if tx.Label != "" {
+		entry.Description += ": " + tx.Label
+	}
+
+	return entry, nil
+}
+
+// isSweep returns whether a transaction sweeps outputs of a force close into
+// our wallet, either directly or through a second level htlc transaction.
+// Outputs of cooperative closes pay to our wallet directly, so spending them
+// is a regular wallet spend. A sweep spends no wallet inputs, so its amount is
+// never negative.
+func isSweep(tx *Transaction, closes map[string]*ClosedChannel,
+	sweeps map[string]bool) bool {
+
+	if tx.Amount < 0 {
+		return false
+	}
+	if sweeps[tx.TxHash] {
+		return true
+	}
+	if tx.Tx == nil {
+		return false
+	}
+
+	for _, in := range tx.Tx.TxIn {
+		channel := closes[in.PreviousOutPoint.Hash.String()]
+		if channel != nil && channel.CloseType != CloseTypeCooperative {
+			return true
+		}
+	}
+
+	return false
+}
+
+// addPayments adds our successful payments to the ledger. The hashes of all
+// payments to ourselves are returned, including the ones outside of the time
+// bounds, as their invoices may settle within the bounds.
+func (l *Ledger) addPayments(ctx context.Context, lnd LightningClient,
+	query HistoryQuery, self route.Vertex) (map[string]bool, error) {
+
+	rebalances := make(map[string]bool)
+
+	iter := NewPaymentIterator(lnd, HistoryQuery{}, false)
+	for iter.Next(ctx) {
+		payment := iter.Payment()
+		if payment.Status != nil &&
+			payment.Status.State != lnrpc.Payment_SUCCEEDED {
+
+			continue
+		}
+
+		rebalance, err := isSelfPayment(&payment, self)
+		if err != nil {
+			return nil, err
+		}
+		if rebalance {
+			rebalances[payment.Hash.String()] = true
+		}
+
+		if query.beforeStart(payment.Timestamp) ||
+			query.afterEnd(payment.Timestamp) {
+
+			continue
+		}
+
+		entry := &LedgerEntry{
+			Timestamp: payment.Timestamp,
+			Type:      LedgerPayment,
+			Reference: payment.Hash.String(),
+			Description: fmt.Sprintf("Payment of %v",
+				payment.Amount),
+		}
+
+		amount := int64(payment.Amount)
+		fee := int64(payment.Fee)
+
+		// The amount of a payment to ourselves only moved between our
+		// channels, so only its fee leaves our balance.
+		if rebalance {
+			entry.Type = LedgerRebalance
+			entry.Description = "Rebalance"
+			entry.Internal = true
+			amount = 0
+		}
+
+		entry.post(AccountPayments, amount)
+		entry.post(AccountPaymentFees, fee)
+		entry.post(AccountLightning, -amount-fee)
+
+		l.Entries = append(l.Entries, entry)
+	}
+
+	return rebalances, iter.Err()
+}
+
+// isSelfPayment returns whether a payment was sent to ourselves.
+func isSelfPayment(payment *Payment, self route.Vertex) (bool, error) {
+	for _, htlc := range payment.Htlcs {
+		if htlc.Status != lnrpc.HTLCAttempt_SUCCEEDED ||
+			htlc.Route == nil || len(htlc.Route.Hops) == 0 {
+
+			continue
+		}
+
+		lastHop := htlc.Route.Hops[len(htlc.Route.Hops)-1]
+		dest, err := route.NewVertexFromStr(lastHop.PubKey)
+		if err != nil {
+			return false, fmt.Errorf("payment %v: %v", payment.Hash,
+				err)
+		}
+
+		return dest == self, nil
+	}
+
+	return false, nil
+}
+
+// addInvoices adds our settled invoices to the ledger, apart from the ones
+// paid by ourselves.
+func (l *Ledger) addInvoices(ctx context.Context, lnd LightningClient,
+	req LedgerRequest, rebalances map[string]bool) error {
+
+	// Invoices are ordered by creation, but booked when they settle, so
+	// we filter them ourselves.
+	iter := NewInvoiceIterator(lnd, HistoryQuery{}, false)
+	for iter.Next(ctx) {
+		invoice := iter.Invoice()
+		if invoice.State != channeldb.ContractSettled ||
+			!req.contains(invoice.SettleDate) ||
+			rebalances[invoice.Hash.String()] {
+
+			continue
+		}
+
+		description := "Invoice"
+		if invoice.IsKeysend {
+			description = "Keysend"
+		}
+		if invoice.Memo != "" {
+			description += ": " + invoice.Memo
+		}
+
+		entry := &LedgerEntry{
+			Timestamp:   invoice.SettleDate,
+			Type:        LedgerInvoice,
+			Reference:   invoice.Hash.String(),
+			Description: description,
+		}
+		entry.post(AccountLightning, int64(invoice.AmountPaid))
+		entry.post(AccountInvoiceIncome, -int64(invoice.AmountPaid))
+
+		l.Entries = append(l.Entries, entry)
+	}
+
+	return iter.Err()
+}
+
+// formatMsat formats an amount in millisatoshis as satoshis.
+func formatMsat(amount int64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	return fmt.Sprintf("%v%d.%03d", sign, amount/1000, amount%1000)
+}
+
+// quoteString escapes a string for beancount and ledger-cli.
+func quoteString(s string) string {
+	return strings.Replace(strconv.Quote(s), "\\n", " ", -1)
+}
+
+// WriteJSON writes the ledger as a JSON array of entries.
+func (l *Ledger) WriteJSON(w io.Writer) error {
+	entries := l.Entries
+	if entries == nil {
+		entries = []*LedgerEntry{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(entries)
+}
+
+// WriteCSV writes the ledger as CSV with one row per posting.
+func (l *Ledger) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write([]string{
+		"timestamp", "type", "reference", "description", "internal",
+		"account", "amount_msat",
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range l.Entries {
+		for _, posting := range entry.Postings {
+			err := writer.Write([]string{
+				entry.Timestamp.UTC().Format(time.RFC3339),
+				string(entry.Type),
+				entry.Reference,
+				entry.Description,
+				strconv.FormatBool(entry.Internal),
+				posting.Account,
+				strconv.FormatInt(posting.AmountMsat, 10),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteBeancount writes the ledger in the beancount format. Accounts are
+// opened at the date of the first entry.
+func (l *Ledger) WriteBeancount(w io.Writer) error {
+	if len(l.Entries) == 0 {
+		return nil
+	}
+
+	var accounts []string
+	opened := make(map[string]bool)
+	for _, entry := range l.Entries {
+		for _, posting := range entry.Postings {
+			if !opened[posting.Account] {
+				opened[posting.Account] = true
+				accounts = append(accounts, posting.Account)
+			}
+		}
+	}
+	sort.Strings(accounts)
+
+	date := l.Entries[0].Timestamp.UTC().Format("2006-01-02")
+	for _, account := range accounts {
+		_, err := fmt.Fprintf(w, "%v open %v %v\n", date, account,
+			ledgerCommodity)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, entry := range l.Entries {
+		_, err := fmt.Fprintf(w, "\n%v * %v\n  type: %v\n"+
+			"  reference: %v\n  internal: %v\n",
+			entry.Timestamp.UTC().Format("2006-01-02"),
+			quoteString(entry.Description),
+			quoteString(string(entry.Type)),
+			quoteString(entry.Reference),
+			strings.ToUpper(strconv.FormatBool(entry.Internal)))
+		if err != nil {
+			return err
+		}
+
+		for _, posting := range entry.Postings {
+			_, err := fmt.Fprintf(w, "  %v  %v %v\n",
+				posting.Account, formatMsat(posting.AmountMsat),
+				ledgerCommodity)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// WriteLedger writes the ledger in the ledger-cli format.
+func (l *Ledger) WriteLedger(w io.Writer) error {
+	for i, entry := range l.Entries {
+		if i > 0 {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+
+		_, err := fmt.Fprintf(w, "%v %v\n    ; type: %v\n"+
+			"    ; reference: %v\n    ; internal: %v\n",
+			entry.Timestamp.UTC().Format("2006/01/02"),
+			strings.Replace(entry.Description, "\n", " ", -1),
+			entry.Type, entry.Reference,
+			entry.Internal)
+		if err != nil {
+			return err
+		}
+
+		for _, posting := range entry.Postings {
+			_, err := fmt.Fprintf(w, "    %v  %v %v\n",
+				posting.Account, formatMsat(posting.AmountMsat),
+				ledgerCommodity)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,298 @@
+package lndclient
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/channeldb"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+var (
+	ledgerSelf        = route.Vertex{9}
+	ledgerOther       = route.Vertex{3}
+	ledgerFunding     = chainhash.Hash{1}
+	ledgerCoopClose   = chainhash.Hash{5}
+	ledgerForceClose  = chainhash.Hash{6}
+	ledgerSecondLevel = chainhash.Hash{10}
+	ledgerRebalance   = lntypes.Hash{2}
+	ledgerStart       = time.Unix(1600000000, 0)
+)
+
+// ledgerHtlc returns a successful htlc that paid the destination.
+func ledgerHtlc(dest route.Vertex) []*lnrpc.HTLCAttempt {
+	return []*lnrpc.HTLCAttempt{{
+		Status: lnrpc.HTLCAttempt_SUCCEEDED,
+		Route: &lnrpc.Route{
+			Hops: []*lnrpc.Hop{{PubKey: dest.String()}},
+		},
+	}}
+}
+
+// ledgerTx returns a wallet transaction that spends the outpoint provided.
+func ledgerTx(hash string, spends wire.OutPoint, amount,
+	fee btcutil.Amount, hours time.Duration) Transaction {
+
+	tx := wire.NewMsgTx(2)
+	tx.AddTxIn(&wire.TxIn{PreviousOutPoint: spends})
+
+	return Transaction{
+		Tx:            tx,
+		TxHash:        hash,
+		Timestamp:     ledgerStart.Add(hours * time.Hour),
+		Amount:        amount,
+		Fee:           fee,
+		Confirmations: 1,
+	}
+}
+
+// newLedgerLightning returns a lightning client that received a deposit,
+// funded a channel, paid another node, rebalanced, was paid and forwarded.
+// A channel the remote opened was closed cooperatively and its output was
+// spent, and the outputs of a force closed channel were swept, one of them
+// through a second level htlc transaction.
+func newLedgerLightning() *mockLightning {
+	txs := []Transaction{{
+		TxHash:        "deposit",
+		Timestamp:     ledgerStart,
+		Amount:        1500000,
+		Confirmations: 1,
+	}, {
+		TxHash:        ledgerFunding.String(),
+		Timestamp:     ledgerStart.Add(time.Hour),
+		Amount:        -1002000,
+		Fee:           2000,
+		Confirmations: 1,
+	}}
+
+	txs = append(txs,
+		ledgerTx(
+			ledgerCoopClose.String(),
+			wire.OutPoint{Hash: chainhash.Hash{7}}, 100000, 0, 6,
+		),
+		ledgerTx(
+			"spend", wire.OutPoint{Hash: ledgerCoopClose}, -60000,
+			1000, 7,
+		),
+		ledgerTx(
+			"sweep", wire.OutPoint{Hash: ledgerForceClose}, 50000,
+			500, 8,
+		),
+		ledgerTx(
+			"htlc sweep", wire.OutPoint{Hash: ledgerSecondLevel},
+			20000, 300, 8,
+		),
+		Transaction{
+			TxHash:    "unconfirmed",
+			Timestamp: ledgerStart.Add(9 * time.Hour),
+			Amount:    1000,
+		},
+	)
+
+	return &mockLightning{
+		info: Info{
+			BlockHeight:    100,
+			IdentityPubkey: ledgerSelf,
+		},
+		channels: []ChannelInfo{{
+			ChannelPoint: ledgerFunding.String() + ":0",
+			Capacity:     1000000,
+			Initiator:    true,
+		}},
+		closed: []ClosedChannel{{
+			ChannelPoint:  chainhash.Hash{7}.String() + ":0",
+			ChannelID:     7,
+			ClosingTxHash: ledgerCoopClose.String(),
+			CloseType:     CloseTypeCooperative,
+			OpenInitiator: InitiatorRemote,
+			Capacity:      200000,
+		}, {
+			ChannelPoint:  chainhash.Hash{8}.String() + ":0",
+			ChannelID:     8,
+			ClosingTxHash: ledgerForceClose.String(),
+			CloseType:     CloseTypeLocalForce,
+			OpenInitiator: InitiatorRemote,
+			Capacity:      200000,
+			Resolutions: []*Resolution{{
+				Type:      lnrpc.ResolutionType_OUTGOING_HTLC,
+				Outcome:   lnrpc.ResolutionOutcome_FIRST_STAGE,
+				SweepTxid: ledgerSecondLevel.String(),
+			}, {
+				Type:      lnrpc.ResolutionType_OUTGOING_HTLC,
+				Outcome:   lnrpc.ResolutionOutcome_TIMEOUT,
+				SweepTxid: "htlc sweep",
+			}},
+		}},
+		txs: txs,
+		payments: []Payment{{
+			Hash:           lntypes.Hash{1},
+			Amount:         100000,
+			Fee:            1000,
+			Htlcs:          ledgerHtlc(ledgerOther),
+			SequenceNumber: 1,
+			Timestamp:      ledgerStart.Add(2 * time.Hour),
+		}, {
+			Hash:           ledgerRebalance,
+			Amount:         200000,
+			Fee:            2000,
+			Htlcs:          ledgerHtlc(ledgerSelf),
+			SequenceNumber: 2,
+			Timestamp:      ledgerStart.Add(3 * time.Hour),
+		}},
+		invoices: []Invoice{{
+			Hash:       lntypes.Hash{3},
+			Memo:       "coffee",
+			AmountPaid: 50000,
+			State:      channeldb.ContractSettled,
+			SettleDate: ledgerStart.Add(4 * time.Hour),
+			AddIndex:   1,
+		}, {
+			Hash:       ledgerRebalance,
+			AmountPaid: 200000,
+			State:      channeldb.ContractSettled,
+			SettleDate: ledgerStart.Add(3*time.Hour + time.Minute),
+			AddIndex:   2,
+		}, {
+			Hash:     lntypes.Hash{4},
+			State:    channeldb.ContractOpen,
+			AddIndex: 3,
+		}},
+		forwards: []ForwardingEvent{{
+			Timestamp:  ledgerStart.Add(5 * time.Hour),
+			ChannelIn:  1,
+			ChannelOut: 2,
+			FeeMsat:    1500,
+		}},
+	}
+}
+
+// TestExportLedger tests that all activity is classified, balanced and
+// ordered by time, that internal transfers are not booked as income, that
+// only sweeps of force closes are booked as sweeps and that rebalances are
+// recognized across the time bounds.
+func TestExportLedger(t *testing.T) {
+	ledger, err := ExportLedger(
+		context.Background(), newLedgerLightning(), LedgerRequest{},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []struct {
+		entryType LedgerEntryType
+		internal  bool
+	}{
+		{LedgerDeposit, false},
+		{LedgerChannelOpen, true},
+		{LedgerPayment, false},
+		{LedgerRebalance, true},
+		{LedgerInvoice, false},
+		{LedgerRoutingFee, false},
+		{LedgerChannelClose, true},
+		{LedgerWithdrawal, false},
+		{LedgerSweep, true},
+		{LedgerSweep, true},
+	}
+	if len(ledger.Entries) != len(expected) {
+		t.Fatalf("expected %v entries, got %v", len(expected),
+			len(ledger.Entries))
+	}
+
+	balances := make(map[string]int64)
+	for i, entry := range ledger.Entries {
+		if entry.Type != expected[i].entryType ||
+			entry.Internal != expected[i].internal {
+
+			t.Fatalf("unexpected entry %v: %+v", i, entry)
+		}
+
+		var sum int64
+		for _, posting := range entry.Postings {
+			sum += posting.AmountMsat
+			balances[posting.Account] += posting.AmountMsat
+		}
+		if sum != 0 {
+			t.Fatalf("unbalanced entry %v: %+v", i, entry)
+		}
+	}
+
+	// The channel funding, the closes and the rebalance are not income,
+	// and only their fees are expenses. The unconfirmed transaction is
+	// not booked.
+	if balances[AccountOnChain] != 608000000 ||
+		balances[AccountLightning] != 829148500 ||
+		balances[AccountChainFees] != 3800000 ||
+		balances[AccountPayments] != 100000 ||
+		balances[AccountPaymentFees] != 3000 ||
+		balances[AccountInvoiceIncome] != -50000 ||
+		balances[AccountRoutingIncome] != -1500 {
+
+		t.Fatalf("unexpected balances: %v", balances)
+	}
+
+	var buf bytes.Buffer
+	if err := ledger.WriteBeancount(&buf); err != nil {
+		t.Fatalf("unable to write beancount: %v", err)
+	}
+	beancount := buf.String()
+	if !strings.Contains(beancount, "2020-09-13 * \"Invoice: coffee\"") ||
+		!strings.Contains(beancount, "  Income:Bitcoin:Invoices  "+
+			"-50.000 SAT\n") {
+
+		t.Fatalf("unexpected beancount output:\n%v", beancount)
+	}
+
+	buf.Reset()
+	if err := ledger.WriteLedger(&buf); err != nil {
+		t.Fatalf("unable to write ledger: %v", err)
+	}
+	if !strings.Contains(buf.String(), "2020/09/13 Invoice: coffee\n") {
+		t.Fatalf("unexpected ledger output:\n%v", buf.String())
+	}
+
+	// Transactions outside of the time bounds are left out.
+	ledger, err = ExportLedger(
+		context.Background(), newLedgerLightning(), LedgerRequest{
+			StartTime: ledgerStart.Add(5 * time.Hour),
+			EndTime:   ledgerStart.Add(7 * time.Hour),
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ledger.Entries) != 3 ||
+		ledger.Entries[0].Type != LedgerRoutingFee ||
+		ledger.Entries[1].Type != LedgerChannelClose ||
+		ledger.Entries[2].Type != LedgerWithdrawal {
+
+		t.Fatalf("unexpected entries: %v", ledger.Entries)
+	}
+
+	// The invoice of a rebalance that was sent before the start time but
+	// settled after it is not booked as income.
+	ledger, err = ExportLedger(
+		context.Background(), newLedgerLightning(), LedgerRequest{
+			StartTime: ledgerStart.Add(3*time.Hour + time.Second),
+			EndTime:   ledgerStart.Add(4 * time.Hour),
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ledger.Entries[0].Reference != (lntypes.Hash{3}).String() {
+		t.Fatalf("unexpected entry: %+v", ledger.Entries[0])
+	}
+	for _, entry := range ledger.Entries {
+		if entry.Reference == ledgerRebalance.String() {
+			t.Fatalf("rebalance booked: %+v", entry)
+		}
+	}
+}
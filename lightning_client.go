@@ -7,6 +7,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -14,24 +17,35 @@ import (
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/psbt"
 	"github.com/lightningnetwork/lnd/channeldb"
 	"github.com/lightningnetwork/lnd/lnrpc"
-	"github.com/lightningnetwork/lnd/lnrpc/invoicesrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/verrpc"
 	"github.com/lightningnetwork/lnd/lntypes"
 	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
 	"github.com/lightningnetwork/lnd/lnwire"
 	"github.com/lightningnetwork/lnd/routing/route"
 	"github.com/lightningnetwork/lnd/zpay32"
-	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
 )
 
 // LightningClient exposes base lightning functionality.
 type LightningClient interface {
+	// PayInvoice pays an invoice through lnd's router and returns a
+	// channel that receives the final result of the payment. The amount
+	// must only be set for invoices that don't specify an amount. If the
+	// invoice was already paid or a payment for it is still in flight, the
+	// result of that payment is returned.
+	//
+	// NOTE: The amount parameter was added to this method, which breaks
+	// callers of the previous signature. They keep the previous behavior
+	// by passing nil.
 	PayInvoice(ctx context.Context, invoice string,
-		maxFee btcutil.Amount,
-		outgoingChannel *uint64) chan PaymentResult
+		maxFee btcutil.Amount, outgoingChannel *uint64,
+		amount *btcutil.Amount) chan PaymentResult
 
 	GetInfo(ctx context.Context) (*Info, error)
 
@@ -41,8 +55,10 @@ type LightningClient interface {
 	// WalletBalance returns a summary of the node's wallet balance.
 	WalletBalance(ctx context.Context) (*WalletBalance, error)
 
-	AddInvoice(ctx context.Context, in *invoicesrpc.AddInvoiceData) (
-		lntypes.Hash, string, error)
+	// AddInvoice adds a new invoice and returns its hash, payment request
+	// and indices.
+	AddInvoice(ctx context.Context, in *AddInvoiceRequest) (
+		*AddInvoiceResult, error)
 
 	// LookupInvoice looks up an invoice by hash.
 	LookupInvoice(ctx context.Context, hash lntypes.Hash) (*Invoice, error)
@@ -61,6 +77,11 @@ type LightningClient interface {
 	// PendingChannels returns a list of lnd's pending channels.
 	PendingChannels(ctx context.Context) (*PendingChannels, error)
 
+	// AbandonChannel removes a channel from lnd's database without closing
+	// it on chain. This is only safe for channels whose funding
+	// transaction will never confirm.
+	AbandonChannel(ctx context.Context, channelPoint wire.OutPoint) error
+
 	// ClosedChannels returns all closed channels of the backing lnd node.
 	ClosedChannels(ctx context.Context) ([]ClosedChannel, error)
 
@@ -102,12 +123,54 @@ type LightningClient interface {
 	DecodePaymentRequest(ctx context.Context,
 		payReq string) (*PaymentRequest, error)
 
-	// OpenChannel opens a channel to the peer provided with the amounts
-	// specified.
-	OpenChannel(ctx context.Context, peer route.Vertex,
-		localSat, pushSat btcutil.Amount, private bool) (
+	// QueryRoutes queries lnd for a route to the target of the request.
+	QueryRoutes(ctx context.Context, req *QueryRoutesRequest) (
+		*QueryRoutesResponse, error)
+
+	// OpenChannel opens a channel with the options provided and returns
+	// the funding outpoint once the funding transaction was published.
+	OpenChannel(ctx context.Context, req *OpenChannelRequest) (
 		*wire.OutPoint, error)
 
+	// OpenChannelStream opens a channel with the options provided and
+	// returns a channel that sends updates about the progress of the
+	// funding flow until the channel is open.
+	OpenChannelStream(ctx context.Context, req *OpenChannelRequest) (
+		chan OpenStatusUpdate, chan error, error)
+
+	// OpenChannelPsbt opens a channel that is funded with a PSBT, which is
+	// funded and signed by the funder provided.
+	OpenChannelPsbt(ctx context.Context, req *OpenChannelRequest,
+		funder PsbtFunder, timeout time.Duration) (*wire.OutPoint,
+		error)
+
+	// BatchOpenChannel opens channels to several peers with a single
+	// funding transaction. It uses lnd's native batch open if possible
+	// and falls back to a PSBT that is funded and signed by the funder
+	// provided.
+	BatchOpenChannel(ctx context.Context, reqs []*OpenChannelRequest,
+		funder PsbtFunder, timeout time.Duration) ([]*BatchChannel,
+		error)
+
+	// ChannelAcceptor registers a channel acceptor that decides on every
+	// inbound channel open.
+	ChannelAcceptor(ctx context.Context, timeout time.Duration,
+		acceptor AcceptorFunc) (chan error, error)
+
+	// PsbtVerify passes the funded PSBT of a channel open to lnd for
+	// verification.
+	PsbtVerify(ctx context.Context, pendingChanID [32]byte,
+		funded *psbt.Packet) error
+
+	// PsbtFinalize passes the signed PSBT of a channel open to lnd, which
+	// continues the funding flow.
+	PsbtFinalize(ctx context.Context, pendingChanID [32]byte,
+		signed *psbt.Packet) error
+
+	// FundingShimCancel cancels the funding shim of a pending channel
+	// open.
+	FundingShimCancel(ctx context.Context, pendingChanID [32]byte) error
+
 	// CloseChannel closes the channel provided.
 	CloseChannel(ctx context.Context, channel *wire.OutPoint,
 		force bool, confTarget int32, deliveryAddr btcutil.Address) (
@@ -119,6 +182,15 @@ type LightningClient interface {
 	UpdateChanPolicy(ctx context.Context, req PolicyUpdateRequest,
 		chanPoint *wire.OutPoint) error
 
+	// UpdateChanPolicies applies a separate policy to each of the channels
+	// provided and reports the result of every update.
+	UpdateChanPolicies(ctx context.Context,
+		updates []ChannelPolicyUpdate) []ChannelPolicyResult
+
+	// FeeReport returns the current fee policies of all channels and the
+	// fees earned by forwarding.
+	FeeReport(ctx context.Context) (*FeeReport, error)
+
 	// GetChanInfo returns the channel info for the passed channel,
 	// including the routing policy for both end.
 	GetChanInfo(ctx context.Context, chanId uint64) (*ChannelEdge, error)
@@ -133,6 +205,15 @@ type LightningClient interface {
 	Connect(ctx context.Context, peer route.Vertex, host string,
 		permanent bool) error
 
+	// DisconnectPeer disconnects from the peer provided. lnd refuses to
+	// disconnect from peers that we have open channels with.
+	DisconnectPeer(ctx context.Context, peer route.Vertex) error
+
+	// SubscribePeerEvents subscribes to peers coming online and going
+	// offline.
+	SubscribePeerEvents(ctx context.Context) (<-chan *PeerEvent,
+		<-chan error, error)
+
 	// SendCoins sends the passed amount of (or all) coins to the passed
 	// address. Either amount or sendAll must be specified, while
 	// confTarget, satsPerByte are optional and may be set to zero in which
@@ -163,6 +244,12 @@ type LightningClient interface {
 	// of newly added/settled invoices.
 	SubscribeInvoices(ctx context.Context, req InvoiceSubscriptionRequest) (
 		<-chan *Invoice, <-chan error, error)
+
+	// SubscribeTransactions subscribes to on chain transactions that are
+	// relevant to our wallet. Transactions are sent once when they are
+	// seen unconfirmed and again when they confirm.
+	SubscribeTransactions(ctx context.Context) (<-chan *Transaction,
+		<-chan error, error)
 }
 
 // Info contains info about the connected lnd node.
@@ -443,6 +530,31 @@ type ClosedChannel struct {
 	// channel close. Note that this does not include cases where we need to
 	// sweep our commitment or htlcs.
 	SettledBalance btcutil.Amount
+
+	// Resolutions holds the outputs of a force close that were resolved on
+	// chain.
+	Resolutions []*Resolution
+}
+
+// Resolution describes how an output of a force closed channel was resolved.
+type Resolution struct {
+	// Type is the type of output that was resolved.
+	Type lnrpc.ResolutionType
+
+	// Outcome is the outcome of the on chain action that resolved the
+	// output.
+	Outcome lnrpc.ResolutionOutcome
+
+	// Outpoint is the output that was resolved.
+	Outpoint string
+
+	// Amount is the amount that was claimed.
+	Amount btcutil.Amount
+
+	// SweepTxid is the hash of the transaction that spent the output. An
+	// htlc that was resolved through a second level transaction has one
+	// resolution for each stage.
+	SweepTxid string
 }
 
 // CloseType is an enum which represents the types of closes our channels may
@@ -567,6 +679,10 @@ type Transaction struct {
 
 	// Label is an optional label set for on chain transactions.
 	Label string
+
+	// BlockHeight is the height of the block that includes the
+	// transaction. It is zero for unconfirmed transactions.
+	BlockHeight int32
 }
 
 // Peer contains information about a peer we are connected to.
@@ -595,6 +711,62 @@ type Peer struct {
 
 	// Received is the total amount we have received from this peer.
 	Received btcutil.Amount
+
+	// Features is the set of features the peer advertised in its init
+	// message.
+	Features []lnwire.FeatureBit
+
+	// FlapCount is the number of times the peer went offline.
+	//
+	// NOTE: lnd v0.11 doesn't track flaps, so ListPeers leaves this zero.
+	// The PeerManager fills it in from the peer events it observed.
+	FlapCount int
+
+	// LastError is the last error the peer sent us. Errors are only kept
+	// for peers that we have channels with.
+	LastError string
+
+	// LastErrorTime is the time the last error was received. It is zero
+	// if the peer didn't send us an error.
+	LastErrorTime time.Time
+}
+
+// PeerEventType indicates whether a peer came online or went offline.
+type PeerEventType uint8
+
+const (
+	// PeerOnline is sent when a peer connected to us.
+	PeerOnline PeerEventType = iota
+
+	// PeerOffline is sent when a peer disconnected from us.
+	PeerOffline
+)
+
+// String returns a string representation of the event type.
+func (p PeerEventType) String() string {
+	switch p {
+	case PeerOnline:
+		return "online"
+
+	case PeerOffline:
+		return "offline"
+
+	default:
+		return "unknown"
+	}
+}
+
+// PeerEvent is sent when a peer comes online or goes offline.
+type PeerEvent struct {
+	// Pubkey is the peer's pubkey.
+	Pubkey route.Vertex
+
+	// Type indicates whether the peer came online or went offline.
+	Type PeerEventType
+
+	// Timestamp is the time the event was received. lnd doesn't report
+	// the time of the event itself.
+	Timestamp time.Time
 }
 
 // ChannelBalance contains information about our channel balances.
@@ -805,44 +977,82 @@ var (
 
 	// PaymentResultSuccess is the string result returned by SendPayment
 	// when the payment was successful.
+	//
+	// Deprecated: PayInvoice reports payment results through
+	// PaymentResult and no longer compares result strings.
 	PaymentResultSuccess = ""
 
 	// PaymentResultAlreadyPaid is the string result returned by SendPayment
 	// when the payment was already completed in a previous SendPayment
 	// call.
+	//
+	// Deprecated: PayInvoice returns the result of the existing payment
+	// instead.
 	PaymentResultAlreadyPaid = channeldb.ErrAlreadyPaid.Error()
 
 	// PaymentResultInFlight is the string result returned by SendPayment
 	// when the payment was initiated in a previous SendPayment call and
 	// still in flight.
+	//
+	// Deprecated: PayInvoice tracks the existing payment until it is
+	// final instead.
 	PaymentResultInFlight = channeldb.ErrPaymentInFlight.Error()
 
-	paymentPollInterval = 3 * time.Second
+	// defaultPaymentTimeout is the time after which lnd stops trying new
+	// routes for a payment started by PayInvoice.
+	defaultPaymentTimeout = 60 * time.Second
 )
 
 type lightningClient struct {
-	client   lnrpc.LightningClient
-	wg       sync.WaitGroup
-	params   *chaincfg.Params
-	adminMac serializedMacaroon
+	client       lnrpc.LightningClient
+	routerClient routerrpc.RouterClient
+	wg           sync.WaitGroup
+	params       *chaincfg.Params
+	adminMac     serializedMacaroon
+
+	// version is the version of the connected lnd, nil if unknown.
+	version *verrpc.Version
 }
 
-func newLightningClient(conn *grpc.ClientConn,
-	params *chaincfg.Params, adminMac serializedMacaroon) *lightningClient {
+func newLightningClient(client lnrpc.LightningClient,
+	routerClient routerrpc.RouterClient, params *chaincfg.Params,
+	adminMac serializedMacaroon) *lightningClient {
 
 	return &lightningClient{
-		client:   lnrpc.NewLightningClient(conn),
-		params:   params,
-		adminMac: adminMac,
+		client:       client,
+		routerClient: routerClient,
+		params:       params,
+		adminMac:     adminMac,
 	}
 }
 
 // PaymentResult signals the result of a payment.
 type PaymentResult struct {
-	Err      error
+	// Err is set if the payment couldn't be completed. If lnd reported
+	// the payment as failed, it is a *PaymentFailedError.
+	Err error
+
+	// FailureReason is the reason lnd reported for a failed payment.
+	FailureReason lnrpc.PaymentFailureReason
+
 	Preimage lntypes.Preimage
 	PaidFee  btcutil.Amount
 	PaidAmt  btcutil.Amount
+
+	// Routes are the routes of all parts of a successful payment.
+	Routes []*lnrpc.Route
+}
+
+// PaymentFailedError is the error that is returned if lnd reported a payment
+// as failed.
+type PaymentFailedError struct {
+	// Reason is the reason the payment failed.
+	Reason lnrpc.PaymentFailureReason
+}
+
+// Error returns the error message of the payment failure.
+func (e *PaymentFailedError) Error() string {
+	return fmt.Sprintf("payment failed: %v", e.Reason)
 }
 
 func (s *lightningClient) WaitForFinished() {
@@ -934,9 +1144,16 @@ func (s *lightningClient) EstimateFeeToP2WSH(ctx context.Context,
 	return btcutil.Amount(resp.FeeSat), nil
 }
 
-// PayInvoice pays an invoice.
+// PayInvoice pays an invoice through lnd's router and returns a channel that
+// receives the final result of the payment. The amount must only be set for
+// invoices that don't specify an amount. If the invoice was already paid or a
+// payment for it is still in flight, the result of that payment is returned.
+//
+// NOTE: The amount parameter was added to this method, which breaks callers of
+// the previous signature. They keep the previous behavior by passing nil.
 func (s *lightningClient) PayInvoice(ctx context.Context, invoice string,
-	maxFee btcutil.Amount, outgoingChannel *uint64) chan PaymentResult {
+	maxFee btcutil.Amount, outgoingChannel *uint64,
+	amount *btcutil.Amount) chan PaymentResult {
 
 	// Use buffer to prevent blocking.
 	paymentChan := make(chan PaymentResult, 1)
@@ -947,7 +1164,9 @@ func (s *lightningClient) PayInvoice(ctx context.Context, invoice string,
 	go func() {
 		defer s.wg.Done()
 
-		result := s.payInvoice(ctx, invoice, maxFee, outgoingChannel)
+		result := s.payInvoice(
+			ctx, invoice, maxFee, outgoingChannel, amount,
+		)
 		if result != nil {
 			paymentChan <- *result
 		}
@@ -956,10 +1175,11 @@ func (s *lightningClient) PayInvoice(ctx context.Context, invoice string,
 	return paymentChan
 }
 
-// payInvoice tries to send a payment and returns the final result. If
-// necessary, it will poll lnd for the payment result.
+// payInvoice sends a payment and waits for its final result. If the payment
+// was already started before, the existing payment is tracked instead.
 func (s *lightningClient) payInvoice(ctx context.Context, invoice string,
-	maxFee btcutil.Amount, outgoingChannel *uint64) *PaymentResult {
+	maxFee btcutil.Amount, outgoingChannel *uint64,
+	amount *btcutil.Amount) *PaymentResult {
 
 	payReq, err := zpay32.Decode(invoice, s.params)
 	if err != nil {
@@ -968,119 +1188,252 @@ func (s *lightningClient) payInvoice(ctx context.Context, invoice string,
 		}
 	}
 
-	if payReq.MilliSat == nil {
+	switch {
+	case payReq.MilliSat == nil && amount == nil:
+		return &PaymentResult{
+			Err: errors.New("no amount in invoice and no amount " +
+				"specified"),
+		}
+
+	case payReq.MilliSat != nil && amount != nil:
 		return &PaymentResult{
-			Err: errors.New("no amount in invoice"),
+			Err: errors.New("amount must not be specified for " +
+				"invoices with an amount"),
 		}
 	}
 
 	hash := lntypes.Hash(*payReq.PaymentHash)
 
+	// Create no timeout context as this call can block for a long time.
 	ctx = s.adminMac.WithMacaroonAuth(ctx)
-	for {
-		// Create no timeout context as this call can block for a long
-		// time.
-
-		req := &lnrpc.SendRequest{
-			FeeLimit: &lnrpc.FeeLimit{
-				Limit: &lnrpc.FeeLimit_Fixed{
-					Fixed: int64(maxFee),
-				},
+	req := &routerrpc.SendPaymentRequest{
+		PaymentRequest: invoice,
+		FeeLimitSat:    int64(maxFee),
+		TimeoutSeconds: int32(defaultPaymentTimeout.Seconds()),
+	}
+	if amount != nil {
+		req.Amt = int64(*amount)
+	}
+	if outgoingChannel != nil {
+		req.OutgoingChanIds = []uint64{*outgoingChannel}
+	}
+
+	stream, err := s.routerClient.SendPaymentV2(ctx, req)
+	if err != nil {
+		return paymentErrResult(err)
+	}
+	payment, err := finalPayment(stream)
+
+	// If the payment was started before, it either already succeeded or
+	// is still in flight. In both cases we pick up the existing payment
+	// to learn about its actual outcome.
+	if status.Code(err) == codes.AlreadyExists {
+		log.Infof("Payment %v already initiated, tracking it", hash)
+
+		stream, err = s.routerClient.TrackPaymentV2(
+			ctx, &routerrpc.TrackPaymentRequest{
+				PaymentHash: hash[:],
+			},
+		)
+		if err != nil {
+			return paymentErrResult(err)
+		}
+		payment, err = finalPayment(stream)
+	}
+	if err != nil {
+		return paymentErrResult(err)
+	}
+
+	if payment.Status == lnrpc.Payment_FAILED {
+		log.Warnf("Payment %v failed: %v", hash, payment.FailureReason)
+
+		return &PaymentResult{
+			Err: &PaymentFailedError{
+				Reason: payment.FailureReason,
 			},
-			PaymentRequest: invoice,
+			FailureReason: payment.FailureReason,
 		}
+	}
+
+	log.Infof("Payment %v completed", hash)
+
+	preimage, err := lntypes.MakePreimageFromStr(payment.PaymentPreimage)
+	if err != nil {
+		return &PaymentResult{Err: err}
+	}
 
-		if outgoingChannel != nil {
-			req.OutgoingChanId = *outgoingChannel
+	var routes []*lnrpc.Route
+	for _, htlc := range payment.Htlcs {
+		if htlc.Status == lnrpc.HTLCAttempt_SUCCEEDED {
+			routes = append(routes, htlc.Route)
 		}
+	}
+
+	return &PaymentResult{
+		Preimage: preimage,
+		PaidFee:  lnwire.MilliSatoshi(payment.FeeMsat).ToSatoshis(),
+		PaidAmt:  lnwire.MilliSatoshi(payment.ValueMsat).ToSatoshis(),
+		Routes:   routes,
+	}
+}
 
-		payResp, err := s.client.SendPaymentSync(ctx, req)
+// finalPayment reads payment updates from the stream until the payment
+// reached a final state.
+func finalPayment(stream routerrpc.Router_TrackPaymentV2Client) (
+	*lnrpc.Payment, error) {
+
+	for {
+		payment, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
 
-		if status.Code(err) == codes.Canceled {
-			return nil
+		switch payment.Status {
+		case lnrpc.Payment_SUCCEEDED, lnrpc.Payment_FAILED:
+			return payment, nil
 		}
+	}
+}
 
-		if err == nil {
-			// TODO: Use structured payment error when available,
-			// instead of this britle string matching.
-			switch payResp.PaymentError {
+// paymentErrResult returns the payment result for an error of the payment
+// RPCs. If the payment was canceled by the caller, no result is returned.
+func paymentErrResult(err error) *PaymentResult {
+	if status.Code(err) == codes.Canceled {
+		return nil
+	}
 
-			// Paid successfully.
-			case PaymentResultSuccess:
-				log.Infof(
-					"Payment %v completed", hash,
-				)
+	return &PaymentResult{Err: err}
+}
 
-				r := payResp.PaymentRoute
-				preimage, err := lntypes.MakePreimage(
-					payResp.PaymentPreimage,
-				)
-				if err != nil {
-					return &PaymentResult{Err: err}
-				}
-				return &PaymentResult{
-					PaidFee: btcutil.Amount(r.TotalFees),
-					PaidAmt: btcutil.Amount(
-						r.TotalAmt - r.TotalFees,
-					),
-					Preimage: preimage,
-				}
+// AddInvoiceRequest holds all parameters of a new invoice.
+type AddInvoiceRequest struct {
+	// Memo is an optional memo that is added to the description of the
+	// invoice, unless DescriptionHash is set.
+	Memo string
 
-			// Invoice was already paid on a previous run.
-			case PaymentResultAlreadyPaid:
-				log.Infof(
-					"Payment %v already completed", hash,
-				)
+	// Preimage is the preimage that settles the invoice. Only one of
+	// Preimage and Hash may be set. If neither is set, a random preimage
+	// is generated.
+	Preimage *lntypes.Preimage
 
-				// Unfortunately lnd doesn't return the route if
-				// the payment was successful in a previous
-				// call. Assume paid fees 0 and take paid amount
-				// from invoice.
+	// Hash is the payment hash of the invoice. It must be set for hold
+	// invoices, for which the preimage isn't known yet.
+	Hash *lntypes.Hash
 
-				return &PaymentResult{
-					PaidFee: 0,
-					PaidAmt: payReq.MilliSat.ToSatoshis(),
-				}
+	// Value is the value of the invoice. If it is zero, the payer can
+	// choose the amount.
+	Value lnwire.MilliSatoshi
 
-			// If the payment is already in flight, we will poll
-			// again later for an outcome.
-			//
-			// TODO: Improve this when lnd expose more API to
-			// tracking existing payments.
-			case PaymentResultInFlight:
-				log.Infof(
-					"Payment %v already in flight", hash,
-				)
+	// DescriptionHash is the SHA-256 hash of the description of the
+	// payment. It is used instead of the memo if the description is too
+	// long to fit into the invoice.
+	DescriptionHash []byte
+
+	// Expiry is the expiry of the invoice in seconds. If zero, lnd's
+	// default of one hour is used.
+	Expiry int64
+
+	// FallbackAddr is an optional on-chain address the invoice can be
+	// paid to.
+	FallbackAddr string
+
+	// CltvExpiry is the CLTV delta of the final hop. If zero, lnd's
+	// default is used.
+	CltvExpiry uint64
+
+	// DisablePrivateHints stops lnd from adding route hints for our
+	// private channels to the invoice, which it does by default.
+	DisablePrivateHints bool
+
+	// RouteHints are additional route hints that are added to the
+	// invoice.
+	//
+	// NOTE: Adding the invoice fails with an UnsupportedRPCError if route
+	// hints are set and the connected lnd is older than v0.12.0, which
+	// ignores them.
+	RouteHints [][]zpay32.HopHint
+}
 
-				time.Sleep(paymentPollInterval)
+// invoiceRouteHintsVersion is the first lnd version that adds the route hints
+// passed to AddInvoice and AddHoldInvoice to the invoice.
+var invoiceRouteHintsVersion = &verrpc.Version{AppMinor: 12}
 
-			// Other errors are transformed into an error struct.
-			default:
-				log.Warnf(
-					"Payment %v failed: %v", hash,
-					payResp.PaymentError,
-				)
+// checkInvoiceRouteHints makes sure the connected lnd adds the route hints of
+// the invoice request, if there are any.
+func checkInvoiceRouteHints(version *verrpc.Version,
+	in *AddInvoiceRequest) error {
 
-				return &PaymentResult{
-					Err: errors.New(payResp.PaymentError),
-				}
-			}
-		}
+	if len(in.RouteHints) == 0 {
+		return nil
+	}
+
+	return checkVersion(version, "invoice route hints",
+		invoiceRouteHintsVersion)
+}
+
+// AddInvoiceResult is the result of adding a new invoice.
+type AddInvoiceResult struct {
+	// Hash is the payment hash of the invoice.
+	Hash lntypes.Hash
+
+	// PaymentRequest is the encoded payment request of the invoice.
+	PaymentRequest string
+
+	// PaymentAddr is the payment address of the invoice, which payers
+	// need to include in their payment. It is nil if the invoice doesn't
+	// have a payment address.
+	PaymentAddr *[32]byte
+
+	// AddIndex is the index of the invoice in lnd's invoice database.
+	//
+	// NOTE: This is not reported for hold invoices by lnd.
+	AddIndex uint64
+}
+
+// newAddInvoiceResult creates the result of adding an invoice, taking the
+// payment address from the payment request.
+func newAddInvoiceResult(hash lntypes.Hash, payReq string, addIndex uint64,
+	params *chaincfg.Params) (*AddInvoiceResult, error) {
+
+	invoice, err := zpay32.Decode(payReq, params)
+	if err != nil {
+		return nil, fmt.Errorf("invoice decode: %v", err)
 	}
+
+	return &AddInvoiceResult{
+		Hash:           hash,
+		PaymentRequest: payReq,
+		PaymentAddr:    invoice.PaymentAddr,
+		AddIndex:       addIndex,
+	}, nil
 }
 
+// AddInvoice adds a new invoice and returns its hash, payment request and
+// indices.
 func (s *lightningClient) AddInvoice(ctx context.Context,
-	in *invoicesrpc.AddInvoiceData) (lntypes.Hash, string, error) {
+	in *AddInvoiceRequest) (*AddInvoiceResult, error) {
+
+	if err := checkInvoiceRouteHints(s.version, in); err != nil {
+		return nil, err
+	}
 
 	rpcCtx, cancel := context.WithTimeout(ctx, rpcTimeout)
 	defer cancel()
 
+	routeHints, err := marshallRouteHints(in.RouteHints)
+	if err != nil {
+		return nil, err
+	}
+
 	rpcIn := &lnrpc.Invoice{
-		Memo:       in.Memo,
-		Value:      int64(in.Value.ToSatoshis()),
-		Expiry:     in.Expiry,
-		CltvExpiry: in.CltvExpiry,
-		Private:    true,
+		Memo:            in.Memo,
+		ValueMsat:       int64(in.Value),
+		DescriptionHash: in.DescriptionHash,
+		Expiry:          in.Expiry,
+		FallbackAddr:    in.FallbackAddr,
+		CltvExpiry:      in.CltvExpiry,
+		RouteHints:      routeHints,
+		Private:         !in.DisablePrivateHints,
 	}
 
 	if in.Preimage != nil {
@@ -1093,14 +1446,16 @@ func (s *lightningClient) AddInvoice(ctx context.Context,
 	rpcCtx = s.adminMac.WithMacaroonAuth(rpcCtx)
 	resp, err := s.client.AddInvoice(rpcCtx, rpcIn)
 	if err != nil {
-		return lntypes.Hash{}, "", err
+		return nil, err
 	}
 	hash, err := lntypes.MakeHash(resp.RHash)
 	if err != nil {
-		return lntypes.Hash{}, "", err
+		return nil, err
 	}
 
-	return hash, resp.PaymentRequest, nil
+	return newAddInvoiceResult(
+		hash, resp.PaymentRequest, resp.AddIndex, s.params,
+	)
 }
 
 // WalletBalance describes our wallet's current balance.
@@ -1293,30 +1648,41 @@ func (s *lightningClient) ListTransactions(ctx context.Context, startHeight,
 
 	txs := make([]Transaction, len(resp.Transactions))
 	for i, respTx := range resp.Transactions {
-		rawTx, err := hex.DecodeString(respTx.RawTxHex)
+		tx, err := unmarshallTransaction(respTx)
 		if err != nil {
 			return nil, err
 		}
 
-		var tx wire.MsgTx
-		if err := tx.Deserialize(bytes.NewReader(rawTx)); err != nil {
-			return nil, err
-		}
-
-		txs[i] = Transaction{
-			Tx:            &tx,
-			TxHash:        tx.TxHash().String(),
-			Timestamp:     time.Unix(respTx.TimeStamp, 0),
-			Amount:        btcutil.Amount(respTx.Amount),
-			Fee:           btcutil.Amount(respTx.TotalFees),
-			Confirmations: respTx.NumConfirmations,
-			Label:         respTx.Label,
-		}
+		txs[i] = *tx
 	}
 
 	return txs, nil
 }
 
+// unmarshallTransaction converts an rpc transaction.
+func unmarshallTransaction(respTx *lnrpc.Transaction) (*Transaction, error) {
+	rawTx, err := hex.DecodeString(respTx.RawTxHex)
+	if err != nil {
+		return nil, err
+	}
+
+	var tx wire.MsgTx
+	if err := tx.Deserialize(bytes.NewReader(rawTx)); err != nil {
+		return nil, err
+	}
+
+	return &Transaction{
+		Tx:            &tx,
+		TxHash:        tx.TxHash().String(),
+		Timestamp:     time.Unix(respTx.TimeStamp, 0),
+		Amount:        btcutil.Amount(respTx.Amount),
+		Fee:           btcutil.Amount(respTx.TotalFees),
+		Confirmations: respTx.NumConfirmations,
+		Label:         respTx.Label,
+		BlockHeight:   respTx.BlockHeight,
+	}, nil
+}
+
 // ListChannels retrieves all channels of the backing lnd node.
 func (s *lightningClient) ListChannels(ctx context.Context) (
 	[]ChannelInfo, error) {
@@ -1539,6 +1905,24 @@ func getClosedChannel(closeSummary *lnrpc.ChannelCloseSummary) (
 		return nil, err
 	}
 
+	resolutions := make([]*Resolution, len(closeSummary.Resolutions))
+	for i, resolution := range closeSummary.Resolutions {
+		var outpoint string
+		if resolution.Outpoint != nil {
+			outpoint = fmt.Sprintf("%v:%v",
+				resolution.Outpoint.TxidStr,
+				resolution.Outpoint.OutputIndex)
+		}
+
+		resolutions[i] = &Resolution{
+			Type:      resolution.ResolutionType,
+			Outcome:   resolution.Outcome,
+			Outpoint:  outpoint,
+			Amount:    btcutil.Amount(resolution.AmountSat),
+			SweepTxid: resolution.SweepTxid,
+		}
+	}
+
 	return &ClosedChannel{
 		ChannelPoint:   closeSummary.ChannelPoint,
 		ChannelID:      closeSummary.ChanId,
@@ -1550,6 +1934,7 @@ func getClosedChannel(closeSummary *lnrpc.ChannelCloseSummary) (
 		PubKeyBytes:    remote,
 		Capacity:       btcutil.Amount(closeSummary.Capacity),
 		SettledBalance: btcutil.Amount(closeSummary.SettledBalance),
+		Resolutions:    resolutions,
 	}, nil
 }
 
@@ -1777,7 +2162,7 @@ func (s *lightningClient) ListInvoices(ctx context.Context,
 	resp, err := s.client.ListInvoices(
 		s.adminMac.WithMacaroonAuth(rpcCtx),
 		&lnrpc.ListInvoiceRequest{
-			PendingOnly:    false,
+			PendingOnly:    req.PendingOnly,
 			IndexOffset:    req.Offset,
 			NumMaxInvoices: req.MaxInvoices,
 			Reversed:       req.Reversed,
@@ -1832,6 +2217,9 @@ type Payment struct {
 
 	// SequenceNumber is a unique id for each payment.
 	SequenceNumber uint64
+
+	// Timestamp is the time the payment was created.
+	Timestamp time.Time
 }
 
 // ListPaymentsRequest contains the request parameters for a paginated
@@ -1902,6 +2290,7 @@ func (s *lightningClient) ListPayments(ctx context.Context,
 			Amount:         lnwire.MilliSatoshi(payment.ValueMsat),
 			Fee:            lnwire.MilliSatoshi(payment.FeeMsat),
 			SequenceNumber: payment.PaymentIndex,
+			Timestamp:      time.Unix(0, payment.CreationTimeNs),
 		}
 
 		// Add our preimage if it is known.
@@ -1925,6 +2314,30 @@ func (s *lightningClient) ListPayments(ctx context.Context,
 	}, nil
 }
 
+// AbandonChannel removes a channel from lnd's database without closing it on
+// chain. This is only safe for channels whose funding transaction will never
+// confirm, as lnd no longer watches abandoned channels on chain.
+//
+// NOTE: lnd v0.11 only offers this call in dev builds.
+func (s *lightningClient) AbandonChannel(ctx context.Context,
+	channelPoint wire.OutPoint) error {
+
+	rpcCtx, cancel := context.WithTimeout(ctx, rpcTimeout)
+	defer cancel()
+
+	rpcCtx = s.adminMac.WithMacaroonAuth(rpcCtx)
+	_, err := s.client.AbandonChannel(rpcCtx, &lnrpc.AbandonChannelRequest{
+		ChannelPoint: &lnrpc.ChannelPoint{
+			FundingTxid: &lnrpc.ChannelPoint_FundingTxidBytes{
+				FundingTxidBytes: channelPoint.Hash[:],
+			},
+			OutputIndex: channelPoint.Index,
+		},
+	})
+
+	return err
+}
+
 // ChannelBackup retrieves the backup for a particular channel. The backup is
 // returned as an encrypted chanbackup.Single payload.
 func (s *lightningClient) ChannelBackup(ctx context.Context,
@@ -2208,28 +2621,191 @@ func (s *lightningClient) DecodePaymentRequest(ctx context.Context,
 	return paymentReq, nil
 }
 
-// OpenChannel opens a channel to the peer provided with the amounts specified.
-func (s *lightningClient) OpenChannel(ctx context.Context, peer route.Vertex,
-	localSat, pushSat btcutil.Amount, private bool) (*wire.OutPoint, error) {
+// OpenChannelRequest holds the parameters of a channel open.
+type OpenChannelRequest struct {
+	// Peer is the public key of the peer to open the channel with.
+	Peer route.Vertex
+
+	// LocalAmt is the amount that we commit to the channel.
+	LocalAmt btcutil.Amount
+
+	// PushAmt is the amount that is pushed to the remote side as part of
+	// the initial commitment state.
+	PushAmt btcutil.Amount
+
+	// Private denotes whether the channel is announced to the network.
+	Private bool
+
+	// FeeRate is the fee rate used for the funding transaction. Only one
+	// of FeeRate and TargetConf may be set. If neither is set, lnd uses
+	// its default confirmation target.
+	FeeRate chainfee.SatPerKWeight
+
+	// TargetConf is the confirmation target used to estimate the fee rate
+	// of the funding transaction.
+	TargetConf int32
+
+	// MinConfs is the minimum number of confirmations that the outputs
+	// used to fund the channel must have. If zero, lnd's default of one
+	// confirmation is used.
+	MinConfs int32
+
+	// SpendUnconfirmed allows the use of unconfirmed outputs to fund the
+	// channel. It can't be combined with MinConfs.
+	SpendUnconfirmed bool
+
+	// CloseAddress is an optional upfront shutdown address that the funds
+	// are paid to on cooperative close.
+	CloseAddress btcutil.Address
+
+	// RemoteCsvDelay is the delay that the remote party has to wait to
+	// claim its funds after a force close. If zero, lnd scales the delay
+	// with the channel size.
+	RemoteCsvDelay uint32
+
+	// MinHtlc is the minimum HTLC value that we accept. If zero, lnd's
+	// default is used.
+	MinHtlc lnwire.MilliSatoshi
+
+	// RemoteMaxValueInFlight is the maximum value in flight that the
+	// remote party may have pending in the channel. If zero, lnd's
+	// default is used.
+	RemoteMaxValueInFlight lnwire.MilliSatoshi
+
+	// MaxLocalCsv is the maximum delay that we accept for our own funds
+	// after a force close. If zero, lnd's default is used.
+	//
+	// NOTE: This requires lnd v0.12.0 or later.
+	MaxLocalCsv uint32
+
+	// CommitmentType is the commitment type of the channel. If nil, lnd
+	// picks the best type supported by both peers.
+	//
+	// NOTE: This requires lnd v0.14.0 or later.
+	CommitmentType *lnrpc.CommitmentType
+
+	// PsbtShim is an optional shim that makes lnd wait for the channel to
+	// be funded with a PSBT, instead of funding it from its own wallet.
+	// The funding flow can be driven with OpenChannelPsbt.
+	PsbtShim *PsbtShim
+}
+
+var (
+	// maxLocalCsvVersion is the first lnd version that lets us limit the
+	// delay of our own funds when opening a channel.
+	maxLocalCsvVersion = &verrpc.Version{AppMinor: 12}
+
+	// commitmentTypeVersion is the first lnd version that lets us choose
+	// the commitment type when opening a channel.
+	commitmentTypeVersion = &verrpc.Version{AppMinor: 14}
+)
+
+// marshallOpenChannelRequest converts an open channel request into its rpc
+// counterpart. Options that the given lnd version doesn't support result in
+// an UnsupportedRPCError.
+func marshallOpenChannelRequest(req *OpenChannelRequest,
+	version *verrpc.Version) (*lnrpc.OpenChannelRequest, error) {
+
+	if req.FeeRate != 0 && req.TargetConf != 0 {
+		return nil, errors.New("only one of fee rate and target conf " +
+			"may be set")
+	}
+
+	if req.SpendUnconfirmed && req.MinConfs != 0 {
+		return nil, errors.New("min confs can't be set when spending " +
+			"unconfirmed outputs")
+	}
+
+	rpcReq := &lnrpc.OpenChannelRequest{
+		NodePubkey:                 req.Peer[:],
+		LocalFundingAmount:         int64(req.LocalAmt),
+		PushSat:                    int64(req.PushAmt),
+		Private:                    req.Private,
+		TargetConf:                 req.TargetConf,
+		MinConfs:                   req.MinConfs,
+		SpendUnconfirmed:           req.SpendUnconfirmed,
+		RemoteCsvDelay:             req.RemoteCsvDelay,
+		MinHtlcMsat:                int64(req.MinHtlc),
+		RemoteMaxValueInFlightMsat: uint64(req.RemoteMaxValueInFlight),
+	}
+
+	// lnd takes whole sat/vbyte, so we round up to never fall below the
+	// requested rate. Truncating rates below 1 sat/vbyte would result in
+	// zero, which makes lnd use its default.
+	if req.FeeRate != 0 {
+		rpcReq.SatPerByte = int64(
+			(req.FeeRate.FeePerKVByte() + 999) / 1000,
+		)
+	}
+
+	if req.CloseAddress != nil {
+		rpcReq.CloseAddress = req.CloseAddress.String()
+	}
+
+	if req.MaxLocalCsv != 0 {
+		err := checkVersion(
+			version, "max local csv", maxLocalCsvVersion,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		rpcReq.MaxLocalCsv = req.MaxLocalCsv
+	}
+
+	if req.CommitmentType != nil {
+		err := checkVersion(
+			version, "commitment type", commitmentTypeVersion,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		rpcReq.CommitmentType = *req.CommitmentType
+	}
+
+	if req.PsbtShim != nil {
+		var err error
+		rpcReq.FundingShim, err = req.PsbtShim.marshall()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return rpcReq, nil
+}
+
+// OpenChannel opens a channel with the options provided and returns the
+// funding outpoint once the funding transaction was published.
+func (s *lightningClient) OpenChannel(ctx context.Context,
+	req *OpenChannelRequest) (*wire.OutPoint, error) {
+
+	rpcReq, err := marshallOpenChannelRequest(req, s.version)
+	if err != nil {
+		return nil, err
+	}
 
 	rpcCtx, cancel := context.WithTimeout(ctx, rpcTimeout)
 	defer cancel()
 
 	rpcCtx = s.adminMac.WithMacaroonAuth(rpcCtx)
 
-	chanPoint, err := s.client.OpenChannelSync(
-		rpcCtx, &lnrpc.OpenChannelRequest{
-			NodePubkey:         peer[:],
-			LocalFundingAmount: int64(localSat),
-			PushSat:            int64(pushSat),
-			Private:            private,
-		},
-	)
+	chanPoint, err := s.client.OpenChannelSync(rpcCtx, rpcReq)
 	if err != nil {
 		return nil, err
 	}
 
-	var hash *chainhash.Hash
+	return unmarshallChannelPoint(chanPoint)
+}
+
+// unmarshallChannelPoint converts an rpc channel point into an outpoint.
+func unmarshallChannelPoint(chanPoint *lnrpc.ChannelPoint) (*wire.OutPoint,
+	error) {
+
+	var (
+		hash *chainhash.Hash
+		err  error
+	)
 	switch h := chanPoint.FundingTxid.(type) {
 	case *lnrpc.ChannelPoint_FundingTxidBytes:
 		hash, err = chainhash.NewHash(h.FundingTxidBytes)
@@ -2251,35 +2827,210 @@ func (s *lightningClient) OpenChannel(ctx context.Context, peer route.Vertex,
 	}, nil
 }
 
-// CloseChannelUpdate is an interface implemented by channel close updates.
-type CloseChannelUpdate interface {
-	// CloseTxid returns the closing txid of the channel.
-	CloseTxid() chainhash.Hash
+// OpenStatusUpdate is an interface implemented by channel open updates.
+type OpenStatusUpdate interface {
+	// PendingChannelID returns the pending channel ID that lnd assigned
+	// to the channel open.
+	PendingChannelID() [32]byte
 }
 
-// PendingCloseUpdate indicates that our closing transaction has been broadcast.
-type PendingCloseUpdate struct {
-	// CloseTx is the closing transaction id.
-	CloseTx chainhash.Hash
-}
+// PendingOpenUpdate indicates that our funding transaction has been
+// broadcast.
+type PendingOpenUpdate struct {
+	// PendingChanID is the pending channel ID of the channel open.
+	PendingChanID [32]byte
 
-// CloseTxid returns the closing txid of the channel.
-func (p *PendingCloseUpdate) CloseTxid() chainhash.Hash {
-	return p.CloseTx
+	// ChannelPoint is the funding outpoint of the channel.
+	ChannelPoint wire.OutPoint
 }
 
-// ChannelClosedUpdate indicates that our channel close has confirmed on chain.
-type ChannelClosedUpdate struct {
-	// CloseTx is the closing transaction id.
-	CloseTx chainhash.Hash
+// PendingChannelID returns the pending channel ID of the channel open.
+func (p *PendingOpenUpdate) PendingChannelID() [32]byte {
+	return p.PendingChanID
 }
 
-// CloseTxid returns the closing txid of the channel.
-func (p *ChannelClosedUpdate) CloseTxid() chainhash.Hash {
-	return p.CloseTx
-}
+// ChannelOpenedUpdate indicates that the funding transaction has confirmed and
+// the channel is ready to be used.
+type ChannelOpenedUpdate struct {
+	// PendingChanID is the pending channel ID of the channel open.
+	PendingChanID [32]byte
 
-// CloseChannel closes the channel provided, returning a channel that will send
+	// ChannelPoint is the funding outpoint of the channel.
+	ChannelPoint wire.OutPoint
+}
+
+// PendingChannelID returns the pending channel ID of the channel open.
+func (c *ChannelOpenedUpdate) PendingChannelID() [32]byte {
+	return c.PendingChanID
+}
+
+// OpenChannelStream opens a channel with the options provided, returning a
+// channel that will send a stream of open updates, and an error channel which
+// will receive errors if the channel open stream fails. This function starts a
+// goroutine to consume updates from lnd, which can be cancelled by cancelling
+// the context it was called with. If lnd finishes sending updates for the
+// open, we close the updates and error channel to signal that there are no
+// more updates to be sent.
+func (s *lightningClient) OpenChannelStream(ctx context.Context,
+	req *OpenChannelRequest) (chan OpenStatusUpdate, chan error, error) {
+
+	rpcReq, err := marshallOpenChannelRequest(req, s.version)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rpcCtx := s.adminMac.WithMacaroonAuth(ctx)
+	stream, err := s.client.OpenChannel(rpcCtx, rpcReq)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	updateChan := make(chan OpenStatusUpdate)
+	errChan := make(chan error)
+
+	// sendErr is a helper which sends an error or exits because our caller
+	// context was cancelled.
+	sendErr := func(err error) {
+		select {
+		case errChan <- err:
+		case <-ctx.Done():
+		}
+	}
+
+	// sendUpdate is a helper which sends an update or exits because our
+	// caller context was cancelled.
+	sendUpdate := func(update OpenStatusUpdate) {
+		select {
+		case updateChan <- update:
+		case <-ctx.Done():
+		}
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		for {
+			resp, err := stream.Recv()
+			if err == io.EOF {
+				close(updateChan)
+				close(errChan)
+				return
+			} else if err != nil {
+				sendErr(err)
+				return
+			}
+
+			update, err := unmarshallOpenUpdate(resp, s.params)
+			if err != nil {
+				sendErr(err)
+				return
+			}
+
+			// We do not need to exit here once the channel is
+			// open, because lnd ends the stream after sending the
+			// final update, which is handled above.
+			sendUpdate(update)
+		}
+	}()
+
+	return updateChan, errChan, nil
+}
+
+// unmarshallOpenUpdate converts an rpc open status update into its typed
+// counterpart.
+func unmarshallOpenUpdate(resp *lnrpc.OpenStatusUpdate,
+	params *chaincfg.Params) (OpenStatusUpdate, error) {
+
+	var pendingChanID [32]byte
+	copy(pendingChanID[:], resp.PendingChanId)
+
+	switch update := resp.Update.(type) {
+	case *lnrpc.OpenStatusUpdate_ChanPending:
+		txid, err := chainhash.NewHash(update.ChanPending.Txid)
+		if err != nil {
+			return nil, err
+		}
+
+		return &PendingOpenUpdate{
+			PendingChanID: pendingChanID,
+			ChannelPoint: wire.OutPoint{
+				Hash:  *txid,
+				Index: update.ChanPending.OutputIndex,
+			},
+		}, nil
+
+	case *lnrpc.OpenStatusUpdate_ChanOpen:
+		chanPoint, err := unmarshallChannelPoint(
+			update.ChanOpen.ChannelPoint,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ChannelOpenedUpdate{
+			PendingChanID: pendingChanID,
+			ChannelPoint:  *chanPoint,
+		}, nil
+
+	case *lnrpc.OpenStatusUpdate_PsbtFund:
+		addr, err := btcutil.DecodeAddress(
+			update.PsbtFund.FundingAddress, params,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("invalid funding address: %v",
+				err)
+		}
+
+		packet, err := psbt.NewFromRawBytes(
+			bytes.NewReader(update.PsbtFund.Psbt), false,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("invalid psbt template: %v", err)
+		}
+
+		return &PsbtFundingUpdate{
+			PendingChanID:  pendingChanID,
+			FundingAddress: addr,
+			FundingAmount:  btcutil.Amount(update.PsbtFund.FundingAmount),
+			Psbt:           packet,
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown channel open update: %T",
+			resp.Update)
+	}
+}
+
+// CloseChannelUpdate is an interface implemented by channel close updates.
+type CloseChannelUpdate interface {
+	// CloseTxid returns the closing txid of the channel.
+	CloseTxid() chainhash.Hash
+}
+
+// PendingCloseUpdate indicates that our closing transaction has been broadcast.
+type PendingCloseUpdate struct {
+	// CloseTx is the closing transaction id.
+	CloseTx chainhash.Hash
+}
+
+// CloseTxid returns the closing txid of the channel.
+func (p *PendingCloseUpdate) CloseTxid() chainhash.Hash {
+	return p.CloseTx
+}
+
+// ChannelClosedUpdate indicates that our channel close has confirmed on chain.
+type ChannelClosedUpdate struct {
+	// CloseTx is the closing transaction id.
+	CloseTx chainhash.Hash
+}
+
+// CloseTxid returns the closing txid of the channel.
+func (p *ChannelClosedUpdate) CloseTxid() chainhash.Hash {
+	return p.CloseTx
+}
+
+// CloseChannel closes the channel provided, returning a channel that will send
 // a stream of close updates, and an error channel which will receive errors if
 // the channel close stream fails. This function starts a goroutine to consume
 // updates from lnd, which can be cancelled by cancelling the context it was
@@ -2427,18 +3178,141 @@ type PolicyUpdateRequest struct {
 
 	// MinHtlcMsatSpecified if true, MinHtlcMsat is applied.
 	MinHtlcMsatSpecified bool
+
+	// InboundBaseFeeMsat is the base fee charged for HTLCs that enter
+	// through the channel. It may be negative to offer a discount.
+	//
+	// NOTE: Inbound fees require lnd v0.18.0 or later and a gRPC
+	// connection.
+	InboundBaseFeeMsat int32
+
+	// InboundFeeRatePpm is the proportional fee in parts per million
+	// charged for HTLCs that enter through the channel. It may be
+	// negative to offer a discount.
+	//
+	// NOTE: Inbound fees require lnd v0.18.0 or later and a gRPC
+	// connection.
+	InboundFeeRatePpm int32
+}
+
+var (
+	// failedUpdatesVersion is the first lnd version that reports the
+	// channels whose policy update failed.
+	failedUpdatesVersion = &verrpc.Version{AppMinor: 14}
+
+	// inboundFeeVersion is the first lnd version that supports inbound
+	// fees.
+	inboundFeeVersion = &verrpc.Version{AppMinor: 18}
+)
+
+const (
+	// policyInboundFeeField is the field number of the inbound fee in
+	// lnd's PolicyUpdateRequest. The field was added in lnd v0.18, so the
+	// protos lndclient is built against don't know it.
+	policyInboundFeeField = 10
+
+	// Field numbers of InboundFee, the message of the inbound fee field.
+	inboundFeeBaseField = 1
+	inboundFeeRateField = 2
+
+	// Field numbers of the inbound fees in lnd's ChannelFeeReport, which
+	// were also added in lnd v0.18.
+	reportInboundBaseField = 6
+	reportInboundRateField = 7
+)
+
+// setInboundFee adds the inbound fee to the policy update request as a raw
+// field, because the protos lndclient is built against don't know it.
+func setInboundFee(rpcReq *lnrpc.PolicyUpdateRequest, baseFeeMsat,
+	feeRatePpm int32) {
+
+	// Negative int32 values are encoded as sign extended varints.
+	var fee []byte
+	fee = protowire.AppendTag(
+		fee, inboundFeeBaseField, protowire.VarintType,
+	)
+	fee = protowire.AppendVarint(fee, uint64(int64(baseFeeMsat)))
+	fee = protowire.AppendTag(
+		fee, inboundFeeRateField, protowire.VarintType,
+	)
+	fee = protowire.AppendVarint(fee, uint64(int64(feeRatePpm)))
+
+	var field []byte
+	field = protowire.AppendTag(
+		field, policyInboundFeeField, protowire.BytesType,
+	)
+	field = protowire.AppendBytes(field, fee)
+
+	rpcReq.ProtoReflect().SetUnknown(field)
+}
+
+// getInboundFee reads the inbound fee that lnd v0.18 and later add to the fee
+// report of a channel. It is zero for older versions.
+func getInboundFee(report *lnrpc.ChannelFeeReport) (int32, int32, error) {
+	var baseFeeMsat, feeRatePpm int32
+
+	raw := report.ProtoReflect().GetUnknown()
+	for len(raw) > 0 {
+		num, typ, n := protowire.ConsumeTag(raw)
+		if n < 0 {
+			return 0, 0, protowire.ParseError(n)
+		}
+		raw = raw[n:]
+
+		isInbound := num == reportInboundBaseField ||
+			num == reportInboundRateField
+		if !isInbound || typ != protowire.VarintType {
+			n = protowire.ConsumeFieldValue(num, typ, raw)
+			if n < 0 {
+				return 0, 0, protowire.ParseError(n)
+			}
+			raw = raw[n:]
+
+			continue
+		}
+
+		value, n := protowire.ConsumeVarint(raw)
+		if n < 0 {
+			return 0, 0, protowire.ParseError(n)
+		}
+		raw = raw[n:]
+
+		if num == reportInboundBaseField {
+			baseFeeMsat = int32(value)
+		} else {
+			feeRatePpm = int32(value)
+		}
+	}
+
+	return baseFeeMsat, feeRatePpm, nil
+}
+
+// policyUpdateError returns an error that describes the failed policy updates
+// reported by lnd, or nil if there are none.
+func policyUpdateError(failed []*lnrpc.FailedUpdate) error {
+	if len(failed) == 0 {
+		return nil
+	}
+
+	reasons := make([]string, 0, len(failed))
+	for _, update := range failed {
+		reasons = append(reasons, fmt.Sprintf("%v:%v: %v (%v)",
+			update.Outpoint.GetTxidStr(),
+			update.Outpoint.GetOutputIndex(), update.Reason,
+			update.UpdateError))
+	}
+
+	return fmt.Errorf("policy update failed: %v",
+		strings.Join(reasons, ", "))
 }
 
 // UpdateChanPolicy updates the channel policy for the passed chanPoint. If
 // the chanPoint is nil, then the policy is applied for all existing channels.
+// An error is returned if lnd reports channels whose update failed, which it
+// does from v0.14.0.
 func (s *lightningClient) UpdateChanPolicy(ctx context.Context,
 	req PolicyUpdateRequest, chanPoint *wire.OutPoint) error {
 
-	rpcCtx, cancel := context.WithTimeout(ctx, rpcTimeout)
-	defer cancel()
-
-	rpcCtx = s.adminMac.WithMacaroonAuth(rpcCtx)
-
 	rpcReq := &lnrpc.PolicyUpdateRequest{
 		BaseFeeMsat:   req.BaseFeeMsat,
 		FeeRate:       req.FeeRate,
@@ -2451,6 +3325,26 @@ func (s *lightningClient) UpdateChanPolicy(ctx context.Context,
 		rpcReq.MinHtlcMsat = req.MinHtlcMsat
 	}
 
+	if req.InboundBaseFeeMsat != 0 || req.InboundFeeRatePpm != 0 {
+		// lnd versions before v0.18 silently ignore the inbound fee,
+		// so we only set it if we know that lnd supports it.
+		supported := s.version != nil && assertVersionCompatible(
+			s.version, inboundFeeVersion,
+		) == nil
+		if !supported {
+			return &UnsupportedRPCError{
+				RPC: "inbound fees",
+				MinVersion: VersionStringShort(
+					inboundFeeVersion,
+				),
+			}
+		}
+
+		setInboundFee(
+			rpcReq, req.InboundBaseFeeMsat, req.InboundFeeRatePpm,
+		)
+	}
+
 	if chanPoint != nil {
 		rpcChanPoint := &lnrpc.ChannelPoint{
 			FundingTxid: &lnrpc.ChannelPoint_FundingTxidBytes{
@@ -2467,8 +3361,221 @@ func (s *lightningClient) UpdateChanPolicy(ctx context.Context,
 		}
 	}
 
-	_, err := s.client.UpdateChannelPolicy(rpcCtx, rpcReq)
-	return err
+	rpcCtx, cancel := context.WithTimeout(ctx, rpcTimeout)
+	defer cancel()
+
+	rpcCtx = s.adminMac.WithMacaroonAuth(rpcCtx)
+	resp, err := s.client.UpdateChannelPolicy(rpcCtx, rpcReq)
+	if err != nil {
+		return err
+	}
+
+	return policyUpdateError(resp.FailedUpdates)
+}
+
+// ChannelPolicyUpdate is the policy update of a single channel.
+type ChannelPolicyUpdate struct {
+	// ChannelPoint is the outpoint of the channel.
+	ChannelPoint wire.OutPoint
+
+	// Policy is the policy that is applied to the channel.
+	Policy PolicyUpdateRequest
+}
+
+// ChannelPolicyResult is the result of the policy update of a single channel.
+type ChannelPolicyResult struct {
+	// ChannelPoint is the outpoint of the channel.
+	ChannelPoint wire.OutPoint
+
+	// Err is the reason the update failed. It is nil if the policy was
+	// applied.
+	Err error
+}
+
+// UpdateChanPolicies applies a separate policy to each of the channels
+// provided. lnd applies a policy either to a single channel or to all
+// channels, so the channels are updated one by one. The results are returned
+// in the order of the updates. Once the context is cancelled, the remaining
+// updates fail with the context error.
+//
+// lnd versions before v0.14.0 don't report failed updates, for example of
+// unknown channels. For those versions, and if the version is unknown, the
+// fees of all updated channels are read back to make sure they were applied.
+func (s *lightningClient) UpdateChanPolicies(ctx context.Context,
+	updates []ChannelPolicyUpdate) []ChannelPolicyResult {
+
+	results := make([]ChannelPolicyResult, len(updates))
+	for i, update := range updates {
+		update := update
+
+		results[i].ChannelPoint = update.ChannelPoint
+
+		if ctx.Err() != nil {
+			results[i].Err = ctx.Err()
+			continue
+		}
+
+		results[i].Err = s.UpdateChanPolicy(
+			ctx, update.Policy, &update.ChannelPoint,
+		)
+	}
+
+	reportsFailures := s.version != nil &&
+		assertVersionCompatible(s.version, failedUpdatesVersion) == nil
+	if !reportsFailures {
+		s.verifyChanPolicies(ctx, updates, results)
+	}
+
+	for _, result := range results {
+		if result.Err != nil {
+			log.Warnf("Policy update of channel %v failed: %v",
+				result.ChannelPoint, result.Err)
+		}
+	}
+
+	return results
+}
+
+// verifyChanPolicies reads back the fees of all channels whose policy update
+// succeeded and fails the updates whose fees weren't applied.
+func (s *lightningClient) verifyChanPolicies(ctx context.Context,
+	updates []ChannelPolicyUpdate, results []ChannelPolicyResult) {
+
+	report, err := s.FeeReport(ctx)
+	if err != nil {
+		for i := range results {
+			if results[i].Err == nil {
+				results[i].Err = fmt.Errorf("unable to verify "+
+					"policy: %v", err)
+			}
+		}
+
+		return
+	}
+
+	fees := make(map[wire.OutPoint]ChannelFeeReport, len(report.Channels))
+	for _, channel := range report.Channels {
+		fees[*channel.ChannelPoint] = channel
+	}
+
+	for i, update := range updates {
+		if results[i].Err != nil {
+			continue
+		}
+
+		fee, ok := fees[update.ChannelPoint]
+		if !ok {
+			results[i].Err = errors.New("channel not found")
+			continue
+		}
+
+		// lnd truncates the fee rate before v0.14 and rounds it
+		// afterwards, so we accept both.
+		feeRate := update.Policy.FeeRate * 1e6
+		if fee.BaseFeeMsat != update.Policy.BaseFeeMsat ||
+			(fee.FeePerMil != int64(uint32(feeRate)) &&
+				fee.FeePerMil != int64(math.Round(feeRate))) {
+
+			results[i].Err = fmt.Errorf("policy not applied, "+
+				"channel charges %v msat + %v ppm",
+				fee.BaseFeeMsat, fee.FeePerMil)
+		}
+	}
+}
+
+// ChannelFeeReport holds the fee policy of a channel.
+type ChannelFeeReport struct {
+	// ChannelID is the short channel ID of the channel.
+	ChannelID uint64
+
+	// ChannelPoint is the outpoint of the channel.
+	ChannelPoint *wire.OutPoint
+
+	// BaseFeeMsat is the base fee charged regardless of the number of
+	// milli-satoshis sent.
+	BaseFeeMsat int64
+
+	// FeePerMil is the proportional fee charged in millionths of the
+	// amount sent.
+	FeePerMil int64
+
+	// FeeRate is the effective fee rate in milli-satoshis, which is
+	// FeePerMil divided by one million.
+	FeeRate float64
+
+	// InboundBaseFeeMsat is the base fee charged for HTLCs that enter
+	// through the channel.
+	//
+	// NOTE: lnd reports inbound fees from v0.18.0 and only over gRPC,
+	// otherwise this is zero.
+	InboundBaseFeeMsat int32
+
+	// InboundFeeRatePpm is the proportional fee in parts per million
+	// charged for HTLCs that enter through the channel.
+	//
+	// NOTE: lnd reports inbound fees from v0.18.0 and only over gRPC,
+	// otherwise this is zero.
+	InboundFeeRatePpm int32
+}
+
+// FeeReport holds the fee policies of all channels and the fees earned by
+// forwarding.
+type FeeReport struct {
+	// Channels holds the fee policy of every channel.
+	Channels []ChannelFeeReport
+
+	// DayFees is the fee revenue of the past 24 hours.
+	DayFees btcutil.Amount
+
+	// WeekFees is the fee revenue of the past week.
+	WeekFees btcutil.Amount
+
+	// MonthFees is the fee revenue of the past month.
+	MonthFees btcutil.Amount
+}
+
+// FeeReport returns the current fee policies of all channels and the fees
+// earned by forwarding.
+func (s *lightningClient) FeeReport(ctx context.Context) (*FeeReport, error) {
+	rpcCtx, cancel := context.WithTimeout(ctx, rpcTimeout)
+	defer cancel()
+
+	rpcCtx = s.adminMac.WithMacaroonAuth(rpcCtx)
+	resp, err := s.client.FeeReport(rpcCtx, &lnrpc.FeeReportRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &FeeReport{
+		Channels:  make([]ChannelFeeReport, len(resp.ChannelFees)),
+		DayFees:   btcutil.Amount(resp.DayFeeSum),
+		WeekFees:  btcutil.Amount(resp.WeekFeeSum),
+		MonthFees: btcutil.Amount(resp.MonthFeeSum),
+	}
+
+	for i, channel := range resp.ChannelFees {
+		point, err := NewOutpointFromStr(channel.ChannelPoint)
+		if err != nil {
+			return nil, err
+		}
+
+		inboundBase, inboundRate, err := getInboundFee(channel)
+		if err != nil {
+			return nil, err
+		}
+
+		report.Channels[i] = ChannelFeeReport{
+			ChannelID:          channel.ChanId,
+			ChannelPoint:       point,
+			BaseFeeMsat:        channel.BaseFeeMsat,
+			FeePerMil:          channel.FeePerMil,
+			FeeRate:            channel.FeeRate,
+			InboundBaseFeeMsat: inboundBase,
+			InboundFeeRatePpm:  inboundRate,
+		}
+	}
+
+	return report, nil
 }
 
 // RoutingPolicy holds the edge routing policy for a channel edge.
@@ -2592,7 +3699,9 @@ func (s *lightningClient) ListPeers(ctx context.Context) ([]Peer,
 
 	rpcCtx = s.adminMac.WithMacaroonAuth(rpcCtx)
 
-	resp, err := s.client.ListPeers(rpcCtx, &lnrpc.ListPeersRequest{})
+	resp, err := s.client.ListPeers(rpcCtx, &lnrpc.ListPeersRequest{
+		LatestError: true,
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -2615,6 +3724,27 @@ func (s *lightningClient) ListPeers(ctx context.Context) ([]Peer,
 			PingTime:      pingTime,
 			Sent:          btcutil.Amount(peer.SatSent),
 			Received:      btcutil.Amount(peer.SatRecv),
+			Features: make(
+				[]lnwire.FeatureBit, 0, len(peer.Features),
+			),
+		}
+
+		for featureBit := range peer.Features {
+			peers[i].Features = append(
+				peers[i].Features, lnwire.FeatureBit(featureBit),
+			)
+		}
+		sort.Slice(peers[i].Features, func(a, b int) bool {
+			return peers[i].Features[a] < peers[i].Features[b]
+		})
+
+		// We only requested the latest error.
+		if len(peer.Errors) > 0 {
+			lastErr := peer.Errors[len(peer.Errors)-1]
+			peers[i].LastError = lastErr.Error
+			peers[i].LastErrorTime = time.Unix(
+				int64(lastErr.Timestamp), 0,
+			)
 		}
 	}
 
@@ -2641,6 +3771,78 @@ func (s *lightningClient) Connect(ctx context.Context, peer route.Vertex,
 	return err
 }
 
+// DisconnectPeer disconnects from the peer provided. lnd refuses to disconnect
+// from peers that we have open channels with.
+func (s *lightningClient) DisconnectPeer(ctx context.Context,
+	peer route.Vertex) error {
+
+	rpcCtx, cancel := context.WithTimeout(ctx, rpcTimeout)
+	defer cancel()
+
+	rpcCtx = s.adminMac.WithMacaroonAuth(rpcCtx)
+
+	_, err := s.client.DisconnectPeer(rpcCtx, &lnrpc.DisconnectPeerRequest{
+		PubKey: peer.String(),
+	})
+
+	return err
+}
+
+// SubscribePeerEvents subscribes to peers coming online and going offline.
+func (s *lightningClient) SubscribePeerEvents(ctx context.Context) (
+	<-chan *PeerEvent, <-chan error, error) {
+
+	rpcCtx := s.adminMac.WithMacaroonAuth(ctx)
+	eventStream, err := s.client.SubscribePeerEvents(
+		rpcCtx, &lnrpc.PeerEventSubscription{},
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	peerEvents := make(chan *PeerEvent)
+	streamErr := make(chan error, 1)
+
+	// Peer events goroutine.
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer close(streamErr)
+		defer close(peerEvents)
+
+		for {
+			rpcEvent, err := eventStream.Recv()
+			if err != nil {
+				streamErr <- err
+				return
+			}
+
+			pubkey, err := route.NewVertexFromStr(rpcEvent.PubKey)
+			if err != nil {
+				streamErr <- err
+				return
+			}
+
+			event := &PeerEvent{
+				Pubkey:    pubkey,
+				Type:      PeerOnline,
+				Timestamp: time.Now(),
+			}
+			if rpcEvent.Type == lnrpc.PeerEvent_PEER_OFFLINE {
+				event.Type = PeerOffline
+			}
+
+			select {
+			case peerEvents <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return peerEvents, streamErr, nil
+}
+
 // SendCoins sends the passed amount of (or all) coins to the passed address.
 // Either amount or sendAll must be specified, while confTarget, satsPerByte are
 // optional and may be set to zero in which case automatic conf target and fee
@@ -3012,3 +4214,50 @@ func (s *lightningClient) SubscribeInvoices(ctx context.Context,
 
 	return invoiceUpdates, streamErr, nil
 }
+
+// SubscribeTransactions subscribes to on chain transactions that are relevant
+// to our wallet. Transactions are sent once when they are seen unconfirmed and
+// again when they confirm.
+func (s *lightningClient) SubscribeTransactions(ctx context.Context) (
+	<-chan *Transaction, <-chan error, error) {
+
+	rpcCtx := s.adminMac.WithMacaroonAuth(ctx)
+	txStream, err := s.client.SubscribeTransactions(
+		rpcCtx, &lnrpc.GetTransactionsRequest{},
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	txUpdates := make(chan *Transaction)
+	streamErr := make(chan error, 1)
+
+	// New transactions goroutine.
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer close(streamErr)
+		defer close(txUpdates)
+
+		for {
+			rpcTx, err := txStream.Recv()
+			if err != nil {
+				streamErr <- err
+				return
+			}
+			tx, err := unmarshallTransaction(rpcTx)
+			if err != nil {
+				streamErr <- err
+				return
+			}
+
+			select {
+			case txUpdates <- tx:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return txUpdates, streamErr, nil
+}
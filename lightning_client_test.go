@@ -0,0 +1,866 @@
+package lndclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/verrpc"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/zpay32"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// mockPaymentStream is a payment update stream that returns the given
+// updates, followed by the error.
+type mockPaymentStream struct {
+	grpc.ClientStream
+
+	updates []*lnrpc.Payment
+	err     error
+}
+
+func (m *mockPaymentStream) Recv() (*lnrpc.Payment, error) {
+	if len(m.updates) == 0 {
+		if m.err != nil {
+			return nil, m.err
+		}
+		return nil, io.EOF
+	}
+
+	update := m.updates[0]
+	m.updates = m.updates[1:]
+	return update, nil
+}
+
+// mockPaymentRouter is a router that returns predefined payment streams for
+// SendPaymentV2 and TrackPaymentV2.
+type mockPaymentRouter struct {
+	routerrpc.RouterClient
+
+	sendReq     *routerrpc.SendPaymentRequest
+	sendStream  *mockPaymentStream
+	trackStream *mockPaymentStream
+}
+
+func (m *mockPaymentRouter) SendPaymentV2(_ context.Context,
+	req *routerrpc.SendPaymentRequest, _ ...grpc.CallOption) (
+	routerrpc.Router_SendPaymentV2Client, error) {
+
+	m.sendReq = req
+	return m.sendStream, nil
+}
+
+func (m *mockPaymentRouter) TrackPaymentV2(_ context.Context,
+	_ *routerrpc.TrackPaymentRequest, _ ...grpc.CallOption) (
+	routerrpc.Router_TrackPaymentV2Client, error) {
+
+	return m.trackStream, nil
+}
+
+// newTestInvoice creates a signed invoice with the given amount. If the amount
+// is nil, the invoice doesn't specify an amount.
+func newTestInvoice(t *testing.T, preimage lntypes.Preimage,
+	amt *lnwire.MilliSatoshi, extraOptions ...func(*zpay32.Invoice)) string {
+
+	privKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatalf("unable to create key: %v", err)
+	}
+
+	options := append(
+		[]func(*zpay32.Invoice){zpay32.Description("test")},
+		extraOptions...,
+	)
+	if amt != nil {
+		options = append(options, zpay32.Amount(*amt))
+	}
+
+	invoice, err := zpay32.NewInvoice(
+		&chaincfg.RegressionNetParams, preimage.Hash(), time.Now(),
+		options...,
+	)
+	if err != nil {
+		t.Fatalf("unable to create invoice: %v", err)
+	}
+
+	payReq, err := invoice.Encode(zpay32.MessageSigner{
+		SignCompact: func(hash []byte) ([]byte, error) {
+			return btcec.SignCompact(
+				btcec.S256(), privKey, chainhash.HashB(hash),
+				true,
+			)
+		},
+	})
+	if err != nil {
+		t.Fatalf("unable to encode invoice: %v", err)
+	}
+
+	return payReq
+}
+
+// TestPayInvoice tests that invoice payments are sent through the router, that
+// already initiated payments are tracked to report their real outcome and
+// that failures are reported with their typed reason.
+func TestPayInvoice(t *testing.T) {
+	preimage := lntypes.Preimage{1, 2, 3}
+	amt := lnwire.MilliSatoshi(100000)
+	invoice := newTestInvoice(t, preimage, &amt)
+	zeroAmtInvoice := newTestInvoice(t, preimage, nil)
+
+	succeeded := &lnrpc.Payment{
+		Status:          lnrpc.Payment_SUCCEEDED,
+		PaymentPreimage: preimage.String(),
+		ValueMsat:       100000,
+		FeeMsat:         2000,
+		Htlcs: []*lnrpc.HTLCAttempt{{
+			Status: lnrpc.HTLCAttempt_FAILED,
+			Route:  &lnrpc.Route{TotalFeesMsat: 5000},
+		}, {
+			Status: lnrpc.HTLCAttempt_SUCCEEDED,
+			Route:  &lnrpc.Route{TotalFeesMsat: 2000},
+		}},
+	}
+	inFlight := &lnrpc.Payment{
+		Status: lnrpc.Payment_IN_FLIGHT,
+	}
+	amount := btcutil.Amount(50)
+
+	tests := []struct {
+		name        string
+		invoice     string
+		amount      *btcutil.Amount
+		sendStream  *mockPaymentStream
+		trackStream *mockPaymentStream
+
+		expectErr    bool
+		expectReason lnrpc.PaymentFailureReason
+		expectFee    btcutil.Amount
+		expectRoutes int
+	}{{
+		name:    "success",
+		invoice: invoice,
+		sendStream: &mockPaymentStream{
+			updates: []*lnrpc.Payment{inFlight, succeeded},
+		},
+		expectFee:    2,
+		expectRoutes: 1,
+	}, {
+		name:    "already paid",
+		invoice: invoice,
+		sendStream: &mockPaymentStream{
+			err: status.Error(codes.AlreadyExists, "paid"),
+		},
+		trackStream: &mockPaymentStream{
+			updates: []*lnrpc.Payment{succeeded},
+		},
+		expectFee:    2,
+		expectRoutes: 1,
+	}, {
+		name:    "failed",
+		invoice: invoice,
+		sendStream: &mockPaymentStream{
+			updates: []*lnrpc.Payment{inFlight, {
+				Status:        lnrpc.Payment_FAILED,
+				FailureReason: lnrpc.PaymentFailureReason_FAILURE_REASON_NO_ROUTE,
+			}},
+		},
+		expectErr:    true,
+		expectReason: lnrpc.PaymentFailureReason_FAILURE_REASON_NO_ROUTE,
+	}, {
+		name:      "zero amount invoice without amount",
+		invoice:   zeroAmtInvoice,
+		expectErr: true,
+	}, {
+		name:    "zero amount invoice with amount",
+		invoice: zeroAmtInvoice,
+		amount:  &amount,
+		sendStream: &mockPaymentStream{
+			updates: []*lnrpc.Payment{succeeded},
+		},
+		expectFee:    2,
+		expectRoutes: 1,
+	}, {
+		name:      "amount for invoice with amount",
+		invoice:   invoice,
+		amount:    &amount,
+		expectErr: true,
+	}}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			router := &mockPaymentRouter{
+				sendStream:  test.sendStream,
+				trackStream: test.trackStream,
+			}
+			client := newLightningClient(
+				nil, router, &chaincfg.RegressionNetParams, "",
+			)
+
+			result := <-client.PayInvoice(
+				context.Background(), test.invoice, 10, nil,
+				test.amount,
+			)
+
+			if test.expectErr {
+				if result.Err == nil {
+					t.Fatalf("expected error")
+				}
+				if result.FailureReason != test.expectReason {
+					t.Fatalf("expected reason %v, got %v",
+						test.expectReason,
+						result.FailureReason)
+				}
+				return
+			}
+
+			if result.Err != nil {
+				t.Fatalf("unexpected error: %v", result.Err)
+			}
+			if result.Preimage != preimage {
+				t.Fatalf("unexpected preimage")
+			}
+			if result.PaidFee != test.expectFee {
+				t.Fatalf("expected fee %v, got %v",
+					test.expectFee, result.PaidFee)
+			}
+			if len(result.Routes) != test.expectRoutes {
+				t.Fatalf("expected %d routes, got %d",
+					test.expectRoutes, len(result.Routes))
+			}
+			if test.amount != nil &&
+				router.sendReq.Amt != int64(*test.amount) {
+
+				t.Fatalf("amount not passed to router")
+			}
+		})
+	}
+}
+
+// mockInvoiceLightning is a lightning client that records added invoices and
+// invoice queries and returns the given payment request.
+type mockInvoiceLightning struct {
+	lnrpc.LightningClient
+
+	invoice *lnrpc.Invoice
+	payReq  string
+	listReq *lnrpc.ListInvoiceRequest
+}
+
+func (m *mockInvoiceLightning) AddInvoice(_ context.Context,
+	in *lnrpc.Invoice, _ ...grpc.CallOption) (*lnrpc.AddInvoiceResponse,
+	error) {
+
+	m.invoice = in
+	preimage := lntypes.Preimage{1}
+	hash := preimage.Hash()
+
+	return &lnrpc.AddInvoiceResponse{
+		RHash:          hash[:],
+		PaymentRequest: m.payReq,
+		AddIndex:       5,
+	}, nil
+}
+
+// TestAddInvoice tests that all invoice options are passed to lnd and that the
+// result contains the payment address of the invoice.
+func TestAddInvoice(t *testing.T) {
+	preimage := lntypes.Preimage{1}
+	paymentAddr := [32]byte{9, 8, 7}
+	amt := lnwire.MilliSatoshi(1500)
+
+	mock := &mockInvoiceLightning{
+		payReq: newTestInvoice(
+			t, preimage, &amt, zpay32.PaymentAddr(paymentAddr),
+		),
+	}
+	client := newLightningClient(
+		mock, nil, &chaincfg.RegressionNetParams, "",
+	)
+
+	result, err := client.AddInvoice(
+		context.Background(), &AddInvoiceRequest{
+			Preimage:     &preimage,
+			Value:        amt,
+			FallbackAddr: "bcrt1qfallback",
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if mock.invoice.ValueMsat != 1500 || mock.invoice.Value != 0 {
+		t.Fatalf("expected msat value, got %v", mock.invoice)
+	}
+	if !mock.invoice.Private {
+		t.Fatalf("expected private route hints by default")
+	}
+	if mock.invoice.FallbackAddr != "bcrt1qfallback" {
+		t.Fatalf("fallback address not passed")
+	}
+
+	if result.Hash != preimage.Hash() {
+		t.Fatalf("unexpected hash: %v", result.Hash)
+	}
+	if result.PaymentRequest != mock.payReq {
+		t.Fatalf("unexpected payment request")
+	}
+	if result.PaymentAddr == nil || *result.PaymentAddr != paymentAddr {
+		t.Fatalf("unexpected payment address: %v", result.PaymentAddr)
+	}
+	if result.AddIndex != 5 {
+		t.Fatalf("expected add index 5, got %v", result.AddIndex)
+	}
+}
+
+// TestAddInvoiceRouteHints tests that route hints are only passed to lnd
+// versions that add them to the invoice.
+func TestAddInvoiceRouteHints(t *testing.T) {
+	privKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatalf("unable to create key: %v", err)
+	}
+	hints := [][]zpay32.HopHint{{{
+		NodeID:    privKey.PubKey(),
+		ChannelID: 123,
+	}}}
+
+	amt := lnwire.MilliSatoshi(1500)
+	mock := &mockInvoiceLightning{
+		payReq: newTestInvoice(t, lntypes.Preimage{1}, &amt),
+	}
+	client := newLightningClient(
+		mock, nil, &chaincfg.RegressionNetParams, "",
+	)
+	client.version = &verrpc.Version{AppMinor: 11}
+
+	_, err = client.AddInvoice(
+		context.Background(), &AddInvoiceRequest{RouteHints: hints},
+	)
+	if _, ok := err.(*UnsupportedRPCError); !ok {
+		t.Fatalf("expected unsupported rpc error, got %v", err)
+	}
+	if mock.invoice != nil {
+		t.Fatalf("expected no invoice to be added")
+	}
+
+	// Without route hints, older versions can still add invoices.
+	_, err = client.AddInvoice(context.Background(), &AddInvoiceRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	client.version = &verrpc.Version{AppMinor: 12}
+	_, err = client.AddInvoice(
+		context.Background(), &AddInvoiceRequest{RouteHints: hints},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(mock.invoice.RouteHints) != 1 ||
+		mock.invoice.RouteHints[0].HopHints[0].ChanId != 123 {
+
+		t.Fatalf("route hints not passed: %v", mock.invoice.RouteHints)
+	}
+}
+
+func (m *mockInvoiceLightning) ListInvoices(_ context.Context,
+	req *lnrpc.ListInvoiceRequest, _ ...grpc.CallOption) (
+	*lnrpc.ListInvoiceResponse, error) {
+
+	m.listReq = req
+	return &lnrpc.ListInvoiceResponse{}, nil
+}
+
+// TestListInvoices tests that the invoice query is passed to lnd.
+func TestListInvoices(t *testing.T) {
+	mock := &mockInvoiceLightning{}
+	client := newLightningClient(
+		mock, nil, &chaincfg.RegressionNetParams, "",
+	)
+
+	_, err := client.ListInvoices(
+		context.Background(), ListInvoicesRequest{
+			PendingOnly: true,
+			Offset:      3,
+			MaxInvoices: 10,
+			Reversed:    true,
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req := mock.listReq
+	if !req.PendingOnly || req.IndexOffset != 3 ||
+		req.NumMaxInvoices != 10 || !req.Reversed {
+
+		t.Fatalf("unexpected request: %v", req)
+	}
+}
+
+// mockOpenStream is an open channel stream that returns the given updates
+// followed by EOF.
+type mockOpenStream struct {
+	grpc.ClientStream
+
+	updates []*lnrpc.OpenStatusUpdate
+}
+
+func (m *mockOpenStream) Recv() (*lnrpc.OpenStatusUpdate, error) {
+	if len(m.updates) == 0 {
+		return nil, io.EOF
+	}
+
+	update := m.updates[0]
+	m.updates = m.updates[1:]
+	return update, nil
+}
+
+// mockOpenLightning is a lightning client that records channel open requests
+// and returns the given open stream.
+type mockOpenLightning struct {
+	lnrpc.LightningClient
+
+	req    *lnrpc.OpenChannelRequest
+	stream *mockOpenStream
+}
+
+func (m *mockOpenLightning) OpenChannel(_ context.Context,
+	req *lnrpc.OpenChannelRequest, _ ...grpc.CallOption) (
+	lnrpc.Lightning_OpenChannelClient, error) {
+
+	m.req = req
+	return m.stream, nil
+}
+
+// TestOpenChannelStream tests that channel open options are passed to lnd and
+// that the pending and open updates are reported as typed events.
+func TestOpenChannelStream(t *testing.T) {
+	txid := chainhash.Hash{1, 2, 3}
+	pendingID := [32]byte{4, 5, 6}
+	chanPoint := &lnrpc.ChannelPoint{
+		FundingTxid: &lnrpc.ChannelPoint_FundingTxidBytes{
+			FundingTxidBytes: txid[:],
+		},
+		OutputIndex: 1,
+	}
+
+	mock := &mockOpenLightning{
+		stream: &mockOpenStream{
+			updates: []*lnrpc.OpenStatusUpdate{{
+				PendingChanId: pendingID[:],
+				Update: &lnrpc.OpenStatusUpdate_ChanPending{
+					ChanPending: &lnrpc.PendingUpdate{
+						Txid:        txid[:],
+						OutputIndex: 1,
+					},
+				},
+			}, {
+				PendingChanId: pendingID[:],
+				Update: &lnrpc.OpenStatusUpdate_ChanOpen{
+					ChanOpen: &lnrpc.ChannelOpenUpdate{
+						ChannelPoint: chanPoint,
+					},
+				},
+			}},
+		},
+	}
+	client := newLightningClient(
+		mock, nil, &chaincfg.RegressionNetParams, "",
+	)
+
+	// Setting both a fee rate and a confirmation target is not allowed.
+	_, _, err := client.OpenChannelStream(
+		context.Background(), &OpenChannelRequest{
+			LocalAmt:   100000,
+			FeeRate:    2500,
+			TargetConf: 6,
+		},
+	)
+	if err == nil {
+		t.Fatalf("expected error for fee rate and conf target")
+	}
+
+	// Fee rates are rounded up to whole sat/vbyte, so that low rates
+	// don't fall back to lnd's default.
+	rpcReq, err := marshallOpenChannelRequest(&OpenChannelRequest{
+		FeeRate: 200,
+	}, nil)
+	if err != nil || rpcReq.SatPerByte != 1 {
+		t.Fatalf("expected rate of 1 sat/vbyte, got %v: %v", rpcReq,
+			err)
+	}
+
+	updates, errChan, err := client.OpenChannelStream(
+		context.Background(), &OpenChannelRequest{
+			LocalAmt:         100000,
+			FeeRate:          2500,
+			SpendUnconfirmed: true,
+			RemoteCsvDelay:   144,
+			MinHtlc:          2000,
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if mock.req.SatPerByte != 10 || !mock.req.SpendUnconfirmed ||
+		mock.req.RemoteCsvDelay != 144 || mock.req.MinHtlcMsat != 2000 {
+
+		t.Fatalf("unexpected open request: %v", mock.req)
+	}
+
+	expectedPoint := wire.OutPoint{Hash: txid, Index: 1}
+
+	update := <-updates
+	pending, ok := update.(*PendingOpenUpdate)
+	if !ok || pending.ChannelPoint != expectedPoint ||
+		pending.PendingChannelID() != pendingID {
+
+		t.Fatalf("unexpected pending update: %v", update)
+	}
+
+	update = <-updates
+	opened, ok := update.(*ChannelOpenedUpdate)
+	if !ok || opened.ChannelPoint != expectedPoint {
+		t.Fatalf("unexpected open update: %v", update)
+	}
+
+	if _, ok := <-updates; ok {
+		t.Fatalf("expected update channel to be closed")
+	}
+	if err, ok := <-errChan; ok {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// TestOpenChannelVersionOptions tests that open options are only passed to lnd
+// versions that support them.
+func TestOpenChannelVersionOptions(t *testing.T) {
+	anchors := lnrpc.CommitmentType_ANCHORS
+	req := &OpenChannelRequest{
+		MaxLocalCsv:    144,
+		CommitmentType: &anchors,
+	}
+
+	_, err := marshallOpenChannelRequest(
+		req, &verrpc.Version{AppMinor: 11},
+	)
+	if _, ok := err.(*UnsupportedRPCError); !ok {
+		t.Fatalf("expected unsupported rpc error, got %v", err)
+	}
+
+	// lnd v0.12 supports the max local csv, but not the commitment type.
+	_, err = marshallOpenChannelRequest(
+		req, &verrpc.Version{AppMinor: 12},
+	)
+	if _, ok := err.(*UnsupportedRPCError); !ok {
+		t.Fatalf("expected unsupported rpc error, got %v", err)
+	}
+
+	rpcReq, err := marshallOpenChannelRequest(
+		req, &verrpc.Version{AppMinor: 14},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rpcReq.MaxLocalCsv != 144 ||
+		rpcReq.CommitmentType != lnrpc.CommitmentType_ANCHORS {
+
+		t.Fatalf("open options not passed: %v", rpcReq)
+	}
+}
+
+// mockTxStream is a transaction stream that returns the given transactions,
+// followed by EOF.
+type mockTxStream struct {
+	grpc.ClientStream
+
+	txs []*lnrpc.Transaction
+}
+
+func (m *mockTxStream) Recv() (*lnrpc.Transaction, error) {
+	if len(m.txs) == 0 {
+		return nil, io.EOF
+	}
+
+	tx := m.txs[0]
+	m.txs = m.txs[1:]
+	return tx, nil
+}
+
+// mockTxLightning is a lightning client that returns the given transaction
+// stream.
+type mockTxLightning struct {
+	lnrpc.LightningClient
+
+	stream *mockTxStream
+}
+
+func (m *mockTxLightning) SubscribeTransactions(_ context.Context,
+	_ *lnrpc.GetTransactionsRequest, _ ...grpc.CallOption) (
+	lnrpc.Lightning_SubscribeTransactionsClient, error) {
+
+	return m.stream, nil
+}
+
+// TestSubscribeTransactions tests that unconfirmed and confirmed transaction
+// events are converted and that the stream error is reported.
+func TestSubscribeTransactions(t *testing.T) {
+	tx := wire.NewMsgTx(2)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 1}, nil, nil))
+	tx.AddTxOut(wire.NewTxOut(10000, []byte{0x51}))
+
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		t.Fatalf("unable to serialize tx: %v", err)
+	}
+	rawTx := hex.EncodeToString(buf.Bytes())
+
+	client := newLightningClient(&mockTxLightning{
+		stream: &mockTxStream{
+			txs: []*lnrpc.Transaction{{
+				RawTxHex: rawTx,
+				Amount:   10000,
+			}, {
+				RawTxHex:         rawTx,
+				Amount:           10000,
+				NumConfirmations: 1,
+				BlockHeight:      600,
+			}},
+		},
+	}, nil, &chaincfg.RegressionNetParams, "")
+
+	txs, errChan, err := client.SubscribeTransactions(
+		context.Background(),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	unconfirmed := <-txs
+	if unconfirmed.TxHash != tx.TxHash().String() ||
+		unconfirmed.Amount != 10000 || unconfirmed.Confirmations != 0 {
+
+		t.Fatalf("unexpected unconfirmed tx: %v", unconfirmed)
+	}
+
+	confirmed := <-txs
+	if confirmed.Confirmations != 1 || confirmed.BlockHeight != 600 {
+		t.Fatalf("unexpected confirmed tx: %v", confirmed)
+	}
+
+	if err := <-errChan; err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+	if _, ok := <-txs; ok {
+		t.Fatalf("expected tx channel to be closed")
+	}
+}
+
+// mockPolicyLightning is a lightning client that applies policy updates to
+// the fees of the channels it knows and reports them in its fee report. Like
+// lnd, it only reports updates of unknown channels as failed if
+// reportFailures is set, which lnd does from v0.14.
+type mockPolicyLightning struct {
+	lnrpc.LightningClient
+
+	fees           map[wire.OutPoint]*lnrpc.ChannelFeeReport
+	reportFailures bool
+	updated        []*lnrpc.PolicyUpdateRequest
+	feeReports     int
+}
+
+func (m *mockPolicyLightning) UpdateChannelPolicy(_ context.Context,
+	req *lnrpc.PolicyUpdateRequest, _ ...grpc.CallOption) (
+	*lnrpc.PolicyUpdateResponse, error) {
+
+	m.updated = append(m.updated, req)
+
+	scope := req.Scope.(*lnrpc.PolicyUpdateRequest_ChanPoint)
+	hash, err := chainhash.NewHash(scope.ChanPoint.GetFundingTxidBytes())
+	if err != nil {
+		return nil, err
+	}
+	point := wire.OutPoint{Hash: *hash, Index: scope.ChanPoint.OutputIndex}
+
+	fee, ok := m.fees[point]
+	if !ok {
+		resp := &lnrpc.PolicyUpdateResponse{}
+		if m.reportFailures {
+			resp.FailedUpdates = []*lnrpc.FailedUpdate{{
+				Outpoint: &lnrpc.OutPoint{
+					TxidStr:     point.Hash.String(),
+					OutputIndex: point.Index,
+				},
+				Reason:      lnrpc.UpdateFailure_UPDATE_FAILURE_NOT_FOUND,
+				UpdateError: "edge not found",
+			}}
+		}
+
+		return resp, nil
+	}
+
+	fee.BaseFeeMsat = req.BaseFeeMsat
+	fee.FeePerMil = int64(math.Round(req.FeeRate * 1e6))
+
+	return &lnrpc.PolicyUpdateResponse{}, nil
+}
+
+func (m *mockPolicyLightning) FeeReport(_ context.Context,
+	_ *lnrpc.FeeReportRequest, _ ...grpc.CallOption) (
+	*lnrpc.FeeReportResponse, error) {
+
+	m.feeReports++
+
+	resp := &lnrpc.FeeReportResponse{}
+	for _, fee := range m.fees {
+		resp.ChannelFees = append(resp.ChannelFees, fee)
+	}
+
+	return resp, nil
+}
+
+// TestUpdateChanPolicies tests that every channel receives its own policy and
+// that failures are reported per channel, also by lnd versions that don't
+// report failed updates.
+func TestUpdateChanPolicies(t *testing.T) {
+	ok := wire.OutPoint{Hash: chainhash.Hash{1}}
+	missing := wire.OutPoint{Hash: chainhash.Hash{2}}
+	updates := []ChannelPolicyUpdate{{
+		ChannelPoint: ok,
+		Policy:       PolicyUpdateRequest{FeeRate: 0.0001},
+	}, {
+		ChannelPoint: missing,
+		Policy:       PolicyUpdateRequest{FeeRate: 0.0002},
+	}}
+
+	for _, minor := range []uint32{13, 14} {
+		mock := &mockPolicyLightning{
+			fees: map[wire.OutPoint]*lnrpc.ChannelFeeReport{
+				ok: {ChannelPoint: ok.String()},
+			},
+			reportFailures: minor >= 14,
+		}
+		client := newLightningClient(
+			mock, nil, &chaincfg.RegressionNetParams, "",
+		)
+		client.version = &verrpc.Version{AppMinor: minor}
+
+		results := client.UpdateChanPolicies(
+			context.Background(), updates,
+		)
+
+		if len(results) != 2 || results[0].ChannelPoint != ok ||
+			results[0].Err != nil || results[1].Err == nil {
+
+			t.Fatalf("v0.%v: unexpected results: %v", minor,
+				results)
+		}
+
+		if len(mock.updated) != 2 || mock.updated[0].FeeRate != 0.0001 {
+			t.Fatalf("v0.%v: unexpected updates: %v", minor,
+				mock.updated)
+		}
+
+		// Only versions that don't report failures need to read the
+		// fees back.
+		if (mock.feeReports == 1) != (minor < 14) {
+			t.Fatalf("v0.%v: unexpected fee reports: %v", minor,
+				mock.feeReports)
+		}
+	}
+}
+
+// TestInboundFees tests that inbound fees are only passed to lnd versions that
+// support them and that they are read from the fee report.
+func TestInboundFees(t *testing.T) {
+	point := wire.OutPoint{Hash: chainhash.Hash{1}}
+	policy := PolicyUpdateRequest{
+		InboundBaseFeeMsat: -1000,
+		InboundFeeRatePpm:  -50,
+	}
+
+	// lnd v0.18 adds the inbound fees as fields 6 and 7 to the fee report.
+	report := &lnrpc.ChannelFeeReport{ChannelPoint: point.String()}
+	var raw []byte
+	raw = protowire.AppendTag(raw, 6, protowire.VarintType)
+	raw = protowire.AppendVarint(
+		raw, uint64(int64(policy.InboundBaseFeeMsat)),
+	)
+	raw = protowire.AppendTag(raw, 7, protowire.VarintType)
+	raw = protowire.AppendVarint(
+		raw, uint64(int64(policy.InboundFeeRatePpm)),
+	)
+	report.ProtoReflect().SetUnknown(raw)
+
+	mock := &mockPolicyLightning{
+		fees: map[wire.OutPoint]*lnrpc.ChannelFeeReport{
+			point: report,
+		},
+	}
+	client := newLightningClient(
+		mock, nil, &chaincfg.RegressionNetParams, "",
+	)
+	client.version = &verrpc.Version{AppMinor: 17}
+
+	err := client.UpdateChanPolicy(context.Background(), policy, &point)
+	if _, ok := err.(*UnsupportedRPCError); !ok {
+		t.Fatalf("expected unsupported rpc error, got %v", err)
+	}
+
+	client.version = &verrpc.Version{AppMinor: 18}
+	err = client.UpdateChanPolicy(context.Background(), policy, &point)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The inbound fee is sent as field 10, a message that holds the base
+	// fee and the fee rate.
+	raw = mock.updated[0].ProtoReflect().GetUnknown()
+	num, typ, n := protowire.ConsumeTag(raw)
+	if num != 10 || typ != protowire.BytesType {
+		t.Fatalf("unexpected inbound fee field %v", num)
+	}
+	fee, _ := protowire.ConsumeBytes(raw[n:])
+
+	var values []int32
+	for len(fee) > 0 {
+		_, _, n := protowire.ConsumeTag(fee)
+		value, m := protowire.ConsumeVarint(fee[n:])
+		values = append(values, int32(value))
+		fee = fee[n+m:]
+	}
+	if len(values) != 2 || values[0] != -1000 || values[1] != -50 {
+		t.Fatalf("unexpected inbound fee: %v", values)
+	}
+
+	feeReport, err := client.FeeReport(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	channel := feeReport.Channels[0]
+	if channel.InboundBaseFeeMsat != -1000 ||
+		channel.InboundFeeRatePpm != -50 {
+
+		t.Fatalf("unexpected inbound fees: %v", channel)
+	}
+}
@@ -0,0 +1,399 @@
+package lndclient
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/lightningnetwork/lnd/zpay32"
+)
+
+const (
+	// defaultProbePrecision is the default precision of the binary search
+	// for the maximum sendable amount.
+	defaultProbePrecision = btcutil.Amount(1000)
+
+	// defaultProbeTimeout is the default time lnd may spend on a single
+	// probe payment.
+	defaultProbeTimeout = time.Minute
+)
+
+// ProbeRequest holds the parameters of a liquidity probe.
+type ProbeRequest struct {
+	// Target is the destination to probe.
+	Target route.Vertex
+
+	// MinAmt is the lowest amount to probe. If zero, one satoshi is used.
+	MinAmt btcutil.Amount
+
+	// MaxAmt is the highest amount to probe.
+	MaxAmt btcutil.Amount
+
+	// Precision is the difference between the highest amount that
+	// succeeded and the lowest amount that failed at which the search
+	// stops. If zero, 1000 satoshis are used.
+	Precision btcutil.Amount
+
+	// MaxFee is the fee limit of every probe payment.
+	MaxFee btcutil.Amount
+
+	// Timeout is the time lnd may spend on a single probe payment. If
+	// zero, one minute is used.
+	Timeout time.Duration
+
+	// FinalCLTVDelta is the CLTV delta of the final hop. If zero, lnd's
+	// default is used.
+	FinalCLTVDelta uint16
+
+	// OutgoingChanIds restricts the channels that probes may leave
+	// through.
+	OutgoingChanIds []uint64
+
+	// LastHopPubkey restricts the last hop before the target.
+	LastHopPubkey *route.Vertex
+
+	// RouteHints are route hints to reach the target.
+	RouteHints [][]zpay32.HopHint
+}
+
+// ChannelLiquidity holds the bounds of the liquidity of a channel in one
+// direction that were learned by probing.
+type ChannelLiquidity struct {
+	// ChannelID is the short channel ID of the channel.
+	ChannelID uint64
+
+	// From is the node that sends through the channel. It is zero for
+	// channels of our own node.
+	From route.Vertex
+
+	// To is the node that receives through the channel.
+	To route.Vertex
+
+	// MinLiquidity is the highest amount that was successfully sent
+	// through the channel.
+	MinLiquidity lnwire.MilliSatoshi
+
+	// MaxLiquidity is the highest amount the channel can carry. It is
+	// only known if HasMax is set.
+	MaxLiquidity lnwire.MilliSatoshi
+
+	// HasMax indicates that an amount failed in the channel, so that
+	// MaxLiquidity is known.
+	HasMax bool
+}
+
+// ProbeResult is the result of a liquidity probe.
+type ProbeResult struct {
+	// MaxSendable is the highest amount that reached the target. It is
+	// zero if no amount reached it.
+	MaxSendable btcutil.Amount
+
+	// Routes holds all routes that were tried.
+	Routes []*Route
+
+	// Liquidity holds the liquidity bounds of all channels that were
+	// part of a route, ordered by channel ID.
+	Liquidity []*ChannelLiquidity
+}
+
+// liquidityKey identifies a channel in one direction.
+type liquidityKey struct {
+	channelID uint64
+	from      route.Vertex
+}
+
+// liquidityTracker collects the liquidity bounds of channels from HTLC
+// attempts.
+type liquidityTracker struct {
+	channels map[liquidityKey]*ChannelLiquidity
+}
+
+// channel returns the liquidity bounds of the channel that leads to the hop
+// with the index provided.
+func (l *liquidityTracker) channel(rt *Route,
+	hopIdx int) *ChannelLiquidity {
+
+	var from route.Vertex
+	if hopIdx > 0 {
+		from = rt.Hops[hopIdx-1].PubKey
+	}
+
+	key := liquidityKey{
+		channelID: rt.Hops[hopIdx].ChannelID,
+		from:      from,
+	}
+
+	channel, ok := l.channels[key]
+	if !ok {
+		channel = &ChannelLiquidity{
+			ChannelID: key.channelID,
+			From:      from,
+			To:        rt.Hops[hopIdx].PubKey,
+		}
+		l.channels[key] = channel
+	}
+
+	return channel
+}
+
+// channelAmt returns the amount that is sent through the channel that leads
+// to the hop with the index provided.
+func channelAmt(rt *Route, hopIdx int) lnwire.MilliSatoshi {
+	if hopIdx == 0 {
+		return rt.TotalAmt
+	}
+
+	return rt.Hops[hopIdx-1].AmtToForward
+}
+
+// addAttempt updates the liquidity bounds with the result of an attempt. All
+// channels before the failing node carried the HTLC. If the failing node is
+// an intermediate node that reported a lack of liquidity, its outgoing
+// channel can't carry the amount.
+func (l *liquidityTracker) addAttempt(attempt *HtlcAttempt) {
+	if attempt.Route == nil || attempt.Failure == nil {
+		return
+	}
+
+	rt := attempt.Route
+	failureIdx := int(attempt.Failure.FailureSourceIndex)
+	if failureIdx > len(rt.Hops) {
+		return
+	}
+
+	for i := 0; i < failureIdx; i++ {
+		channel := l.channel(rt, i)
+		amt := channelAmt(rt, i)
+		if amt > channel.MinLiquidity {
+			channel.MinLiquidity = amt
+		}
+	}
+
+	if failureIdx == len(rt.Hops) || attempt.Failure.Code !=
+		lnrpc.Failure_TEMPORARY_CHANNEL_FAILURE {
+
+		return
+	}
+
+	channel := l.channel(rt, failureIdx)
+	maxAmt := channelAmt(rt, failureIdx) - 1
+	if !channel.HasMax || maxAmt < channel.MaxLiquidity {
+		channel.MaxLiquidity = maxAmt
+		channel.HasMax = true
+	}
+}
+
+// result returns the liquidity bounds of all channels ordered by channel ID.
+func (l *liquidityTracker) result() []*ChannelLiquidity {
+	channels := make([]*ChannelLiquidity, 0, len(l.channels))
+	for _, channel := range l.channels {
+		channels = append(channels, channel)
+	}
+
+	sort.Slice(channels, func(i, j int) bool {
+		if channels[i].ChannelID != channels[j].ChannelID {
+			return channels[i].ChannelID < channels[j].ChannelID
+		}
+
+		return channels[i].From.String() < channels[j].From.String()
+	})
+
+	return channels
+}
+
+// sendProbe sends a payment with a random payment hash, which can't be settled
+// by the target. If the target fails the payment with incorrect payment
+// details, the amount could be delivered. The final payment status is
+// returned along with the outcome.
+func sendProbe(ctx context.Context, router RouterClient,
+	req SendPaymentRequest) (bool, *PaymentStatus, error) {
+
+	var hash lntypes.Hash
+	if _, err := rand.Read(hash[:]); err != nil {
+		return false, nil, err
+	}
+	req.PaymentHash = &hash
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	statusChan, errChan, err := router.SendPayment(ctx, req)
+	if err != nil {
+		return false, nil, err
+	}
+
+	status, err := waitPayment(ctx, statusChan, errChan)
+	if err != nil {
+		return false, nil, err
+	}
+
+	if status.State == lnrpc.Payment_SUCCEEDED {
+		return false, nil, fmt.Errorf("probe with random hash %v "+
+			"succeeded", hash)
+	}
+
+	success := status.FailureReason == lnrpc.
+		PaymentFailureReason_FAILURE_REASON_INCORRECT_PAYMENT_DETAILS
+
+	return success, status, nil
+}
+
+// waitPayment waits for a payment to reach its final state, which is returned.
+func waitPayment(ctx context.Context, statusChan chan PaymentStatus,
+	errChan chan error) (*PaymentStatus, error) {
+
+	for {
+		select {
+		case status, ok := <-statusChan:
+			if !ok {
+				return nil, errors.New("payment ended " +
+					"without final status")
+			}
+
+			if status.State == lnrpc.Payment_SUCCEEDED ||
+				status.State == lnrpc.Payment_FAILED {
+
+				return &status, nil
+			}
+
+		case err, ok := <-errChan:
+			if ok {
+				return nil, err
+			}
+
+			// The error channel is closed together with the
+			// status channel, which reports the missing final
+			// status.
+			errChan = nil
+
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// ProbeLiquidity estimates the highest amount that can be sent to the target
+// by sending probe payments with random payment hashes, which the target can't
+// settle. The amount is searched for between the minimum and maximum amount of
+// the request with a binary search. A probe succeeds if the target fails it
+// with incorrect payment details, as that proves that the amount reached it.
+// Besides the maximum sendable amount, the result contains all routes that
+// were tried and the liquidity bounds that were learned from them.
+func ProbeLiquidity(ctx context.Context, router RouterClient,
+	req *ProbeRequest) (*ProbeResult, error) {
+
+	minAmt := req.MinAmt
+	if minAmt == 0 {
+		minAmt = 1
+	}
+
+	if req.MaxAmt < minAmt {
+		return nil, fmt.Errorf("max amount %v below min amount %v",
+			req.MaxAmt, minAmt)
+	}
+
+	precision := req.Precision
+	if precision == 0 {
+		precision = defaultProbePrecision
+	}
+
+	timeout := req.Timeout
+	if timeout == 0 {
+		timeout = defaultProbeTimeout
+	}
+
+	var (
+		result  = &ProbeResult{}
+		tracker = &liquidityTracker{
+			channels: make(map[liquidityKey]*ChannelLiquidity),
+		}
+	)
+
+	probe := func(amt btcutil.Amount) (bool, error) {
+		success, status, err := sendProbe(
+			ctx, router, SendPaymentRequest{
+				Target:          req.Target,
+				Amount:          amt,
+				MaxFee:          req.MaxFee,
+				Timeout:         timeout,
+				FinalCLTVDelta:  req.FinalCLTVDelta,
+				OutgoingChanIds: req.OutgoingChanIds,
+				LastHopPubkey:   req.LastHopPubkey,
+				RouteHints:      req.RouteHints,
+				MaxParts:        1,
+			},
+		)
+		if err != nil {
+			return false, err
+		}
+
+		for _, attempt := range status.Htlcs {
+			if attempt.Route != nil {
+				result.Routes = append(
+					result.Routes, attempt.Route,
+				)
+			}
+			tracker.addAttempt(attempt)
+		}
+
+		log.Debugf("Probe of %v to %v: success=%v, reason=%v", amt,
+			req.Target, success, status.FailureReason)
+
+		return success, nil
+	}
+
+	// Try the maximum amount first, which saves us the search if it can
+	// be sent.
+	success, err := probe(req.MaxAmt)
+	if err != nil {
+		return nil, err
+	}
+
+	if success {
+		result.MaxSendable = req.MaxAmt
+	} else {
+		// The highest amount that succeeded is below low, the lowest
+		// amount that failed is high.
+		low, high := minAmt, req.MaxAmt
+		for high-low > precision {
+			amt := low + (high-low)/2
+
+			success, err := probe(amt)
+			if err != nil {
+				return nil, err
+			}
+
+			if success {
+				result.MaxSendable = amt
+				low = amt
+			} else {
+				high = amt
+			}
+		}
+
+		// If no amount in the search succeeded, the minimum amount
+		// hasn't been tried yet.
+		if result.MaxSendable == 0 && low == minAmt {
+			success, err := probe(minAmt)
+			if err != nil {
+				return nil, err
+			}
+
+			if success {
+				result.MaxSendable = minAmt
+			}
+		}
+	}
+
+	result.Liquidity = tracker.result()
+
+	return result, nil
+}
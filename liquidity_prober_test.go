@@ -0,0 +1,143 @@
+package lndclient
+
+import (
+	"context"
+	"testing"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// mockProbeRouter is a router that sends probes over a two hop route. The
+// second channel of the route carries at most the bottleneck amount.
+type mockProbeRouter struct {
+	RouterClient
+
+	bottleneck lnwire.MilliSatoshi
+	probes     int
+}
+
+func (m *mockProbeRouter) SendPayment(_ context.Context,
+	req SendPaymentRequest) (chan PaymentStatus, chan error, error) {
+
+	m.probes++
+
+	amt := lnwire.NewMSatFromSatoshis(req.Amount)
+	rt := &Route{
+		TotalAmt: amt + 1000,
+		Hops: []*Hop{{
+			ChannelID:    1,
+			PubKey:       route.Vertex{1},
+			AmtToForward: amt,
+			Fee:          1000,
+		}, {
+			ChannelID:    2,
+			PubKey:       req.Target,
+			AmtToForward: amt,
+		}},
+	}
+
+	status := PaymentStatus{
+		State: lnrpc.Payment_FAILED,
+		FailureReason: lnrpc.
+			PaymentFailureReason_FAILURE_REASON_INCORRECT_PAYMENT_DETAILS,
+	}
+	failure := &HtlcFailure{
+		Code:               lnrpc.Failure_INCORRECT_OR_UNKNOWN_PAYMENT_DETAILS,
+		FailureSourceIndex: 2,
+	}
+	if amt > m.bottleneck {
+		status.FailureReason = lnrpc.
+			PaymentFailureReason_FAILURE_REASON_NO_ROUTE
+		failure = &HtlcFailure{
+			Code:               lnrpc.Failure_TEMPORARY_CHANNEL_FAILURE,
+			FailureSourceIndex: 1,
+		}
+	}
+	status.Htlcs = []*HtlcAttempt{{
+		Status:  lnrpc.HTLCAttempt_FAILED,
+		Route:   rt,
+		Failure: failure,
+	}}
+
+	statusChan := make(chan PaymentStatus, 1)
+	statusChan <- status
+	close(statusChan)
+
+	return statusChan, make(chan error), nil
+}
+
+// TestProbeLiquidity tests that the binary search finds the bottleneck of the
+// route and that the liquidity bounds of its channels are reported.
+func TestProbeLiquidity(t *testing.T) {
+	router := &mockProbeRouter{
+		bottleneck: lnwire.NewMSatFromSatoshis(60000),
+	}
+
+	result, err := ProbeLiquidity(
+		context.Background(), router, &ProbeRequest{
+			Target:    route.Vertex{2},
+			MaxAmt:    100000,
+			Precision: 100,
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.MaxSendable > 60000 || result.MaxSendable < 59900 {
+		t.Fatalf("unexpected max sendable amount: %v",
+			result.MaxSendable)
+	}
+	if len(result.Routes) != router.probes {
+		t.Fatalf("expected %v routes, got %v", router.probes,
+			len(result.Routes))
+	}
+
+	if len(result.Liquidity) != 2 {
+		t.Fatalf("expected 2 channels, got %v", len(result.Liquidity))
+	}
+
+	first := result.Liquidity[0]
+	if first.ChannelID != 1 || first.From != (route.Vertex{}) ||
+		first.HasMax {
+
+		t.Fatalf("unexpected first channel: %+v", first)
+	}
+
+	second := result.Liquidity[1]
+	if second.ChannelID != 2 || second.From != (route.Vertex{1}) {
+		t.Fatalf("unexpected second channel: %+v", second)
+	}
+	if second.MinLiquidity != lnwire.NewMSatFromSatoshis(
+		result.MaxSendable) {
+
+		t.Fatalf("unexpected min liquidity: %v", second.MinLiquidity)
+	}
+	if !second.HasMax || second.MaxLiquidity < router.bottleneck ||
+		second.MaxLiquidity > router.bottleneck+
+			lnwire.NewMSatFromSatoshis(100) {
+
+		t.Fatalf("unexpected max liquidity: %v", second.MaxLiquidity)
+	}
+
+	// If the full amount can be sent, a single probe suffices.
+	router = &mockProbeRouter{
+		bottleneck: lnwire.NewMSatFromSatoshis(btcutil.SatoshiPerBitcoin),
+	}
+	result, err = ProbeLiquidity(
+		context.Background(), router, &ProbeRequest{
+			Target: route.Vertex{2},
+			MaxAmt: 100000,
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.MaxSendable != 100000 || router.probes != 1 {
+		t.Fatalf("expected single successful probe, got %v after %v "+
+			"probes", result.MaxSendable, router.probes)
+	}
+}
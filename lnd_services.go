@@ -11,7 +11,13 @@ import (
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcutil"
 	"github.com/lightningnetwork/lnd/lncfg"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/chainrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/invoicesrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/signrpc"
 	"github.com/lightningnetwork/lnd/lnrpc/verrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/walletrpc"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
@@ -70,6 +76,15 @@ type LndServicesConfig struct {
 	// Network is the bitcoin network we expect the lnd node to operate on.
 	Network Network
 
+	// SigNetChallenge is an optional custom signet challenge script. It is
+	// only used if Network is set to NetworkSignet. If it is not set, the
+	// challenge of the default, public signet is used.
+	SigNetChallenge []byte
+
+	// SigNetSeedNodes is an optional list of seed nodes of a custom signet.
+	// It is only used if Network is set to NetworkSignet.
+	SigNetSeedNodes []string
+
 	// MacaroonDir is the directory where all lnd macaroons can be found.
 	// Either this or CustomMacaroonPath can be specified but not both.
 	MacaroonDir string
@@ -81,6 +96,11 @@ type LndServicesConfig struct {
 	// TLSPath is the path to lnd's TLS certificate file.
 	TLSPath string
 
+	// Transport is the transport that is used to talk to lnd. If no
+	// transport is set, gRPC is used. If TransportRest is used, LndAddress
+	// must point to lnd's REST proxy instead of its gRPC port.
+	Transport Transport
+
 	// CheckVersion is the minimum version the connected lnd node needs to
 	// be in order to be compatible. The node will be checked against this
 	// when connecting. If no version is supplied, the default minimum
@@ -108,6 +128,20 @@ type LndServicesConfig struct {
 // DialerFunc is a function that is used as grpc.WithContextDialer().
 type DialerFunc func(context.Context, string) (net.Conn, error)
 
+// Transport defines the protocol that is used to talk to lnd.
+type Transport string
+
+const (
+	// TransportGrpc talks to lnd's gRPC interface. This is the default.
+	TransportGrpc Transport = "grpc"
+
+	// TransportRest talks to lnd's REST proxy. This can be used if only
+	// lnd's REST port is reachable, for example through an HTTP reverse
+	// proxy. Client side and bidirectional streaming RPCs are not
+	// available through the REST proxy and return an Unimplemented error.
+	TransportRest Transport = "rest"
+)
+
 // LndServices constitutes a set of required services.
 type LndServices struct {
 	Client        LightningClient
@@ -136,70 +170,21 @@ type GrpcLndServices struct {
 // NewLndServices creates creates a connection to the given lnd instance and
 // creates a set of required RPC services.
 func NewLndServices(cfg *LndServicesConfig) (*GrpcLndServices, error) {
-	// We need to use a custom dialer so we can also connect to unix
-	// sockets and not just TCP addresses.
-	if cfg.Dialer == nil {
-		cfg.Dialer = lncfg.ClientAddressDialer(defaultRPCPort)
-	}
-
-	// Fall back to minimal compatible version if none if specified.
-	if cfg.CheckVersion == nil {
-		cfg.CheckVersion = minimalCompatibleVersion
-	}
-
-	// We don't allow setting both the macaroon directory and the custom
-	// macaroon path. If both are empty, that's fine, the default behavior
-	// is to use lnd's default directory to try to locate the macaroons.
-	if cfg.MacaroonDir != "" && cfg.CustomMacaroonPath != "" {
-		return nil, fmt.Errorf("must set either MacaroonDir or " +
-			"CustomMacaroonPath but not both")
-	}
-
-	// Based on the network, if the macaroon directory isn't set, then
-	// we'll use the expected default locations.
-	macaroonDir := cfg.MacaroonDir
-	if macaroonDir == "" {
-		switch cfg.Network {
-		case NetworkTestnet:
-			macaroonDir = filepath.Join(
-				defaultLndDir, defaultDataDir,
-				defaultChainSubDir, "bitcoin", "testnet",
-			)
-
-		case NetworkMainnet:
-			macaroonDir = filepath.Join(
-				defaultLndDir, defaultDataDir,
-				defaultChainSubDir, "bitcoin", "mainnet",
-			)
-
-		case NetworkSimnet:
-			macaroonDir = filepath.Join(
-				defaultLndDir, defaultDataDir,
-				defaultChainSubDir, "bitcoin", "simnet",
-			)
-
-		case NetworkRegtest:
-			macaroonDir = filepath.Join(
-				defaultLndDir, defaultDataDir,
-				defaultChainSubDir, "bitcoin", "regtest",
-			)
-
-		default:
-			return nil, fmt.Errorf("unsupported network: %v",
-				cfg.Network)
-		}
+	macaroonDir, err := cfg.setDefaults()
+	if err != nil {
+		return nil, err
 	}
 
 	// Setup connection with lnd
 	log.Infof("Creating lnd connection to %v", cfg.LndAddress)
-	conn, err := getClientConn(cfg)
+	conn, err := getLndConn(cfg)
 	if err != nil {
 		return nil, err
 	}
 
 	log.Infof("Connected to lnd")
 
-	chainParams, err := cfg.Network.ChainParams()
+	chainParams, err := cfg.chainParams()
 	if err != nil {
 		return nil, err
 	}
@@ -233,21 +218,34 @@ func NewLndServices(cfg *LndServicesConfig) (*GrpcLndServices, error) {
 	// With the macaroons loaded and the version checked, we can now create
 	// the real lightning client which uses the admin macaroon.
 	lightningClient := newLightningClient(
-		conn, chainParams, macaroons.adminMac,
+		conn.lightning, conn.router, chainParams, macaroons.adminMac,
 	)
+	lightningClient.version = version
 
 	// With the network check passed, we'll now initialize the rest of the
 	// sub-server connections, giving each of them their specific macaroon.
-	notifierClient := newChainNotifierClient(conn, macaroons.chainMac)
-	signerClient := newSignerClient(conn, macaroons.signerMac)
-	walletKitClient := newWalletKitClient(conn, macaroons.walletKitMac)
-	invoicesClient := newInvoicesClient(conn, macaroons.invoiceMac)
-	routerClient := newRouterClient(conn, macaroons.routerMac)
-	versionerClient := newVersionerClient(conn, macaroons.readonlyMac)
+	notifierClient := newChainNotifierClient(
+		conn.chainNotifier, macaroons.chainMac,
+	)
+	signerClient := newSignerClient(conn.signer, macaroons.signerMac)
+	walletKitClient := newWalletKitClient(
+		conn.walletKit, macaroons.walletKitMac,
+	)
+	invoicesClient := newInvoicesClient(
+		conn.invoices, chainParams, macaroons.invoiceMac,
+	)
+	invoicesClient.version = version
+	routerClient := newRouterClient(
+		conn.router, chainParams, macaroons.routerMac,
+	)
+	routerClient.version = version
+	versionerClient := newVersionerClient(
+		conn.versioner, macaroons.readonlyMac,
+	)
 
 	cleanup := func() {
 		log.Debugf("Closing lnd connection")
-		err := conn.Close()
+		err := conn.close()
 		if err != nil {
 			log.Errorf("Error closing client connection: %v", err)
 		}
@@ -305,6 +303,59 @@ func NewLndServices(cfg *LndServicesConfig) (*GrpcLndServices, error) {
 	return services, nil
 }
 
+// setDefaults fills in the default dialer and minimum version if they are not
+// set and validates the macaroon settings. The directory the macaroons should
+// be loaded from is returned.
+func (cfg *LndServicesConfig) setDefaults() (string, error) {
+	// We need to use a custom dialer so we can also connect to unix
+	// sockets and not just TCP addresses.
+	if cfg.Dialer == nil {
+		defaultPort := defaultRPCPort
+		if cfg.Transport == TransportRest {
+			defaultPort = defaultRESTPort
+		}
+		cfg.Dialer = lncfg.ClientAddressDialer(defaultPort)
+	}
+
+	// Fall back to minimal compatible version if none if specified.
+	if cfg.CheckVersion == nil {
+		cfg.CheckVersion = minimalCompatibleVersion
+	}
+
+	// We don't allow setting both the macaroon directory and the custom
+	// macaroon path. If both are empty, that's fine, the default behavior
+	// is to use lnd's default directory to try to locate the macaroons.
+	if cfg.MacaroonDir != "" && cfg.CustomMacaroonPath != "" {
+		return "", fmt.Errorf("must set either MacaroonDir or " +
+			"CustomMacaroonPath but not both")
+	}
+
+	// Based on the network, if the macaroon directory isn't set, then
+	// we'll use the expected default locations.
+	macaroonDir := cfg.MacaroonDir
+	if macaroonDir == "" {
+		var err error
+		macaroonDir, err = defaultMacaroonDir(cfg.Network)
+		if err != nil {
+			return "", fmt.Errorf("unsupported network: %v",
+				cfg.Network)
+		}
+	}
+
+	return macaroonDir, nil
+}
+
+// chainParams returns the chain parameters of the configured network.
+func (cfg *LndServicesConfig) chainParams() (*chaincfg.Params, error) {
+	// Custom signets are only distinguished by their challenge, so we need
+	// to derive their chain parameters from the configuration.
+	if cfg.Network == NetworkSignet {
+		return SigNetParams(cfg.SigNetChallenge, cfg.SigNetSeedNodes)
+	}
+
+	return cfg.Network.ChainParams()
+}
+
 // Close closes the lnd connection and waits for all sub server clients to
 // finish their goroutines.
 func (s *GrpcLndServices) Close() {
@@ -368,14 +419,14 @@ func (s *GrpcLndServices) waitForChainSync(ctx context.Context) error {
 // checkLndCompatibility makes sure the connected lnd instance is running on the
 // correct network, has the version RPC implemented, is the correct minimal
 // version and supports all required build tags/subservers.
-func checkLndCompatibility(conn *grpc.ClientConn, chainParams *chaincfg.Params,
+func checkLndCompatibility(conn *lndConn, chainParams *chaincfg.Params,
 	readonlyMac serializedMacaroon, network Network,
 	minVersion *verrpc.Version) (string, [33]byte, *verrpc.Version, error) {
 
 	// onErr is a closure that simplifies returning multiple values in the
 	// error case.
 	onErr := func(err error) (string, [33]byte, *verrpc.Version, error) {
-		closeErr := conn.Close()
+		closeErr := conn.close()
 		if closeErr != nil {
 			log.Errorf("Error closing lnd connection: %v", closeErr)
 		}
@@ -394,8 +445,10 @@ func checkLndCompatibility(conn *grpc.ClientConn, chainParams *chaincfg.Params,
 
 	// We use our own clients with a readonly macaroon here, because we know
 	// that's all we need for the checks.
-	lightningClient := newLightningClient(conn, chainParams, readonlyMac)
-	versionerClient := newVersionerClient(conn, readonlyMac)
+	lightningClient := newLightningClient(
+		conn.lightning, conn.router, chainParams, readonlyMac,
+	)
+	versionerClient := newVersionerClient(conn.versioner, readonlyMac)
 
 	// With our readonly macaroon obtained, we'll ensure that the network
 	// for lnd matches our expected network.
@@ -491,6 +544,37 @@ func assertVersionCompatible(actual *verrpc.Version,
 	return nil
 }
 
+// UnsupportedRPCError is returned by methods that wrap an rpc or use a feature
+// which the connected lnd version doesn't offer yet.
+type UnsupportedRPCError struct {
+	// RPC is the name of the rpc or feature.
+	RPC string
+
+	// MinVersion is the first lnd version that offers the rpc.
+	MinVersion string
+}
+
+// Error returns the error message of the unsupported rpc.
+func (e *UnsupportedRPCError) Error() string {
+	return fmt.Sprintf("%v requires lnd %v or later", e.RPC, e.MinVersion)
+}
+
+// checkVersion returns an UnsupportedRPCError if the connected lnd is older
+// than the first version that offers the rpc. If the version of the connected
+// lnd isn't known, the rpc is attempted anyway.
+func checkVersion(actual *verrpc.Version, rpc string,
+	minVersion *verrpc.Version) error {
+
+	if actual == nil || assertVersionCompatible(actual, minVersion) == nil {
+		return nil
+	}
+
+	return &UnsupportedRPCError{
+		RPC:        rpc,
+		MinVersion: VersionStringShort(minVersion),
+	}
+}
+
 // assertBuildTagsEnabled makes sure all required build tags are set.
 func assertBuildTagsEnabled(actual *verrpc.Version,
 	requiredTags []string) error {
@@ -511,6 +595,7 @@ func assertBuildTagsEnabled(actual *verrpc.Version,
 
 var (
 	defaultRPCPort         = "10009"
+	defaultRESTPort        = "8080"
 	defaultLndDir          = btcutil.AppDataDir("lnd", false)
 	defaultTLSCertFilename = "tls.cert"
 	defaultTLSCertPath     = filepath.Join(
@@ -532,6 +617,65 @@ var (
 	maxMsgRecvSize = grpc.MaxCallRecvMsgSize(1 * 1024 * 1024 * 200)
 )
 
+// lndConn holds the raw RPC clients of all subservers that are needed to
+// create the lnd services. Depending on the configured transport, they either
+// use a gRPC connection or a connection to lnd's REST proxy.
+type lndConn struct {
+	lightning     lnrpc.LightningClient
+	chainNotifier chainrpc.ChainNotifierClient
+	signer        signrpc.SignerClient
+	walletKit     walletrpc.WalletKitClient
+	invoices      invoicesrpc.InvoicesClient
+	router        routerrpc.RouterClient
+	versioner     verrpc.VersionerClient
+
+	// close closes the underlying connection.
+	close func() error
+}
+
+// getLndConn creates the connection to lnd using the configured transport.
+func getLndConn(cfg *LndServicesConfig) (*lndConn, error) {
+	switch cfg.Transport {
+	case "", TransportGrpc:
+		conn, err := getClientConn(cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		return &lndConn{
+			lightning:     lnrpc.NewLightningClient(conn),
+			chainNotifier: chainrpc.NewChainNotifierClient(conn),
+			signer:        signrpc.NewSignerClient(conn),
+			walletKit:     walletrpc.NewWalletKitClient(conn),
+			invoices:      invoicesrpc.NewInvoicesClient(conn),
+			router:        routerrpc.NewRouterClient(conn),
+			versioner:     verrpc.NewVersionerClient(conn),
+			close:         conn.Close,
+		}, nil
+
+	case TransportRest:
+		conn, err := newRestConn(cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		return &lndConn{
+			lightning:     &restLightningClient{conn: conn},
+			chainNotifier: &restChainNotifierClient{conn: conn},
+			signer:        &restSignerClient{conn: conn},
+			walletKit:     &restWalletKitClient{conn: conn},
+			invoices:      &restInvoicesClient{conn: conn},
+			router:        &restRouterClient{conn: conn},
+			versioner:     &restVersionerClient{conn: conn},
+			close:         conn.Close,
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported transport: %v",
+			cfg.Transport)
+	}
+}
+
 func getClientConn(cfg *LndServicesConfig) (*grpc.ClientConn, error) {
 
 	// Load the specified TLS certificate and build transport credentials
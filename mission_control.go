@@ -0,0 +1,285 @@
+package lndclient
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/verrpc"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// Mission control is lnd's memory of past payment attempts, which it uses to
+// estimate the success probability of routes.
+
+// MissionControlConfig holds the parameters mission control uses to estimate
+// success probabilities.
+type MissionControlConfig struct {
+	// HalfLifeTime is the time after which a failure has lost half of its
+	// impact on the probability.
+	HalfLifeTime time.Duration
+
+	// HopProbability is the probability of a hop that has no recorded
+	// results.
+	HopProbability float64
+
+	// Weight is the weight of historical results compared to the hop
+	// probability.
+	Weight float64
+
+	// MaximumPaymentResults is the number of payment results that are
+	// kept.
+	MaximumPaymentResults uint32
+
+	// MinimumFailureRelaxInterval is the minimum time that must pass
+	// before a failure may be overwritten by a success at a higher
+	// amount.
+	MinimumFailureRelaxInterval time.Duration
+}
+
+// PairData holds the results of past payment attempts through a node pair.
+type PairData struct {
+	// FailTime is the time of the last failure. It is zero if there was
+	// no failure.
+	FailTime time.Time
+
+	// FailAmt is the lowest amount that failed. It is only set if
+	// FailTime is set.
+	FailAmt lnwire.MilliSatoshi
+
+	// SuccessTime is the time of the last success. It is zero if there
+	// was no success.
+	SuccessTime time.Time
+
+	// SuccessAmt is the highest amount that succeeded. It is only set if
+	// SuccessTime is set.
+	SuccessAmt lnwire.MilliSatoshi
+}
+
+// unixTime converts a unix timestamp in seconds, which is zero if unset.
+func unixTime(timestamp int64) time.Time {
+	if timestamp == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(timestamp, 0)
+}
+
+// newPairData converts rpc pair data, which may be nil.
+func newPairData(data *routerrpc.PairData) *PairData {
+	if data == nil {
+		return &PairData{}
+	}
+
+	return &PairData{
+		FailTime:    unixTime(data.FailTime),
+		FailAmt:     lnwire.MilliSatoshi(data.FailAmtMsat),
+		SuccessTime: unixTime(data.SuccessTime),
+		SuccessAmt:  lnwire.MilliSatoshi(data.SuccessAmtMsat),
+	}
+}
+
+// QueryMissionControl returns the history of all node pairs that mission
+// control has recorded results for.
+func (r *routerClient) QueryMissionControl(ctx context.Context) (
+	map[NodePair]*PairData, error) {
+
+	rpcCtx, cancel := context.WithTimeout(ctx, rpcTimeout)
+	defer cancel()
+
+	rpcCtx = r.routerKitMac.WithMacaroonAuth(rpcCtx)
+	resp, err := r.client.QueryMissionControl(
+		rpcCtx, &routerrpc.QueryMissionControlRequest{},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := make(map[NodePair]*PairData, len(resp.Pairs))
+	for _, pair := range resp.Pairs {
+		from, err := route.NewVertexFromBytes(pair.NodeFrom)
+		if err != nil {
+			return nil, err
+		}
+
+		to, err := route.NewVertexFromBytes(pair.NodeTo)
+		if err != nil {
+			return nil, err
+		}
+
+		pairs[NodePair{From: from, To: to}] = newPairData(pair.History)
+	}
+
+	return pairs, nil
+}
+
+// ResetMissionControl clears all results recorded by mission control.
+func (r *routerClient) ResetMissionControl(ctx context.Context) error {
+	rpcCtx, cancel := context.WithTimeout(ctx, rpcTimeout)
+	defer cancel()
+
+	rpcCtx = r.routerKitMac.WithMacaroonAuth(rpcCtx)
+	_, err := r.client.ResetMissionControl(
+		rpcCtx, &routerrpc.ResetMissionControlRequest{},
+	)
+	return err
+}
+
+// QueryProbability returns the probability that mission control assigns to
+// sending the amount provided through the node pair, along with the history of
+// the pair.
+func (r *routerClient) QueryProbability(ctx context.Context, pair NodePair,
+	amt lnwire.MilliSatoshi) (float64, *PairData, error) {
+
+	rpcCtx, cancel := context.WithTimeout(ctx, rpcTimeout)
+	defer cancel()
+
+	rpcCtx = r.routerKitMac.WithMacaroonAuth(rpcCtx)
+	resp, err := r.client.QueryProbability(
+		rpcCtx, &routerrpc.QueryProbabilityRequest{
+			FromNode: pair.From[:],
+			ToNode:   pair.To[:],
+			AmtMsat:  int64(amt),
+		},
+	)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return resp.Probability, newPairData(resp.History), nil
+}
+
+// missionControlVersion is the first lnd version that lets us import pair
+// histories into mission control and change its config.
+var missionControlVersion = &verrpc.Version{AppMinor: 13}
+
+// ImportMissionControl adds the pair histories provided to mission control.
+func (r *routerClient) ImportMissionControl(ctx context.Context,
+	pairs map[NodePair]*PairData) error {
+
+	err := checkVersion(
+		r.version, "XImportMissionControl", missionControlVersion,
+	)
+	if err != nil {
+		return err
+	}
+
+	rpcPairs := make([]*routerrpc.PairHistory, 0, len(pairs))
+	for pair, data := range pairs {
+		pair := pair
+		rpcPairs = append(rpcPairs, &routerrpc.PairHistory{
+			NodeFrom: pair.From[:],
+			NodeTo:   pair.To[:],
+			History:  marshallPairData(data),
+		})
+	}
+
+	rpcCtx, cancel := context.WithTimeout(ctx, rpcTimeout)
+	defer cancel()
+
+	rpcCtx = r.routerKitMac.WithMacaroonAuth(rpcCtx)
+	_, err = r.client.XImportMissionControl(
+		rpcCtx, &routerrpc.XImportMissionControlRequest{
+			Pairs: rpcPairs,
+		},
+	)
+	return err
+}
+
+// unixTimestamp converts a time to a unix timestamp in seconds, which is zero
+// if the time is unset.
+func unixTimestamp(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.Unix()
+}
+
+// marshallPairData converts pair data into its rpc counterpart.
+func marshallPairData(data *PairData) *routerrpc.PairData {
+	return &routerrpc.PairData{
+		FailTime:       unixTimestamp(data.FailTime),
+		FailAmtSat:     int64(data.FailAmt.ToSatoshis()),
+		FailAmtMsat:    int64(data.FailAmt),
+		SuccessTime:    unixTimestamp(data.SuccessTime),
+		SuccessAmtSat:  int64(data.SuccessAmt.ToSatoshis()),
+		SuccessAmtMsat: int64(data.SuccessAmt),
+	}
+}
+
+// GetMissionControlConfig returns the config of mission control.
+func (r *routerClient) GetMissionControlConfig(ctx context.Context) (
+	*MissionControlConfig, error) {
+
+	err := checkVersion(
+		r.version, "GetMissionControlConfig", missionControlVersion,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	rpcCtx, cancel := context.WithTimeout(ctx, rpcTimeout)
+	defer cancel()
+
+	rpcCtx = r.routerKitMac.WithMacaroonAuth(rpcCtx)
+	resp, err := r.client.GetMissionControlConfig(
+		rpcCtx, &routerrpc.GetMissionControlConfigRequest{},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := resp.Config
+	if cfg == nil {
+		return nil, errors.New("no mission control config returned")
+	}
+
+	halfLife := time.Duration(cfg.HalfLifeSeconds) * time.Second
+	relaxInterval := time.Duration(cfg.MinimumFailureRelaxInterval) *
+		time.Second
+
+	return &MissionControlConfig{
+		HalfLifeTime:                halfLife,
+		HopProbability:              float64(cfg.HopProbability),
+		Weight:                      float64(cfg.Weight),
+		MaximumPaymentResults:       cfg.MaximumPaymentResults,
+		MinimumFailureRelaxInterval: relaxInterval,
+	}, nil
+}
+
+// SetMissionControlConfig changes the config of mission control. lnd applies
+// the full config, so all of its values must be set.
+func (r *routerClient) SetMissionControlConfig(ctx context.Context,
+	cfg *MissionControlConfig) error {
+
+	err := checkVersion(
+		r.version, "SetMissionControlConfig", missionControlVersion,
+	)
+	if err != nil {
+		return err
+	}
+
+	rpcCtx, cancel := context.WithTimeout(ctx, rpcTimeout)
+	defer cancel()
+
+	rpcCfg := &routerrpc.MissionControlConfig{
+		HalfLifeSeconds:       uint64(cfg.HalfLifeTime / time.Second),
+		HopProbability:        float32(cfg.HopProbability),
+		Weight:                float32(cfg.Weight),
+		MaximumPaymentResults: cfg.MaximumPaymentResults,
+		MinimumFailureRelaxInterval: uint64(
+			cfg.MinimumFailureRelaxInterval / time.Second,
+		),
+	}
+
+	rpcCtx = r.routerKitMac.WithMacaroonAuth(rpcCtx)
+	_, err = r.client.SetMissionControlConfig(
+		rpcCtx, &routerrpc.SetMissionControlConfigRequest{
+			Config: rpcCfg,
+		},
+	)
+	return err
+}
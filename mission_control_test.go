@@ -0,0 +1,169 @@
+package lndclient
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/verrpc"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"google.golang.org/grpc"
+)
+
+// mockMissionControlRouter is a router that returns the given pair history and
+// mission control config, and records imports and config changes.
+type mockMissionControlRouter struct {
+	routerrpc.RouterClient
+
+	pairs []*routerrpc.PairHistory
+	cfg   *routerrpc.MissionControlConfig
+}
+
+func (m *mockMissionControlRouter) QueryMissionControl(_ context.Context,
+	_ *routerrpc.QueryMissionControlRequest, _ ...grpc.CallOption) (
+	*routerrpc.QueryMissionControlResponse, error) {
+
+	return &routerrpc.QueryMissionControlResponse{Pairs: m.pairs}, nil
+}
+
+// TestQueryMissionControl tests that pair histories are keyed by their node
+// pair and that unset timestamps are reported as zero times.
+func TestQueryMissionControl(t *testing.T) {
+	from := route.Vertex{1}
+	to := route.Vertex{2}
+
+	client := newRouterClient(&mockMissionControlRouter{
+		pairs: []*routerrpc.PairHistory{{
+			NodeFrom: from[:],
+			NodeTo:   to[:],
+			History: &routerrpc.PairData{
+				FailTime:    1000,
+				FailAmtMsat: 5000,
+			},
+		}},
+	}, nil, "")
+
+	pairs, err := client.QueryMissionControl(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, ok := pairs[NodePair{From: from, To: to}]
+	if !ok {
+		t.Fatalf("pair not found: %v", pairs)
+	}
+	if !data.FailTime.Equal(time.Unix(1000, 0)) || data.FailAmt != 5000 {
+		t.Fatalf("unexpected failure data: %v", data)
+	}
+	if !data.SuccessTime.IsZero() {
+		t.Fatalf("expected zero success time, got %v",
+			data.SuccessTime)
+	}
+}
+
+func (m *mockMissionControlRouter) XImportMissionControl(_ context.Context,
+	req *routerrpc.XImportMissionControlRequest, _ ...grpc.CallOption) (
+	*routerrpc.XImportMissionControlResponse, error) {
+
+	m.pairs = append(m.pairs, req.Pairs...)
+	return &routerrpc.XImportMissionControlResponse{}, nil
+}
+
+func (m *mockMissionControlRouter) GetMissionControlConfig(_ context.Context,
+	_ *routerrpc.GetMissionControlConfigRequest, _ ...grpc.CallOption) (
+	*routerrpc.GetMissionControlConfigResponse, error) {
+
+	return &routerrpc.GetMissionControlConfigResponse{
+		Config: m.cfg,
+	}, nil
+}
+
+func (m *mockMissionControlRouter) SetMissionControlConfig(_ context.Context,
+	req *routerrpc.SetMissionControlConfigRequest, _ ...grpc.CallOption) (
+	*routerrpc.SetMissionControlConfigResponse, error) {
+
+	m.cfg = req.Config
+	return &routerrpc.SetMissionControlConfigResponse{}, nil
+}
+
+// TestMissionControlImportAndConfig tests that pair histories are imported
+// and that the config can be changed and read back.
+func TestMissionControlImportAndConfig(t *testing.T) {
+	mock := &mockMissionControlRouter{}
+	client := newRouterClient(mock, nil, "")
+	client.version = &verrpc.Version{AppMinor: 13}
+	ctx := context.Background()
+
+	from := route.Vertex{1}
+	to := route.Vertex{2}
+	err := client.ImportMissionControl(ctx, map[NodePair]*PairData{
+		{From: from, To: to}: {
+			SuccessTime: time.Unix(2000, 0),
+			SuccessAmt:  12345,
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(mock.pairs) != 1 {
+		t.Fatalf("expected 1 imported pair, got %v", len(mock.pairs))
+	}
+	history := mock.pairs[0].History
+	if !bytes.Equal(mock.pairs[0].NodeFrom, from[:]) ||
+		history.SuccessTime != 2000 ||
+		history.SuccessAmtMsat != 12345 ||
+		history.SuccessAmtSat != 12 || history.FailTime != 0 {
+
+		t.Fatalf("unexpected imported pair: %v", mock.pairs[0])
+	}
+
+	cfg := &MissionControlConfig{
+		HalfLifeTime:                time.Hour,
+		HopProbability:              0.5,
+		Weight:                      0.25,
+		MaximumPaymentResults:       1000,
+		MinimumFailureRelaxInterval: time.Minute,
+	}
+	if err := client.SetMissionControlConfig(ctx, cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mock.cfg.HalfLifeSeconds != 3600 ||
+		mock.cfg.MinimumFailureRelaxInterval != 60 {
+
+		t.Fatalf("unexpected rpc config: %v", mock.cfg)
+	}
+
+	readCfg, err := client.GetMissionControlConfig(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *readCfg != *cfg {
+		t.Fatalf("expected config %v, got %v", cfg, readCfg)
+	}
+}
+
+// TestMissionControlUnsupported tests that the mission control rpcs fail with
+// an explicit error if lnd is older than v0.13.
+func TestMissionControlUnsupported(t *testing.T) {
+	client := newRouterClient(&mockMissionControlRouter{}, nil, "")
+	client.version = &verrpc.Version{AppMinor: 12}
+	ctx := context.Background()
+
+	_, err := client.GetMissionControlConfig(ctx)
+	if _, ok := err.(*UnsupportedRPCError); !ok {
+		t.Fatalf("expected unsupported rpc error, got %v", err)
+	}
+
+	err = client.SetMissionControlConfig(ctx, &MissionControlConfig{})
+	if _, ok := err.(*UnsupportedRPCError); !ok {
+		t.Fatalf("expected unsupported rpc error, got %v", err)
+	}
+
+	err = client.ImportMissionControl(ctx, nil)
+	if _, ok := err.(*UnsupportedRPCError); !ok {
+		t.Fatalf("expected unsupported rpc error, got %v", err)
+	}
+}
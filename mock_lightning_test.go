@@ -0,0 +1,184 @@
+package lndclient
+
+import (
+	"context"
+	"errors"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// mockLightning is a lightning client that serves the node state it is
+// configured with and records the requests and updates it receives. History
+// queries are paged like lnd does.
+type mockLightning struct {
+	LightningClient
+
+	info     Info
+	channels []ChannelInfo
+	closed   []ClosedChannel
+	pending  []PendingChannel
+	txs      []Transaction
+	forwards []ForwardingEvent
+	payments []Payment
+	invoices []Invoice
+	edges    map[uint64]*ChannelEdge
+
+	// updateErr is the error that all policy updates fail with.
+	updateErr error
+
+	txRanges        [][2]int32
+	forwardRequests []ForwardingHistoryRequest
+	paymentRequests []ListPaymentsRequest
+	invoiceRequests []ListInvoicesRequest
+	updates         []ChannelPolicyUpdate
+	abandoned       []wire.OutPoint
+}
+
+func (m *mockLightning) GetInfo(_ context.Context) (*Info, error) {
+	info := m.info
+	return &info, nil
+}
+
+func (m *mockLightning) ListChannels(_ context.Context) ([]ChannelInfo,
+	error) {
+
+	return m.channels, nil
+}
+
+func (m *mockLightning) ClosedChannels(_ context.Context) ([]ClosedChannel,
+	error) {
+
+	return m.closed, nil
+}
+
+func (m *mockLightning) PendingChannels(_ context.Context) (*PendingChannels,
+	error) {
+
+	return &PendingChannels{PendingOpen: m.pending}, nil
+}
+
+// ListTransactions returns the transactions within the block range. An end
+// height of -1 includes unconfirmed transactions.
+func (m *mockLightning) ListTransactions(_ context.Context, start,
+	end int32) ([]Transaction, error) {
+
+	m.txRanges = append(m.txRanges, [2]int32{start, end})
+
+	var txs []Transaction
+	for _, tx := range m.txs {
+		if tx.BlockHeight < start ||
+			(end != -1 && tx.BlockHeight > end) {
+
+			continue
+		}
+		txs = append(txs, tx)
+	}
+
+	return txs, nil
+}
+
+// ForwardingHistory returns the events after the offset. Events are not
+// filtered by time.
+func (m *mockLightning) ForwardingHistory(_ context.Context,
+	req ForwardingHistoryRequest) (*ForwardingHistoryResponse, error) {
+
+	m.forwardRequests = append(m.forwardRequests, req)
+
+	resp := &ForwardingHistoryResponse{LastIndexOffset: req.Offset}
+	for i := int(req.Offset); i < len(m.forwards); i++ {
+		if req.MaxEvents != 0 &&
+			len(resp.Events) == int(req.MaxEvents) {
+
+			break
+		}
+
+		resp.Events = append(resp.Events, m.forwards[i])
+		resp.LastIndexOffset++
+	}
+
+	return resp, nil
+}
+
+// ListPayments returns the payments with a sequence number after the offset.
+func (m *mockLightning) ListPayments(_ context.Context,
+	req ListPaymentsRequest) (*ListPaymentsResponse, error) {
+
+	m.paymentRequests = append(m.paymentRequests, req)
+
+	resp := &ListPaymentsResponse{}
+	for _, payment := range m.payments {
+		if req.MaxPayments != 0 &&
+			uint64(len(resp.Payments)) == req.MaxPayments {
+
+			break
+		}
+		if payment.SequenceNumber <= req.Offset {
+			continue
+		}
+
+		resp.Payments = append(resp.Payments, payment)
+	}
+
+	return resp, nil
+}
+
+// ListInvoices returns the invoices with an add index after the offset.
+func (m *mockLightning) ListInvoices(_ context.Context,
+	req ListInvoicesRequest) (*ListInvoicesResponse, error) {
+
+	m.invoiceRequests = append(m.invoiceRequests, req)
+
+	resp := &ListInvoicesResponse{}
+	for _, invoice := range m.invoices {
+		if req.MaxInvoices != 0 &&
+			uint64(len(resp.Invoices)) == req.MaxInvoices {
+
+			break
+		}
+		if invoice.AddIndex <= req.Offset {
+			continue
+		}
+
+		resp.Invoices = append(resp.Invoices, invoice)
+	}
+
+	return resp, nil
+}
+
+func (m *mockLightning) GetChanInfo(_ context.Context,
+	chanID uint64) (*ChannelEdge, error) {
+
+	edge, ok := m.edges[chanID]
+	if !ok {
+		return nil, errors.New("edge not found")
+	}
+
+	return edge, nil
+}
+
+func (m *mockLightning) UpdateChanPolicies(_ context.Context,
+	updates []ChannelPolicyUpdate) []ChannelPolicyResult {
+
+	m.updates = append(m.updates, updates...)
+
+	results := make([]ChannelPolicyResult, len(updates))
+	for i, update := range updates {
+		results[i].ChannelPoint = update.ChannelPoint
+		results[i].Err = m.updateErr
+	}
+
+	return results
+}
+
+func (m *mockLightning) ChannelBackup(_ context.Context,
+	point wire.OutPoint) ([]byte, error) {
+
+	return point.Hash[:], nil
+}
+
+func (m *mockLightning) AbandonChannel(_ context.Context,
+	point wire.OutPoint) error {
+
+	m.abandoned = append(m.abandoned, point)
+	return nil
+}
@@ -1,9 +1,17 @@
 package lndclient
 
 import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
 	"errors"
+	"math/big"
+	"path/filepath"
+	"time"
 
 	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
 )
 
 // Network defines the chain that we operate on.
@@ -21,9 +29,38 @@ const (
 
 	// NetworkSimnet is bitcoin simnet.
 	NetworkSimnet Network = "simnet"
+
+	// NetworkSignet is bitcoin signet. Without any further configuration
+	// this refers to the default, public signet. Custom signets can be
+	// used by creating their chain parameters with SigNetParams.
+	NetworkSignet Network = "signet"
+)
+
+var (
+	// DefaultSigNetChallenge is the challenge script of the default,
+	// public signet.
+	DefaultSigNetChallenge, _ = hex.DecodeString(
+		"512103ad5e0edad18cb1f0fc0d28a3d4f1f3e445640337489abb10404f2d" +
+			"1e086be430210359ef5021964fe22d6f8e05b2463c9540ce9688" +
+			"3fe3b278760f048f5189f2e6c452ae",
+	)
+
+	// DefaultSigNetSeedNodes is the list of DNS seeds of the default,
+	// public signet.
+	DefaultSigNetSeedNodes = []string{
+		"seed.signet.bitcoin.sprovoost.nl",
+	}
+
+	// sigNetPowLimit is the highest proof of work value a signet block can
+	// have. It is the value 0x0377ae << 216.
+	sigNetPowLimit = new(big.Int).Lsh(big.NewInt(0x0377ae), 216)
+
+	// sigNetPowLimitBits is the compact representation of sigNetPowLimit.
+	sigNetPowLimitBits uint32 = 0x1e0377ae
 )
 
-// ChainParams returns chain parameters based on a network name.
+// ChainParams returns chain parameters based on a network name. For signet,
+// the parameters of the default, public signet are returned.
 func (n Network) ChainParams() (*chaincfg.Params, error) {
 	switch n {
 	case NetworkMainnet:
@@ -38,7 +75,88 @@ func (n Network) ChainParams() (*chaincfg.Params, error) {
 	case NetworkSimnet:
 		return &chaincfg.SimNetParams, nil
 
+	case NetworkSignet:
+		return SigNetParams(nil, nil)
+
 	default:
 		return nil, errors.New("unknown network")
 	}
 }
+
+// defaultMacaroonDir returns the directory lnd stores its macaroons in by
+// default when running on the given network.
+func defaultMacaroonDir(network Network) (string, error) {
+	switch network {
+	case NetworkMainnet, NetworkTestnet, NetworkRegtest, NetworkSimnet,
+		NetworkSignet:
+
+		return filepath.Join(
+			defaultLndDir, defaultDataDir, defaultChainSubDir,
+			"bitcoin", string(network),
+		), nil
+
+	default:
+		return "", errors.New("unknown network")
+	}
+}
+
+// SigNetParams returns the chain parameters for a signet with the given
+// challenge script and seed nodes. If no challenge is given, the challenge of
+// the default, public signet is used. If no seed nodes are given and the
+// challenge is the default one, the default signet seed nodes are used.
+//
+// NOTE: All signets share the same genesis block, they are only distinguished
+// by their challenge which determines the network magic.
+func SigNetParams(challenge []byte, seedNodes []string) (*chaincfg.Params,
+	error) {
+
+	if len(challenge) == 0 {
+		challenge = DefaultSigNetChallenge
+		if len(seedNodes) == 0 {
+			seedNodes = DefaultSigNetSeedNodes
+		}
+	}
+
+	// The network magic is defined as the first four bytes of the double
+	// SHA256 of the challenge script, serialized as a single push (that is,
+	// prefixed with its length as a var int).
+	var challengeBuf bytes.Buffer
+	if err := wire.WriteVarBytes(&challengeBuf, 0, challenge); err != nil {
+		return nil, err
+	}
+	challengeHash := chainhash.DoubleHashB(challengeBuf.Bytes())
+
+	dnsSeeds := make([]chaincfg.DNSSeed, len(seedNodes))
+	for i, seedNode := range seedNodes {
+		dnsSeeds[i] = chaincfg.DNSSeed{
+			Host: seedNode,
+		}
+	}
+
+	// Signet shares the genesis coinbase transaction with all other
+	// networks, only the header is different.
+	genesisBlock := *chaincfg.MainNetParams.GenesisBlock
+	genesisBlock.Header.Timestamp = time.Unix(1598918400, 0)
+	genesisBlock.Header.Bits = sigNetPowLimitBits
+	genesisBlock.Header.Nonce = 52613770
+	genesisHash := genesisBlock.BlockHash()
+
+	// Signet uses the same address encoding as testnet, so we start with a
+	// copy of its parameters and only change what's different.
+	params := chaincfg.TestNet3Params
+	params.Name = string(NetworkSignet)
+	params.Net = wire.BitcoinNet(
+		binary.LittleEndian.Uint32(challengeHash[0:4]),
+	)
+	params.DefaultPort = "38333"
+	params.DNSSeeds = dnsSeeds
+	params.GenesisBlock = &genesisBlock
+	params.GenesisHash = &genesisHash
+	params.PowLimit = sigNetPowLimit
+	params.PowLimitBits = sigNetPowLimitBits
+	params.ReduceMinDifficulty = false
+	params.MinDiffReductionTime = 0
+	params.Checkpoints = nil
+
+	return &params, nil
+}
@@ -0,0 +1,49 @@
+package lndclient
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// TestSigNetParams makes sure the chain parameters of the default signet and
+// of custom signets are derived correctly.
+func TestSigNetParams(t *testing.T) {
+	params, err := NetworkSignet.ChainParams()
+	if err != nil {
+		t.Fatalf("unable to get signet params: %v", err)
+	}
+
+	const genesisHash = "00000008819873e925422c1ff0f99f7cc9bbb232af63a0" +
+		"77a480a3633bee1ef6"
+	if params.GenesisHash.String() != genesisHash {
+		t.Fatalf("unexpected genesis hash. got %v wanted %v",
+			params.GenesisHash, genesisHash)
+	}
+	if params.GenesisBlock.BlockHash() != *params.GenesisHash {
+		t.Fatalf("genesis block doesn't match genesis hash")
+	}
+	if params.Net != wire.BitcoinNet(0x40cf030a) {
+		t.Fatalf("unexpected network magic %x", uint32(params.Net))
+	}
+	if len(params.DNSSeeds) != len(DefaultSigNetSeedNodes) {
+		t.Fatalf("expected default seed nodes, got %v",
+			params.DNSSeeds)
+	}
+
+	// A custom signet must share the genesis block but use a different
+	// network magic and only the seed nodes we specify.
+	custom, err := SigNetParams([]byte{0x51}, []string{"127.0.0.1"})
+	if err != nil {
+		t.Fatalf("unable to get custom signet params: %v", err)
+	}
+	if *custom.GenesisHash != *params.GenesisHash {
+		t.Fatalf("custom signet must share the genesis block")
+	}
+	if custom.Net == params.Net {
+		t.Fatalf("custom signet must use a different network magic")
+	}
+	if len(custom.DNSSeeds) != 1 || custom.DNSSeeds[0].Host != "127.0.0.1" {
+		t.Fatalf("unexpected seed nodes %v", custom.DNSSeeds)
+	}
+}
@@ -0,0 +1,410 @@
+package lndclient
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+const (
+	// defaultPeerMinBackoff is the default delay before the first
+	// reconnection attempt after a failed one.
+	defaultPeerMinBackoff = 5 * time.Second
+
+	// defaultPeerMaxBackoff is the default maximum delay between
+	// reconnection attempts.
+	defaultPeerMaxBackoff = 10 * time.Minute
+
+	// defaultPeerCheckInterval is the default interval at which offline
+	// peers are checked for reconnection.
+	defaultPeerCheckInterval = time.Second
+
+	// maxOnlinePeriods is the number of online periods that are kept per
+	// peer.
+	maxOnlinePeriods = 100
+)
+
+// OnlinePeriod is a period of time that a peer was connected to us.
+type OnlinePeriod struct {
+	// Start is the time the peer came online.
+	Start time.Time
+
+	// End is the time the peer went offline. It is zero if the peer is
+	// still online.
+	End time.Time
+}
+
+// PeerHistory holds the connection history of a peer.
+type PeerHistory struct {
+	// Online indicates whether the peer is currently connected.
+	Online bool
+
+	// Periods holds the most recent periods that the peer was online,
+	// oldest first.
+	Periods []OnlinePeriod
+
+	// FlapCount is the number of times the peer went offline.
+	FlapCount int
+
+	// LastError is the last error of a connection attempt to the peer.
+	LastError error
+
+	// LastErrorTime is the time of the last failed connection attempt.
+	LastErrorTime time.Time
+}
+
+// Uptime returns the time the peer was online between the start time and now.
+func (p *PeerHistory) Uptime(start, now time.Time) time.Duration {
+	var uptime time.Duration
+	for _, period := range p.Periods {
+		begin, end := period.Start, period.End
+		if end.IsZero() || end.After(now) {
+			end = now
+		}
+		if begin.Before(start) {
+			begin = start
+		}
+
+		if end.After(begin) {
+			uptime += end.Sub(begin)
+		}
+	}
+
+	return uptime
+}
+
+// copy returns a deep copy of the history.
+func (p *PeerHistory) copy() *PeerHistory {
+	cp := *p
+	cp.Periods = append([]OnlinePeriod(nil), p.Periods...)
+
+	return &cp
+}
+
+// desiredPeer is a peer that the manager keeps connected.
+type desiredPeer struct {
+	hosts       []string
+	nextHost    int
+	backoff     time.Duration
+	nextAttempt time.Time
+
+	// connecting is set while a connection attempt is in flight.
+	connecting bool
+}
+
+// PeerManagerConfig holds the configuration of a peer manager.
+type PeerManagerConfig struct {
+	// Lnd is the client that peers are connected through.
+	Lnd LightningClient
+
+	// MinBackoff is the delay before the first reconnection attempt after
+	// a failed one. It doubles after every failure, up to MaxBackoff. If
+	// zero, five seconds are used.
+	MinBackoff time.Duration
+
+	// MaxBackoff is the maximum delay between reconnection attempts. If
+	// zero, ten minutes are used.
+	MaxBackoff time.Duration
+
+	// CheckInterval is the interval at which offline peers are checked
+	// for reconnection. If zero, one second is used.
+	CheckInterval time.Duration
+}
+
+// PeerManager keeps a set of desired peers connected and records the online
+// history of all peers it sees. Connection attempts rotate through the hosts
+// of a peer and back off exponentially while they fail.
+type PeerManager struct {
+	cfg PeerManagerConfig
+
+	desired map[route.Vertex]*desiredPeer
+	history map[route.Vertex]*PeerHistory
+	mtx     sync.Mutex
+
+	// wg tracks the connection attempts in flight.
+	wg sync.WaitGroup
+}
+
+// NewPeerManager creates a new peer manager.
+func NewPeerManager(cfg PeerManagerConfig) *PeerManager {
+	if cfg.MinBackoff == 0 {
+		cfg.MinBackoff = defaultPeerMinBackoff
+	}
+	if cfg.MaxBackoff == 0 {
+		cfg.MaxBackoff = defaultPeerMaxBackoff
+	}
+	if cfg.CheckInterval == 0 {
+		cfg.CheckInterval = defaultPeerCheckInterval
+	}
+
+	return &PeerManager{
+		cfg:     cfg,
+		desired: make(map[route.Vertex]*desiredPeer),
+		history: make(map[route.Vertex]*PeerHistory),
+	}
+}
+
+// AddPeer adds a peer to the set of peers that are kept connected. The hosts
+// are tried in turn. Adding a peer that was already added replaces its hosts.
+func (m *PeerManager) AddPeer(peer route.Vertex, hosts []string) error {
+	if len(hosts) == 0 {
+		return errors.New("at least one host required")
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.desired[peer] = &desiredPeer{
+		hosts: append([]string(nil), hosts...),
+	}
+
+	return nil
+}
+
+// RemovePeer removes a peer from the set of peers that are kept connected. It
+// doesn't disconnect the peer.
+func (m *PeerManager) RemovePeer(peer route.Vertex) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	delete(m.desired, peer)
+}
+
+// History returns the connection history of a peer, if the manager has seen
+// it.
+func (m *PeerManager) History(peer route.Vertex) (*PeerHistory, bool) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	history, ok := m.history[peer]
+	if !ok {
+		return nil, false
+	}
+
+	return history.copy(), true
+}
+
+// Peers returns the peers we are currently connected to, with their flap
+// counts filled in from the history of the manager.
+func (m *PeerManager) Peers(ctx context.Context) ([]Peer, error) {
+	peers, err := m.cfg.Lnd.ListPeers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	for i := range peers {
+		if history, ok := m.history[peers[i].Pubkey]; ok {
+			peers[i].FlapCount = history.FlapCount
+		}
+	}
+
+	return peers, nil
+}
+
+// Run keeps the desired peers connected until the context is cancelled or the
+// peer event stream fails. It may be called again after it returned.
+func (m *PeerManager) Run(ctx context.Context) error {
+	events, errChan, err := m.cfg.Lnd.SubscribePeerEvents(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Peers that are connected already won't send an online event, so we
+	// mark them online after subscribing to avoid missing any events.
+	peers, err := m.cfg.Lnd.ListPeers(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Peers that went offline while we weren't running are marked
+	// offline.
+	now := time.Now()
+	connected := make(map[route.Vertex]bool, len(peers))
+
+	m.mtx.Lock()
+	for _, peer := range peers {
+		connected[peer.Pubkey] = true
+		m.setOnline(peer.Pubkey, now)
+	}
+	for peer := range m.history {
+		if !connected[peer] {
+			m.setOffline(peer, now)
+		}
+	}
+	m.mtx.Unlock()
+
+	ticker := time.NewTicker(m.cfg.CheckInterval)
+	defer ticker.Stop()
+
+	// Connection attempts run in the background, so we wait for them
+	// before returning.
+	defer m.wg.Wait()
+
+	for {
+		m.connectPeers(ctx)
+
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return errors.New("peer event stream closed")
+			}
+
+			m.mtx.Lock()
+			if event.Type == PeerOnline {
+				m.setOnline(event.Pubkey, event.Timestamp)
+			} else {
+				m.setOffline(event.Pubkey, event.Timestamp)
+			}
+			m.mtx.Unlock()
+
+		case err := <-errChan:
+			return err
+
+		case <-ticker.C:
+
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// peerHistory returns the history of a peer, creating it if needed. The mutex
+// must be held.
+func (m *PeerManager) peerHistory(peer route.Vertex) *PeerHistory {
+	history, ok := m.history[peer]
+	if !ok {
+		history = &PeerHistory{}
+		m.history[peer] = history
+	}
+
+	return history
+}
+
+// setOnline records that a peer came online. The mutex must be held.
+func (m *PeerManager) setOnline(peer route.Vertex, timestamp time.Time) {
+	history := m.peerHistory(peer)
+	if history.Online {
+		return
+	}
+
+	history.Online = true
+	history.Periods = append(history.Periods, OnlinePeriod{
+		Start: timestamp,
+	})
+	if len(history.Periods) > maxOnlinePeriods {
+		history.Periods = history.Periods[1:]
+	}
+
+	// Reset the backoff of a desired peer, so that it is reconnected
+	// quickly if it goes offline again.
+	if desired, ok := m.desired[peer]; ok {
+		desired.backoff = 0
+		desired.nextAttempt = time.Time{}
+	}
+}
+
+// setOffline records that a peer went offline. The mutex must be held.
+func (m *PeerManager) setOffline(peer route.Vertex, timestamp time.Time) {
+	history := m.peerHistory(peer)
+	if !history.Online {
+		return
+	}
+
+	history.Online = false
+	history.FlapCount++
+	history.Periods[len(history.Periods)-1].End = timestamp
+
+	log.Debugf("Peer %v went offline, flap count %v", peer,
+		history.FlapCount)
+}
+
+// connectPeers starts a connection attempt to every desired peer that is
+// offline and due for one. Attempts run in the background, so that a slow
+// attempt doesn't hold up peer events.
+func (m *PeerManager) connectPeers(ctx context.Context) {
+	now := time.Now()
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	for peer, desired := range m.desired {
+		online := m.peerHistory(peer).Online
+		if online || desired.connecting ||
+			now.Before(desired.nextAttempt) {
+
+			continue
+		}
+
+		host := desired.hosts[desired.nextHost%len(desired.hosts)]
+		desired.nextHost++
+		desired.connecting = true
+
+		m.wg.Add(1)
+		go m.connect(ctx, peer, desired, host)
+	}
+}
+
+// connect tries to connect to a desired peer at the host provided and records
+// the result.
+func (m *PeerManager) connect(ctx context.Context, peer route.Vertex,
+	desired *desiredPeer, host string) {
+
+	defer m.wg.Done()
+
+	err := m.cfg.Lnd.Connect(ctx, peer, host, false)
+
+	// lnd fails if we are connected already, which happens if the peer
+	// connected before we received its online event.
+	if err != nil && strings.Contains(err.Error(), "already connected") {
+		err = nil
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	desired.connecting = false
+	m.connectResult(peer, host, err)
+}
+
+// connectResult records the result of a connection attempt. A successful
+// attempt is only marked online by the peer event that follows it. The mutex
+// must be held.
+func (m *PeerManager) connectResult(peer route.Vertex, host string,
+	err error) {
+
+	desired, ok := m.desired[peer]
+	if !ok {
+		return
+	}
+
+	if err == nil {
+		// Give the online event some time to arrive before trying
+		// again.
+		desired.nextAttempt = time.Now().Add(m.cfg.MinBackoff)
+		return
+	}
+
+	history := m.peerHistory(peer)
+	history.LastError = err
+	history.LastErrorTime = time.Now()
+
+	if desired.backoff == 0 {
+		desired.backoff = m.cfg.MinBackoff
+	} else {
+		desired.backoff *= 2
+	}
+	if desired.backoff > m.cfg.MaxBackoff {
+		desired.backoff = m.cfg.MaxBackoff
+	}
+	desired.nextAttempt = time.Now().Add(desired.backoff)
+
+	log.Warnf("Unable to connect to peer %v at %v, retrying in %v: %v",
+		peer, host, desired.backoff, err)
+}
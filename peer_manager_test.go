@@ -0,0 +1,192 @@
+package lndclient
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// mockPeerLightning is a lightning client that fails connections to the first
+// host and emits an online event for successful connections.
+type mockPeerLightning struct {
+	LightningClient
+
+	peers  []Peer
+	events chan *PeerEvent
+
+	mtx      sync.Mutex
+	attempts []string
+}
+
+func (m *mockPeerLightning) ListPeers(_ context.Context) ([]Peer, error) {
+	return m.peers, nil
+}
+
+func (m *mockPeerLightning) SubscribePeerEvents(_ context.Context) (
+	<-chan *PeerEvent, <-chan error, error) {
+
+	return m.events, make(chan error), nil
+}
+
+func (m *mockPeerLightning) Connect(_ context.Context, peer route.Vertex,
+	host string, _ bool) error {
+
+	m.mtx.Lock()
+	m.attempts = append(m.attempts, host)
+	m.mtx.Unlock()
+
+	if host == "bad:9735" {
+		return errors.New("connection refused")
+	}
+
+	go func() {
+		m.events <- &PeerEvent{
+			Pubkey:    peer,
+			Type:      PeerOnline,
+			Timestamp: time.Now(),
+		}
+	}()
+
+	return nil
+}
+
+// TestPeerManager tests that desired peers are reconnected through their
+// hosts in turn and that their online history is recorded.
+func TestPeerManager(t *testing.T) {
+	connected := route.Vertex{1}
+	desired := route.Vertex{2}
+
+	lnd := &mockPeerLightning{
+		peers:  []Peer{{Pubkey: connected}},
+		events: make(chan *PeerEvent, 10),
+	}
+	manager := NewPeerManager(PeerManagerConfig{
+		Lnd:           lnd,
+		MinBackoff:    time.Millisecond,
+		CheckInterval: time.Millisecond,
+	})
+
+	err := manager.AddPeer(desired, []string{"bad:9735", "good:9735"})
+	if err != nil {
+		t.Fatalf("unable to add peer: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- manager.Run(ctx)
+	}()
+
+	// The connected peer flaps once.
+	lnd.events <- &PeerEvent{
+		Pubkey: connected, Type: PeerOffline, Timestamp: time.Now(),
+	}
+	lnd.events <- &PeerEvent{
+		Pubkey: connected, Type: PeerOnline, Timestamp: time.Now(),
+	}
+
+	// Wait for the desired peer to come online.
+	deadline := time.After(5 * time.Second)
+	for {
+		history, ok := manager.History(desired)
+		if ok && history.Online {
+			if history.LastError == nil {
+				t.Fatalf("expected failed first attempt")
+			}
+			break
+		}
+
+		select {
+		case <-deadline:
+			t.Fatalf("desired peer not connected")
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	lnd.mtx.Lock()
+	attempts := lnd.attempts
+	lnd.mtx.Unlock()
+	if len(attempts) < 2 || attempts[0] != "bad:9735" ||
+		attempts[1] != "good:9735" {
+
+		t.Fatalf("unexpected connection attempts: %v", attempts)
+	}
+
+	history, _ := manager.History(connected)
+	if !history.Online || history.FlapCount != 1 ||
+		len(history.Periods) != 2 || history.Periods[0].End.IsZero() {
+
+		t.Fatalf("unexpected history: %+v", history)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// mockSlowPeerLightning is a lightning client whose connection attempts block
+// until they are cancelled.
+type mockSlowPeerLightning struct {
+	*mockPeerLightning
+}
+
+func (m *mockSlowPeerLightning) Connect(ctx context.Context, _ route.Vertex,
+	_ string, _ bool) error {
+
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// TestPeerManagerSlowConnect tests that peer events are recorded while a
+// connection attempt is in flight.
+func TestPeerManagerSlowConnect(t *testing.T) {
+	peer := route.Vertex{1}
+
+	lnd := &mockSlowPeerLightning{
+		mockPeerLightning: &mockPeerLightning{
+			events: make(chan *PeerEvent),
+		},
+	}
+	manager := NewPeerManager(PeerManagerConfig{
+		Lnd:           lnd,
+		CheckInterval: time.Millisecond,
+	})
+
+	err := manager.AddPeer(route.Vertex{2}, []string{"slow:9735"})
+	if err != nil {
+		t.Fatalf("unable to add peer: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- manager.Run(ctx)
+	}()
+
+	select {
+	case lnd.events <- &PeerEvent{
+		Pubkey: peer, Type: PeerOnline, Timestamp: time.Now(),
+	}:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("peer event not received")
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	history, ok := manager.History(peer)
+	if !ok || !history.Online {
+		t.Fatalf("unexpected history: %+v", history)
+	}
+}
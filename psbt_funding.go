@@ -0,0 +1,720 @@
+package lndclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/psbt"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/verrpc"
+)
+
+const (
+	// defaultPsbtFundingTimeout is the default time that a PSBT funding
+	// flow may take before we cancel the funding shim. The remote peer
+	// usually gives up on the open after 10 minutes, so we don't wait any
+	// longer than that.
+	defaultPsbtFundingTimeout = 10 * time.Minute
+)
+
+// PsbtShim describes a channel open that is funded with a PSBT that is signed
+// outside of lnd's wallet.
+type PsbtShim struct {
+	// PendingChanID is the pending channel ID that identifies the channel
+	// open in all subsequent funding steps. If it is zero, a random ID is
+	// generated by OpenChannelPsbt.
+	PendingChanID [32]byte
+
+	// BasePsbt is an optional PSBT that the funding output is added to.
+	BasePsbt *psbt.Packet
+
+	// NoPublish instructs lnd not to publish the funding transaction once
+	// it is finalized. This is used to fund multiple channels with a
+	// single transaction, where only the last channel open publishes it.
+	NoPublish bool
+}
+
+// marshall converts a PSBT shim into its rpc counterpart.
+func (p *PsbtShim) marshall() (*lnrpc.FundingShim, error) {
+	if p.PendingChanID == ([32]byte{}) {
+		return nil, errors.New("pending channel id required for psbt " +
+			"shim")
+	}
+
+	var basePsbt []byte
+	if p.BasePsbt != nil {
+		var err error
+		basePsbt, err = serializePsbt(p.BasePsbt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &lnrpc.FundingShim{
+		Shim: &lnrpc.FundingShim_PsbtShim{
+			PsbtShim: &lnrpc.PsbtShim{
+				PendingChanId: p.PendingChanID[:],
+				BasePsbt:      basePsbt,
+				NoPublish:     p.NoPublish,
+			},
+		},
+	}, nil
+}
+
+// PsbtFundingUpdate indicates that lnd is ready for the channel to be funded
+// with a PSBT. It holds the funding output that the PSBT must pay to.
+type PsbtFundingUpdate struct {
+	// PendingChanID is the pending channel ID of the channel open.
+	PendingChanID [32]byte
+
+	// FundingAddress is the address of the funding output.
+	FundingAddress btcutil.Address
+
+	// FundingAmount is the exact amount the funding output must have.
+	FundingAmount btcutil.Amount
+
+	// Psbt is the PSBT template that contains the funding output and the
+	// inputs and outputs of the base PSBT, if one was provided.
+	Psbt *psbt.Packet
+}
+
+// PendingChannelID returns the pending channel ID of the channel open.
+func (p *PsbtFundingUpdate) PendingChannelID() [32]byte {
+	return p.PendingChanID
+}
+
+// PsbtFunder funds and signs the PSBT of a channel open. It is implemented by
+// external wallets, for example multisig signers or cold storage.
+type PsbtFunder interface {
+	// FundPsbt adds inputs and, if required, change outputs to the
+	// template, which already contains the funding outputs. It must not
+	// alter the funding outputs.
+	FundPsbt(ctx context.Context, template *psbt.Packet) (*psbt.Packet,
+		error)
+
+	// SignPsbt signs all inputs of the funded PSBT, after lnd verified
+	// that it pays to the funding outputs.
+	SignPsbt(ctx context.Context, funded *psbt.Packet) (*psbt.Packet,
+		error)
+}
+
+// NewPendingChanID returns a random pending channel ID.
+func NewPendingChanID() ([32]byte, error) {
+	var pendingChanID [32]byte
+	if _, err := rand.Read(pendingChanID[:]); err != nil {
+		return pendingChanID, err
+	}
+
+	return pendingChanID, nil
+}
+
+// serializePsbt returns the binary serialization of a PSBT.
+func serializePsbt(packet *psbt.Packet) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := packet.Serialize(&buf); err != nil {
+		return nil, fmt.Errorf("unable to serialize psbt: %v", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// PsbtVerify passes the funded PSBT of a channel open to lnd, which verifies
+// that it pays the exact amount to the funding output. Only after that, the
+// PSBT may be signed.
+func (s *lightningClient) PsbtVerify(ctx context.Context,
+	pendingChanID [32]byte, funded *psbt.Packet) error {
+
+	fundedPsbt, err := serializePsbt(funded)
+	if err != nil {
+		return err
+	}
+
+	return s.fundingStateStep(ctx, &lnrpc.FundingTransitionMsg{
+		Trigger: &lnrpc.FundingTransitionMsg_PsbtVerify{
+			PsbtVerify: &lnrpc.FundingPsbtVerify{
+				PendingChanId: pendingChanID[:],
+				FundedPsbt:    fundedPsbt,
+			},
+		},
+	})
+}
+
+// PsbtFinalize passes the signed PSBT of a channel open to lnd, which
+// finalizes it and continues the funding flow with the peer.
+func (s *lightningClient) PsbtFinalize(ctx context.Context,
+	pendingChanID [32]byte, signed *psbt.Packet) error {
+
+	signedPsbt, err := serializePsbt(signed)
+	if err != nil {
+		return err
+	}
+
+	return s.fundingStateStep(ctx, &lnrpc.FundingTransitionMsg{
+		Trigger: &lnrpc.FundingTransitionMsg_PsbtFinalize{
+			PsbtFinalize: &lnrpc.FundingPsbtFinalize{
+				PendingChanId: pendingChanID[:],
+				SignedPsbt:    signedPsbt,
+			},
+		},
+	})
+}
+
+// FundingShimCancel cancels the funding shim of a pending channel open, which
+// aborts the open.
+func (s *lightningClient) FundingShimCancel(ctx context.Context,
+	pendingChanID [32]byte) error {
+
+	return s.fundingStateStep(ctx, &lnrpc.FundingTransitionMsg{
+		Trigger: &lnrpc.FundingTransitionMsg_ShimCancel{
+			ShimCancel: &lnrpc.FundingShimCancel{
+				PendingChanId: pendingChanID[:],
+			},
+		},
+	})
+}
+
+// fundingStateStep advances the funding flow of a pending channel open.
+func (s *lightningClient) fundingStateStep(ctx context.Context,
+	msg *lnrpc.FundingTransitionMsg) error {
+
+	rpcCtx, cancel := context.WithTimeout(ctx, rpcTimeout)
+	defer cancel()
+
+	rpcCtx = s.adminMac.WithMacaroonAuth(rpcCtx)
+	_, err := s.client.FundingStateStep(rpcCtx, msg)
+	return err
+}
+
+// OpenChannelPsbt opens a channel that is funded by a PSBT, which is funded and
+// signed by the funder provided. It drives the complete funding flow: it waits
+// for lnd to provide the PSBT template, lets the funder add inputs, has lnd
+// verify the funded PSBT, lets the funder sign it and finally passes it to lnd
+// to be finalized. If the flow fails or doesn't complete within the timeout
+// provided, the funding shim is cancelled. A zero timeout defaults to 10
+// minutes. The funding outpoint is returned once the funding transaction was
+// finalized.
+func (s *lightningClient) OpenChannelPsbt(ctx context.Context,
+	req *OpenChannelRequest, funder PsbtFunder,
+	timeout time.Duration) (*wire.OutPoint, error) {
+
+	if timeout == 0 {
+		timeout = defaultPsbtFundingTimeout
+	}
+
+	// Copy the request so that we can add the PSBT shim without modifying
+	// the caller's request.
+	openReq := *req
+	var shim PsbtShim
+	if req.PsbtShim != nil {
+		shim = *req.PsbtShim
+	}
+	if shim.PendingChanID == ([32]byte{}) {
+		var err error
+		shim.PendingChanID, err = NewPendingChanID()
+		if err != nil {
+			return nil, err
+		}
+	}
+	openReq.PsbtShim = &shim
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	updates, errChan, err := s.OpenChannelStream(ctx, &openReq)
+	if err != nil {
+		return nil, err
+	}
+
+	update, err := nextOpenUpdate(ctx, updates, errChan)
+	if err != nil {
+		return nil, s.cancelShim(shim.PendingChanID, err)
+	}
+
+	ready, ok := update.(*PsbtFundingUpdate)
+	if !ok {
+		return nil, s.cancelShim(shim.PendingChanID, fmt.Errorf(
+			"expected psbt funding update, got %T", update,
+		))
+	}
+
+	funded, err := funder.FundPsbt(ctx, ready.Psbt)
+	if err != nil {
+		return nil, s.cancelShim(
+			shim.PendingChanID, fmt.Errorf("fund psbt: %v", err),
+		)
+	}
+
+	err = s.PsbtVerify(ctx, shim.PendingChanID, funded)
+	if err != nil {
+		return nil, s.cancelShim(
+			shim.PendingChanID, fmt.Errorf("verify psbt: %v", err),
+		)
+	}
+
+	signed, err := funder.SignPsbt(ctx, funded)
+	if err != nil {
+		return nil, s.cancelShim(
+			shim.PendingChanID, fmt.Errorf("sign psbt: %v", err),
+		)
+	}
+
+	err = s.PsbtFinalize(ctx, shim.PendingChanID, signed)
+	if err != nil {
+		return nil, s.cancelShim(
+			shim.PendingChanID, fmt.Errorf("finalize psbt: %v",
+				err),
+		)
+	}
+
+	// Once the PSBT is finalized, lnd continues the funding flow with the
+	// peer on its own, so we don't cancel the shim anymore.
+	update, err = nextOpenUpdate(ctx, updates, errChan)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, ok := update.(*PendingOpenUpdate)
+	if !ok {
+		return nil, fmt.Errorf("expected pending open update, got %T",
+			update)
+	}
+
+	return &pending.ChannelPoint, nil
+}
+
+// cancelShim cancels the funding shim of a failed PSBT funding flow and
+// returns the error that caused the failure. We use a fresh context, because
+// the flow may have failed due to its context timing out.
+func (s *lightningClient) cancelShim(pendingChanID [32]byte,
+	cause error) error {
+
+	err := s.FundingShimCancel(context.Background(), pendingChanID)
+	if err != nil {
+		log.Warnf("Unable to cancel funding shim %x: %v",
+			pendingChanID, err)
+	}
+
+	return cause
+}
+
+// nextOpenUpdate waits for the next update of a channel open stream.
+func nextOpenUpdate(ctx context.Context, updates chan OpenStatusUpdate,
+	errChan chan error) (OpenStatusUpdate, error) {
+
+	select {
+	case update, ok := <-updates:
+		if !ok {
+			return nil, errors.New("channel open stream ended")
+		}
+		return update, nil
+
+	case err, ok := <-errChan:
+		if !ok {
+			return nil, errors.New("channel open stream ended")
+		}
+		return nil, err
+
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// BatchChannel is a channel that was opened as part of a batch.
+type BatchChannel struct {
+	// PendingChanID is the pending channel ID of the channel open.
+	PendingChanID [32]byte
+
+	// ChannelPoint is the funding outpoint of the channel.
+	ChannelPoint wire.OutPoint
+
+	// Updates receives the remaining updates of the channel open, which
+	// ends with a ChannelOpenedUpdate once the funding transaction has
+	// confirmed. It is closed when lnd ends the update stream.
+	Updates chan OpenStatusUpdate
+
+	// Errors receives an error if the update stream fails.
+	Errors chan error
+}
+
+// BatchOpenChannel opens channels to several peers with a single funding
+// transaction. Every channel is opened with its own options and the batch is
+// all-or-nothing. A zero timeout defaults to 10 minutes.
+//
+// If the connected lnd offers the BatchOpenChannel rpc (v0.14.0 or later) and
+// the options of the batch can be passed to it, lnd opens the channels on its
+// own and funds the transaction from its wallet. The rpc takes the funding
+// options FeeRate, TargetConf, MinConfs and SpendUnconfirmed once for the
+// whole batch, so they must be identical for all channels, and it doesn't take
+// MaxLocalCsv and RemoteMaxValueInFlight. Otherwise, the batch is funded with
+// a PSBT that is funded and signed by the funder provided, which may be nil if
+// the native rpc is known to be used.
+func (s *lightningClient) BatchOpenChannel(ctx context.Context,
+	reqs []*OpenChannelRequest, funder PsbtFunder,
+	timeout time.Duration) ([]*BatchChannel, error) {
+
+	if len(reqs) == 0 {
+		return nil, errors.New("no channels to open")
+	}
+
+	if timeout == 0 {
+		timeout = defaultPsbtFundingTimeout
+	}
+
+	batchReq, err := marshallBatchOpenRequest(reqs, s.version)
+	if err == nil {
+		return s.batchOpenNative(ctx, batchReq, timeout)
+	}
+	if funder == nil {
+		return nil, fmt.Errorf("psbt funder required, native batch "+
+			"open not possible: %v", err)
+	}
+
+	log.Debugf("Funding channel batch with a psbt, native batch open "+
+		"not possible: %v", err)
+
+	return s.batchOpenPsbt(ctx, reqs, funder, timeout)
+}
+
+// batchOpenVersion is the first lnd version that offers the BatchOpenChannel
+// rpc.
+var batchOpenVersion = &verrpc.Version{AppMinor: 14}
+
+// marshallBatchOpenRequest converts the open requests of a batch into a
+// request of lnd's BatchOpenChannel rpc. An error is returned if the connected
+// lnd doesn't offer the rpc or the options of the batch can't be passed to it.
+func marshallBatchOpenRequest(reqs []*OpenChannelRequest,
+	version *verrpc.Version) (*lnrpc.BatchOpenChannelRequest, error) {
+
+	err := checkVersion(version, "BatchOpenChannel", batchOpenVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	first := reqs[0]
+	batchReq := &lnrpc.BatchOpenChannelRequest{
+		Channels: make([]*lnrpc.BatchOpenChannel, 0, len(reqs)),
+	}
+	for i, req := range reqs {
+		if req.PsbtShim != nil {
+			return nil, fmt.Errorf("channel %v: psbt shim not "+
+				"allowed in batch", i)
+		}
+
+		if req.MaxLocalCsv != 0 || req.RemoteMaxValueInFlight != 0 {
+			return nil, fmt.Errorf("channel %v: max local csv and "+
+				"remote max value in flight not supported by "+
+				"BatchOpenChannel", i)
+		}
+
+		if req.FeeRate != first.FeeRate ||
+			req.TargetConf != first.TargetConf ||
+			req.MinConfs != first.MinConfs ||
+			req.SpendUnconfirmed != first.SpendUnconfirmed {
+
+			return nil, fmt.Errorf("channel %v: funding options "+
+				"differ within batch", i)
+		}
+
+		rpcReq, err := marshallOpenChannelRequest(req, version)
+		if err != nil {
+			return nil, fmt.Errorf("channel %v: %v", i, err)
+		}
+
+		pendingChanID, err := NewPendingChanID()
+		if err != nil {
+			return nil, err
+		}
+
+		batchReq.Channels = append(
+			batchReq.Channels, &lnrpc.BatchOpenChannel{
+				NodePubkey:         rpcReq.NodePubkey,
+				LocalFundingAmount: rpcReq.LocalFundingAmount,
+				PushSat:            rpcReq.PushSat,
+				Private:            rpcReq.Private,
+				MinHtlcMsat:        rpcReq.MinHtlcMsat,
+				RemoteCsvDelay:     rpcReq.RemoteCsvDelay,
+				CloseAddress:       rpcReq.CloseAddress,
+				PendingChanId:      pendingChanID[:],
+				CommitmentType:     rpcReq.CommitmentType,
+			},
+		)
+
+		// The funding options are identical for all channels, so we
+		// take them from the first one.
+		if i == 0 {
+			batchReq.TargetConf = rpcReq.TargetConf
+			batchReq.SatPerVbyte = rpcReq.SatPerByte
+			batchReq.MinConfs = rpcReq.MinConfs
+			batchReq.SpendUnconfirmed = rpcReq.SpendUnconfirmed
+		}
+	}
+
+	return batchReq, nil
+}
+
+// batchOpenNative opens a batch of channels with lnd's BatchOpenChannel rpc.
+// lnd only reports the funding outpoints of the channels, so we follow the
+// channel events to send an update once a channel is open.
+func (s *lightningClient) batchOpenNative(ctx context.Context,
+	req *lnrpc.BatchOpenChannelRequest,
+	timeout time.Duration) ([]*BatchChannel, error) {
+
+	// We subscribe to the channel events before opening the channels so
+	// that we don't miss any of them. The subscription ends once all
+	// channels are open.
+	streamCtx, cancelStream := context.WithCancel(ctx)
+	events, eventErrs, err := s.SubscribeChannelEvents(streamCtx)
+	if err != nil {
+		cancelStream()
+		return nil, err
+	}
+
+	// lnd negotiates all channels with their peers before it returns, so
+	// we allow the call to take as long as the funding flow.
+	rpcCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	rpcCtx = s.adminMac.WithMacaroonAuth(rpcCtx)
+	resp, err := s.client.BatchOpenChannel(rpcCtx, req)
+	if err != nil {
+		cancelStream()
+		return nil, err
+	}
+
+	if len(resp.PendingChannels) != len(req.Channels) {
+		cancelStream()
+		return nil, fmt.Errorf("expected %v pending channels, got %v",
+			len(req.Channels), len(resp.PendingChannels))
+	}
+
+	channels := make([]*BatchChannel, 0, len(req.Channels))
+	for i, pending := range resp.PendingChannels {
+		chanPoint, err := getOutPoint(pending.Txid, pending.OutputIndex)
+		if err != nil {
+			cancelStream()
+			return nil, err
+		}
+
+		// Every channel only receives a single update, so buffering it
+		// doesn't block the other channels of the batch.
+		channel := &BatchChannel{
+			ChannelPoint: *chanPoint,
+			Updates:      make(chan OpenStatusUpdate, 1),
+			Errors:       make(chan error, 1),
+		}
+		copy(channel.PendingChanID[:], req.Channels[i].PendingChanId)
+
+		channels = append(channels, channel)
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer cancelStream()
+
+		forwardBatchOpens(streamCtx, channels, events, eventErrs)
+	}()
+
+	return channels, nil
+}
+
+// forwardBatchOpens sends an update to the channels of a batch once lnd reports
+// them as open, which ends their update stream. If the channel event stream
+// fails, its error is sent to all channels that aren't open yet.
+func forwardBatchOpens(ctx context.Context, channels []*BatchChannel,
+	events <-chan *ChannelEventUpdate, eventErrs <-chan error) {
+
+	pending := make(map[wire.OutPoint]*BatchChannel, len(channels))
+	for _, channel := range channels {
+		pending[channel.ChannelPoint] = channel
+	}
+
+	for len(pending) > 0 {
+		select {
+		case event := <-events:
+			if event.UpdateType != OpenChannelUpdate {
+				continue
+			}
+
+			chanPoint, err := NewOutpointFromStr(
+				event.OpenedChannelInfo.ChannelPoint,
+			)
+			if err != nil {
+				log.Errorf("Invalid channel point: %v", err)
+				continue
+			}
+
+			channel, ok := pending[*chanPoint]
+			if !ok {
+				continue
+			}
+
+			channel.Updates <- &ChannelOpenedUpdate{
+				PendingChanID: channel.PendingChanID,
+				ChannelPoint:  channel.ChannelPoint,
+			}
+			close(channel.Updates)
+			delete(pending, *chanPoint)
+
+		case err := <-eventErrs:
+			for _, channel := range pending {
+				channel.Errors <- err
+			}
+			return
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// batchOpenPsbt opens a batch of channels that is funded and signed by the
+// funder provided. The funding flow of all channels is driven in lock step:
+// the PSBT template that the funder receives contains the funding outputs of
+// all channels and the signed PSBT is finalized for every channel, with only
+// the last channel publishing the transaction. If any channel fails before the
+// PSBT is finalized, the funding shims of all channels are cancelled.
+func (s *lightningClient) batchOpenPsbt(ctx context.Context,
+	reqs []*OpenChannelRequest, funder PsbtFunder,
+	timeout time.Duration) ([]*BatchChannel, error) {
+
+	fundingCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// The update streams of the channels outlive the funding flow, so
+	// they are only cancelled if the batch fails.
+	streamCtx, cancelStreams := context.WithCancel(ctx)
+
+	var (
+		channels = make([]*BatchChannel, 0, len(reqs))
+		outputs  = make([]*wire.TxOut, 0, len(reqs))
+	)
+
+	// fail cancels the funding shims and update streams of all channels
+	// of the batch and returns the error provided.
+	fail := func(err error) ([]*BatchChannel, error) {
+		for _, channel := range channels {
+			_ = s.cancelShim(channel.PendingChanID, nil)
+		}
+		cancelStreams()
+
+		return nil, err
+	}
+
+	for i, req := range reqs {
+		if req.PsbtShim != nil {
+			return fail(fmt.Errorf("channel %v: psbt shim not "+
+				"allowed in batch", i))
+		}
+
+		pendingChanID, err := NewPendingChanID()
+		if err != nil {
+			return fail(err)
+		}
+
+		// Only the last channel of the batch publishes the funding
+		// transaction, once all channels are finalized.
+		openReq := *req
+		openReq.PsbtShim = &PsbtShim{
+			PendingChanID: pendingChanID,
+			NoPublish:     i < len(reqs)-1,
+		}
+
+		updates, errChan, err := s.OpenChannelStream(
+			streamCtx, &openReq,
+		)
+		if err != nil {
+			return fail(fmt.Errorf("channel %v: %v", i, err))
+		}
+
+		channels = append(channels, &BatchChannel{
+			PendingChanID: pendingChanID,
+			Updates:       updates,
+			Errors:        errChan,
+		})
+
+		update, err := nextOpenUpdate(fundingCtx, updates, errChan)
+		if err != nil {
+			return fail(fmt.Errorf("channel %v: %v", i, err))
+		}
+
+		ready, ok := update.(*PsbtFundingUpdate)
+		if !ok {
+			return fail(fmt.Errorf("channel %v: expected psbt "+
+				"funding update, got %T", i, update))
+		}
+
+		pkScript, err := txscript.PayToAddrScript(ready.FundingAddress)
+		if err != nil {
+			return fail(fmt.Errorf("channel %v: %v", i, err))
+		}
+
+		outputs = append(outputs, &wire.TxOut{
+			Value:    int64(ready.FundingAmount),
+			PkScript: pkScript,
+		})
+	}
+
+	template, err := psbt.New(nil, outputs, 2, 0, nil)
+	if err != nil {
+		return fail(fmt.Errorf("unable to create psbt template: %v",
+			err))
+	}
+
+	funded, err := funder.FundPsbt(fundingCtx, template)
+	if err != nil {
+		return fail(fmt.Errorf("fund psbt: %v", err))
+	}
+
+	for i, channel := range channels {
+		err := s.PsbtVerify(fundingCtx, channel.PendingChanID, funded)
+		if err != nil {
+			return fail(fmt.Errorf("channel %v: verify psbt: %v",
+				i, err))
+		}
+	}
+
+	signed, err := funder.SignPsbt(fundingCtx, funded)
+	if err != nil {
+		return fail(fmt.Errorf("sign psbt: %v", err))
+	}
+
+	for i, channel := range channels {
+		err := s.PsbtFinalize(fundingCtx, channel.PendingChanID, signed)
+		if err != nil {
+			return fail(fmt.Errorf("channel %v: finalize psbt: %v",
+				i, err))
+		}
+	}
+
+	// All channels are finalized, so lnd continues the funding flows on
+	// its own. We only wait for the pending updates that report the
+	// funding outpoints.
+	for i, channel := range channels {
+		update, err := nextOpenUpdate(
+			fundingCtx, channel.Updates, channel.Errors,
+		)
+		if err != nil {
+			cancelStreams()
+			return nil, fmt.Errorf("channel %v: %v", i, err)
+		}
+
+		pending, ok := update.(*PendingOpenUpdate)
+		if !ok {
+			cancelStreams()
+			return nil, fmt.Errorf("channel %v: expected pending "+
+				"open update, got %T", i, update)
+		}
+
+		channel.ChannelPoint = pending.ChannelPoint
+	}
+
+	return channels, nil
+}
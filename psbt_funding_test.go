@@ -0,0 +1,475 @@
+package lndclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/psbt"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/verrpc"
+	"google.golang.org/grpc"
+)
+
+// mockFundingStepLightning is an open channel mock that also records the
+// funding state steps it receives.
+type mockFundingStepLightning struct {
+	mockOpenLightning
+
+	steps []*lnrpc.FundingTransitionMsg
+}
+
+func (m *mockFundingStepLightning) FundingStateStep(_ context.Context,
+	msg *lnrpc.FundingTransitionMsg, _ ...grpc.CallOption) (
+	*lnrpc.FundingStateStepResp, error) {
+
+	m.steps = append(m.steps, msg)
+	return &lnrpc.FundingStateStepResp{}, nil
+}
+
+// mockPsbtFunder is a funder that adds a single input to the template and
+// optionally fails signing.
+type mockPsbtFunder struct {
+	template *psbt.Packet
+	signErr  error
+}
+
+func (m *mockPsbtFunder) FundPsbt(_ context.Context,
+	template *psbt.Packet) (*psbt.Packet, error) {
+
+	m.template = template
+	template.UnsignedTx.AddTxIn(&wire.TxIn{})
+	template.Inputs = append(template.Inputs, psbt.PInput{})
+
+	return template, nil
+}
+
+func (m *mockPsbtFunder) SignPsbt(_ context.Context,
+	funded *psbt.Packet) (*psbt.Packet, error) {
+
+	if m.signErr != nil {
+		return nil, m.signErr
+	}
+
+	return funded, nil
+}
+
+// TestOpenChannelPsbt tests the PSBT funding flow for a successful channel
+// open and the cancellation of the funding shim if signing fails.
+func TestOpenChannelPsbt(t *testing.T) {
+	params := &chaincfg.RegressionNetParams
+	fundingAddr, err := btcutil.NewAddressWitnessScriptHash(
+		make([]byte, 32), params,
+	)
+	if err != nil {
+		t.Fatalf("unable to create address: %v", err)
+	}
+
+	template, err := psbt.New(
+		nil, []*wire.TxOut{{Value: 100000}}, 2, 0, nil,
+	)
+	if err != nil {
+		t.Fatalf("unable to create psbt: %v", err)
+	}
+	rawTemplate, err := serializePsbt(template)
+	if err != nil {
+		t.Fatalf("unable to serialize psbt: %v", err)
+	}
+
+	txid := chainhash.Hash{7}
+	newUpdates := func() []*lnrpc.OpenStatusUpdate {
+		return []*lnrpc.OpenStatusUpdate{{
+			Update: &lnrpc.OpenStatusUpdate_PsbtFund{
+				PsbtFund: &lnrpc.ReadyForPsbtFunding{
+					FundingAddress: fundingAddr.String(),
+					FundingAmount:  100000,
+					Psbt:           rawTemplate,
+				},
+			},
+		}, {
+			Update: &lnrpc.OpenStatusUpdate_ChanPending{
+				ChanPending: &lnrpc.PendingUpdate{
+					Txid: txid[:],
+				},
+			},
+		}}
+	}
+
+	tests := []struct {
+		name        string
+		signErr     error
+		expectSteps []string
+	}{{
+		name:        "success",
+		expectSteps: []string{"verify", "finalize"},
+	}, {
+		name:        "sign failure",
+		signErr:     errors.New("signer offline"),
+		expectSteps: []string{"verify", "cancel"},
+	}}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			mock := &mockFundingStepLightning{}
+			mock.stream = &mockOpenStream{updates: newUpdates()}
+			funder := &mockPsbtFunder{signErr: test.signErr}
+
+			client := newLightningClient(mock, nil, params, "")
+			chanPoint, err := client.OpenChannelPsbt(
+				context.Background(), &OpenChannelRequest{
+					LocalAmt: 100000,
+				}, funder, 0,
+			)
+
+			if test.signErr != nil {
+				if err == nil {
+					t.Fatalf("expected error")
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if chanPoint.Hash != txid {
+					t.Fatalf("unexpected channel point: %v",
+						chanPoint)
+				}
+			}
+
+			shim := mock.req.FundingShim.GetPsbtShim()
+			if shim == nil || len(shim.PendingChanId) != 32 {
+				t.Fatalf("expected psbt shim, got %v",
+					mock.req.FundingShim)
+			}
+			if len(funder.template.UnsignedTx.TxOut) != 1 {
+				t.Fatalf("expected funding output in template")
+			}
+
+			var steps []string
+			for _, step := range mock.steps {
+				switch {
+				case step.GetPsbtVerify() != nil:
+					steps = append(steps, "verify")
+				case step.GetPsbtFinalize() != nil:
+					steps = append(steps, "finalize")
+				case step.GetShimCancel() != nil:
+					steps = append(steps, "cancel")
+				}
+			}
+			if len(steps) != len(test.expectSteps) {
+				t.Fatalf("expected steps %v, got %v",
+					test.expectSteps, steps)
+			}
+			for i := range steps {
+				if steps[i] != test.expectSteps[i] {
+					t.Fatalf("expected steps %v, got %v",
+						test.expectSteps, steps)
+				}
+			}
+		})
+	}
+}
+
+// mockBatchLightning is a lightning client that returns a separate open
+// stream for every channel open and records the funding state steps.
+type mockBatchLightning struct {
+	mockFundingStepLightning
+
+	reqs    []*lnrpc.OpenChannelRequest
+	streams []*mockOpenStream
+}
+
+func (m *mockBatchLightning) OpenChannel(_ context.Context,
+	req *lnrpc.OpenChannelRequest, _ ...grpc.CallOption) (
+	lnrpc.Lightning_OpenChannelClient, error) {
+
+	stream := m.streams[len(m.reqs)]
+	m.reqs = append(m.reqs, req)
+
+	return stream, nil
+}
+
+// TestBatchOpenChannel tests that a batch of channels is funded with a single
+// PSBT that pays to all funding outputs and that only the last channel
+// publishes the funding transaction.
+func TestBatchOpenChannel(t *testing.T) {
+	params := &chaincfg.RegressionNetParams
+	txid := chainhash.Hash{8}
+
+	var streams []*mockOpenStream
+	for i := 0; i < 2; i++ {
+		addr, err := btcutil.NewAddressWitnessScriptHash(
+			bytes.Repeat([]byte{byte(i)}, 32), params,
+		)
+		if err != nil {
+			t.Fatalf("unable to create address: %v", err)
+		}
+
+		streams = append(streams, &mockOpenStream{
+			updates: []*lnrpc.OpenStatusUpdate{{
+				Update: &lnrpc.OpenStatusUpdate_PsbtFund{
+					PsbtFund: &lnrpc.ReadyForPsbtFunding{
+						FundingAddress: addr.String(),
+						FundingAmount:  int64(i+1) * 1000,
+					},
+				},
+			}, {
+				Update: &lnrpc.OpenStatusUpdate_ChanPending{
+					ChanPending: &lnrpc.PendingUpdate{
+						Txid:        txid[:],
+						OutputIndex: uint32(i),
+					},
+				},
+			}},
+		})
+	}
+
+	// The batch builds its own template from the funding outputs, so the
+	// templates of the individual channels can be empty.
+	emptyTemplate, err := psbt.New(nil, nil, 2, 0, nil)
+	if err != nil {
+		t.Fatalf("unable to create psbt: %v", err)
+	}
+	rawTemplate, err := serializePsbt(emptyTemplate)
+	if err != nil {
+		t.Fatalf("unable to serialize psbt: %v", err)
+	}
+	for _, stream := range streams {
+		stream.updates[0].GetPsbtFund().Psbt = rawTemplate
+	}
+
+	// lnd v0.13 doesn't offer native batch opens, so the batch is funded
+	// with a PSBT.
+	mock := &mockBatchLightning{streams: streams}
+	funder := &mockPsbtFunder{}
+	client := newLightningClient(mock, nil, params, "")
+	client.version = &verrpc.Version{AppMinor: 13}
+
+	_, err = client.BatchOpenChannel(
+		context.Background(), []*OpenChannelRequest{{LocalAmt: 1000}},
+		nil, 0,
+	)
+	if err == nil {
+		t.Fatalf("expected error without psbt funder")
+	}
+
+	channels, err := client.BatchOpenChannel(
+		context.Background(), []*OpenChannelRequest{
+			{LocalAmt: 1000, Private: true},
+			{LocalAmt: 2000},
+		}, funder, 0,
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(channels) != 2 {
+		t.Fatalf("expected 2 channels, got %v", len(channels))
+	}
+	for i, channel := range channels {
+		if channel.ChannelPoint.Hash != txid ||
+			channel.ChannelPoint.Index != uint32(i) {
+
+			t.Fatalf("unexpected channel point: %v",
+				channel.ChannelPoint)
+		}
+	}
+
+	// Per-channel options must be passed on and only the last channel
+	// may publish the transaction.
+	if !mock.reqs[0].Private || mock.reqs[1].Private {
+		t.Fatalf("per-channel options not passed")
+	}
+	if !mock.reqs[0].FundingShim.GetPsbtShim().NoPublish ||
+		mock.reqs[1].FundingShim.GetPsbtShim().NoPublish {
+
+		t.Fatalf("only last channel must publish")
+	}
+
+	outputs := funder.template.UnsignedTx.TxOut
+	if len(outputs) != 2 || outputs[0].Value != 1000 ||
+		outputs[1].Value != 2000 {
+
+		t.Fatalf("unexpected funding outputs: %v", outputs)
+	}
+
+	// Both channels are verified first and finalized once the PSBT is
+	// signed.
+	if len(mock.steps) != 4 {
+		t.Fatalf("expected 4 funding steps, got %v", len(mock.steps))
+	}
+	for i, step := range mock.steps {
+		if (i < 2) != (step.GetPsbtVerify() != nil) {
+			t.Fatalf("unexpected funding step %v: %v", i, step)
+		}
+	}
+}
+
+// mockChannelEventStream is a channel event stream that returns the given
+// events, followed by io.EOF.
+type mockChannelEventStream struct {
+	grpc.ClientStream
+
+	events []*lnrpc.ChannelEventUpdate
+}
+
+func (m *mockChannelEventStream) Recv() (*lnrpc.ChannelEventUpdate, error) {
+	if len(m.events) == 0 {
+		return nil, io.EOF
+	}
+
+	event := m.events[0]
+	m.events = m.events[1:]
+	return event, nil
+}
+
+// mockNativeBatchLightning is a lightning client that opens batches of
+// channels natively and reports the given channel events.
+type mockNativeBatchLightning struct {
+	lnrpc.LightningClient
+
+	req    *lnrpc.BatchOpenChannelRequest
+	txid   chainhash.Hash
+	events []*lnrpc.ChannelEventUpdate
+}
+
+func (m *mockNativeBatchLightning) BatchOpenChannel(_ context.Context,
+	req *lnrpc.BatchOpenChannelRequest, _ ...grpc.CallOption) (
+	*lnrpc.BatchOpenChannelResponse, error) {
+
+	m.req = req
+
+	resp := &lnrpc.BatchOpenChannelResponse{}
+	for i := range req.Channels {
+		resp.PendingChannels = append(
+			resp.PendingChannels, &lnrpc.PendingUpdate{
+				Txid:        m.txid[:],
+				OutputIndex: uint32(i),
+			},
+		)
+	}
+
+	return resp, nil
+}
+
+func (m *mockNativeBatchLightning) SubscribeChannelEvents(_ context.Context,
+	_ *lnrpc.ChannelEventSubscription, _ ...grpc.CallOption) (
+	lnrpc.Lightning_SubscribeChannelEventsClient, error) {
+
+	return &mockChannelEventStream{events: m.events}, nil
+}
+
+// TestBatchOpenChannelNative tests that lnd's native batch open is used if
+// lnd offers it and that channels receive an update once they are open.
+func TestBatchOpenChannelNative(t *testing.T) {
+	privKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatalf("unable to create key: %v", err)
+	}
+	pubkey := hex.EncodeToString(privKey.PubKey().SerializeCompressed())
+
+	txid := chainhash.Hash{8}
+	openEvent := func(chanPoint wire.OutPoint) *lnrpc.ChannelEventUpdate {
+		return &lnrpc.ChannelEventUpdate{
+			Type: lnrpc.ChannelEventUpdate_OPEN_CHANNEL,
+			Channel: &lnrpc.ChannelEventUpdate_OpenChannel{
+				OpenChannel: &lnrpc.Channel{
+					ChannelPoint: chanPoint.String(),
+					RemotePubkey: pubkey,
+				},
+			},
+		}
+	}
+
+	// Only the first channel of the batch opens before the event stream
+	// ends. The open of an unrelated channel is ignored.
+	mock := &mockNativeBatchLightning{
+		txid: txid,
+		events: []*lnrpc.ChannelEventUpdate{
+			openEvent(wire.OutPoint{Hash: chainhash.Hash{9}}),
+			openEvent(wire.OutPoint{Hash: txid}),
+		},
+	}
+	client := newLightningClient(
+		mock, nil, &chaincfg.RegressionNetParams, "",
+	)
+	client.version = &verrpc.Version{AppMinor: 14}
+
+	// The funding options apply to the whole batch, so they must not
+	// differ between channels.
+	_, err = client.BatchOpenChannel(
+		context.Background(), []*OpenChannelRequest{
+			{LocalAmt: 1000, TargetConf: 6},
+			{LocalAmt: 2000, TargetConf: 3},
+		}, nil, 0,
+	)
+	if err == nil {
+		t.Fatalf("expected error for differing funding options")
+	}
+
+	channels, err := client.BatchOpenChannel(
+		context.Background(), []*OpenChannelRequest{
+			{LocalAmt: 1000, TargetConf: 6, Private: true},
+			{LocalAmt: 2000, TargetConf: 6},
+		}, nil, 0,
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if mock.req.TargetConf != 6 || len(mock.req.Channels) != 2 {
+		t.Fatalf("unexpected batch request: %v", mock.req)
+	}
+	if !mock.req.Channels[0].Private || mock.req.Channels[1].Private {
+		t.Fatalf("per-channel options not passed")
+	}
+
+	for i, channel := range channels {
+		if channel.ChannelPoint.Hash != txid ||
+			channel.ChannelPoint.Index != uint32(i) {
+
+			t.Fatalf("unexpected channel point: %v",
+				channel.ChannelPoint)
+		}
+		if !bytes.Equal(
+			channel.PendingChanID[:],
+			mock.req.Channels[i].PendingChanId,
+		) {
+
+			t.Fatalf("unexpected pending channel id")
+		}
+	}
+
+	select {
+	case update := <-channels[0].Updates:
+		opened, ok := update.(*ChannelOpenedUpdate)
+		if !ok || opened.ChannelPoint != channels[0].ChannelPoint {
+			t.Fatalf("unexpected update: %v", update)
+		}
+
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no open update received")
+	}
+
+	select {
+	case err := <-channels[1].Errors:
+		if err != io.EOF {
+			t.Fatalf("expected end of stream, got %v", err)
+		}
+
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no error received")
+	}
+
+	client.WaitForFinished()
+}
@@ -0,0 +1,314 @@
+package lndclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+const (
+	// defaultRebalanceAttempts is the default number of payments that
+	// may fail before a rebalance is given up.
+	defaultRebalanceAttempts = 3
+
+	// defaultRebalanceParts is the default maximum number of parts a
+	// rebalance payment may be split into.
+	defaultRebalanceParts = 16
+
+	// defaultRebalanceTimeout is the default time lnd may spend on a
+	// single rebalance payment.
+	defaultRebalanceTimeout = time.Minute
+
+	// rebalanceInvoiceExpiry is the expiry of rebalance invoices in
+	// seconds.
+	rebalanceInvoiceExpiry = 3600
+)
+
+// RebalanceRequest holds the parameters of a circular rebalance.
+type RebalanceRequest struct {
+	// Source is the channel that funds leave through.
+	Source uint64
+
+	// Destination is the channel that funds return through. lnd only
+	// allows restricting the last hop node, so the funds may return
+	// through a different channel with the same peer.
+	Destination uint64
+
+	// Amount is the amount to move.
+	Amount btcutil.Amount
+
+	// MaxFee is the fee budget of the whole rebalance. It must be
+	// positive.
+	MaxFee btcutil.Amount
+
+	// MaxParts is the maximum number of parts a single payment may be
+	// split into. If zero, 16 parts are allowed.
+	MaxParts uint32
+
+	// Attempts is the number of payments that may fail before the
+	// rebalance is given up. After every failure, the amount of the next
+	// payment is halved. If zero, three attempts are allowed.
+	Attempts int
+
+	// MinAmount is the smallest amount of a single payment. Once a failed
+	// payment can't be halved without going below it, the rebalance is
+	// given up. If zero, the amount of a payment isn't limited.
+	MinAmount btcutil.Amount
+
+	// Timeout is the time lnd may spend on a single payment. If zero, one
+	// minute is used.
+	Timeout time.Duration
+}
+
+// RebalanceResult is the outcome of a rebalance.
+type RebalanceResult struct {
+	// Amount is the amount that was moved. It is less than the requested
+	// amount if the rebalance was given up.
+	Amount btcutil.Amount
+
+	// FeesPaid is the total routing fee paid.
+	FeesPaid lnwire.MilliSatoshi
+
+	// Routes holds the routes of all successful HTLCs.
+	Routes []*Route
+
+	// Payments is the number of payments that were sent.
+	Payments int
+
+	// LastFailure is the reason the last failed payment failed.
+	LastFailure lnrpc.PaymentFailureReason
+}
+
+// Rebalancer moves funds between our channels by paying ourselves through a
+// circular route.
+type Rebalancer struct {
+	lnd      LightningClient
+	router   RouterClient
+	invoices InvoicesClient
+}
+
+// NewRebalancer creates a new rebalancer. The invoices client is used to
+// cancel the invoices of failed payments.
+func NewRebalancer(lnd LightningClient, router RouterClient,
+	invoices InvoicesClient) *Rebalancer {
+
+	return &Rebalancer{
+		lnd:      lnd,
+		router:   router,
+		invoices: invoices,
+	}
+}
+
+// Rebalance moves the requested amount out through the source channel and
+// back in through the destination channel, by paying invoices of our own node.
+// The amount is sent in one or more payments, which may each be split into
+// multiple parts. The fee budget is shared between the payments in proportion
+// to their amount. The rebalance stops once the amount was moved, the allowed
+// attempts failed or the fee budget was exhausted. The invoices of failed
+// payments are cancelled.
+func (r *Rebalancer) Rebalance(ctx context.Context,
+	req *RebalanceRequest) (*RebalanceResult, error) {
+
+	if req.Source == req.Destination {
+		return nil, errors.New("source and destination are the same " +
+			"channel")
+	}
+	if req.Amount <= 0 {
+		return nil, errors.New("amount must be positive")
+	}
+	if req.MaxFee <= 0 {
+		return nil, errors.New("fee budget must be positive")
+	}
+
+	lastHop, err := r.checkChannels(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	attempts := req.Attempts
+	if attempts == 0 {
+		attempts = defaultRebalanceAttempts
+	}
+
+	maxParts := req.MaxParts
+	if maxParts == 0 {
+		maxParts = defaultRebalanceParts
+	}
+
+	timeout := req.Timeout
+	if timeout == 0 {
+		timeout = defaultRebalanceTimeout
+	}
+
+	var (
+		result    = &RebalanceResult{}
+		budget    = lnwire.NewMSatFromSatoshis(req.MaxFee)
+		remaining = req.Amount
+		chunk     = req.Amount
+		failures  int
+	)
+	for remaining > 0 && failures < attempts {
+		amt := chunk
+		if amt > remaining {
+			amt = remaining
+		}
+
+		// Share the remaining budget in proportion to the amount.
+		maxFee := lnwire.MilliSatoshi(
+			float64(budget) * float64(amt) / float64(remaining),
+		)
+
+		// lnd limits fees in whole satoshis, so the budget is exhausted
+		// once the share of the payment is below a satoshi.
+		if maxFee.ToSatoshis() == 0 {
+			log.Debugf("Rebalance from %v to %v exhausted fee "+
+				"budget of %v", req.Source, req.Destination,
+				req.MaxFee)
+
+			break
+		}
+
+		status, err := r.pay(
+			ctx, req, amt, maxFee.ToSatoshis(), lastHop, maxParts,
+			timeout,
+		)
+		if err != nil {
+			return result, err
+		}
+		result.Payments++
+
+		if status.State != lnrpc.Payment_SUCCEEDED {
+			log.Debugf("Rebalance of %v from %v to %v failed: %v",
+				amt, req.Source, req.Destination,
+				status.FailureReason)
+
+			result.LastFailure = status.FailureReason
+			failures++
+
+			chunk /= 2
+			if chunk == 0 || chunk < req.MinAmount {
+				break
+			}
+			continue
+		}
+
+		remaining -= amt
+		budget -= status.Fee
+		result.Amount += amt
+		result.FeesPaid += status.Fee
+
+		for _, htlc := range status.Htlcs {
+			if htlc.Status == lnrpc.HTLCAttempt_SUCCEEDED {
+				result.Routes = append(
+					result.Routes, htlc.Route,
+				)
+			}
+		}
+	}
+
+	log.Infof("Rebalanced %v of %v from %v to %v for %v in %v payments",
+		result.Amount, req.Amount, req.Source, req.Destination,
+		result.FeesPaid, result.Payments)
+
+	return result, nil
+}
+
+// checkChannels verifies that both channels exist and that the source channel
+// holds enough funds. The peer of the destination channel is returned.
+func (r *Rebalancer) checkChannels(ctx context.Context,
+	req *RebalanceRequest) (route.Vertex, error) {
+
+	channels, err := r.lnd.ListChannels(ctx)
+	if err != nil {
+		return route.Vertex{}, err
+	}
+
+	var source, destination *ChannelInfo
+	for i := range channels {
+		switch channels[i].ChannelID {
+		case req.Source:
+			source = &channels[i]
+
+		case req.Destination:
+			destination = &channels[i]
+		}
+	}
+
+	switch {
+	case source == nil:
+		return route.Vertex{}, fmt.Errorf("source channel %v not "+
+			"found", req.Source)
+
+	case destination == nil:
+		return route.Vertex{}, fmt.Errorf("destination channel %v "+
+			"not found", req.Destination)
+
+	case source.LocalBalance < req.Amount:
+		return route.Vertex{}, fmt.Errorf("source channel balance %v "+
+			"below amount %v", source.LocalBalance, req.Amount)
+
+	case destination.RemoteBalance < req.Amount:
+		return route.Vertex{}, fmt.Errorf("destination channel "+
+			"remote balance %v below amount %v",
+			destination.RemoteBalance, req.Amount)
+	}
+
+	return destination.PubKeyBytes, nil
+}
+
+// pay sends a single rebalance payment and returns its final status.
+func (r *Rebalancer) pay(ctx context.Context, req *RebalanceRequest,
+	amt, maxFee btcutil.Amount, lastHop route.Vertex, maxParts uint32,
+	timeout time.Duration) (*PaymentStatus, error) {
+
+	invoice, err := r.lnd.AddInvoice(ctx, &AddInvoiceRequest{
+		Memo: fmt.Sprintf("rebalance %v -> %v", req.Source,
+			req.Destination),
+		Value:  lnwire.NewMSatFromSatoshis(amt),
+		Expiry: rebalanceInvoiceExpiry,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	statusChan, errChan, err := r.router.SendPayment(
+		ctx, SendPaymentRequest{
+			Invoice:          invoice.PaymentRequest,
+			MaxFee:           maxFee,
+			OutgoingChanIds:  []uint64{req.Source},
+			LastHopPubkey:    &lastHop,
+			AllowSelfPayment: true,
+			MaxParts:         maxParts,
+			Timeout:          timeout,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := waitPayment(ctx, statusChan, errChan)
+	if err != nil {
+		return nil, err
+	}
+
+	// The invoice of a failed payment is never paid, so we cancel it
+	// rather than leaving it open until it expires.
+	if status.State == lnrpc.Payment_FAILED {
+		err := r.invoices.CancelInvoice(ctx, invoice.Hash)
+		if err != nil {
+			log.Warnf("Unable to cancel rebalance invoice %v: %v",
+				invoice.Hash, err)
+		}
+	}
+
+	return status, nil
+}
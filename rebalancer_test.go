@@ -0,0 +1,189 @@
+package lndclient
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// mockRebalanceLightning is a lightning client with a source and destination
+// channel that creates invoices for the requested amount.
+type mockRebalanceLightning struct {
+	LightningClient
+
+	invoices int
+}
+
+func (m *mockRebalanceLightning) ListChannels(_ context.Context) (
+	[]ChannelInfo, error) {
+
+	return []ChannelInfo{{
+		ChannelID:    1,
+		PubKeyBytes:  route.Vertex{1},
+		LocalBalance: 500000,
+	}, {
+		ChannelID:     2,
+		PubKeyBytes:   route.Vertex{2},
+		RemoteBalance: 500000,
+	}}, nil
+}
+
+func (m *mockRebalanceLightning) AddInvoice(_ context.Context,
+	in *AddInvoiceRequest) (*AddInvoiceResult, error) {
+
+	m.invoices++
+
+	return &AddInvoiceResult{
+		Hash:           lntypes.Hash{byte(m.invoices)},
+		PaymentRequest: strconv.FormatUint(uint64(in.Value), 10),
+	}, nil
+}
+
+// mockRebalanceInvoices is an invoices client that records cancelled
+// invoices.
+type mockRebalanceInvoices struct {
+	InvoicesClient
+
+	cancelled []lntypes.Hash
+}
+
+func (m *mockRebalanceInvoices) CancelInvoice(_ context.Context,
+	hash lntypes.Hash) error {
+
+	m.cancelled = append(m.cancelled, hash)
+	return nil
+}
+
+// mockRebalanceRouter is a router that fails payments above the max amount
+// and charges a fee of 0.1% for the others.
+type mockRebalanceRouter struct {
+	RouterClient
+
+	maxAmt   btcutil.Amount
+	requests []SendPaymentRequest
+}
+
+func (m *mockRebalanceRouter) SendPayment(_ context.Context,
+	req SendPaymentRequest) (chan PaymentStatus, chan error, error) {
+
+	m.requests = append(m.requests, req)
+
+	// The invoice holds the amount of the payment.
+	value, err := strconv.ParseUint(req.Invoice, 10, 64)
+	if err != nil {
+		return nil, nil, err
+	}
+	amt := lnwire.MilliSatoshi(value)
+
+	status := PaymentStatus{
+		State: lnrpc.Payment_FAILED,
+		FailureReason: lnrpc.
+			PaymentFailureReason_FAILURE_REASON_NO_ROUTE,
+	}
+	if amt <= lnwire.NewMSatFromSatoshis(m.maxAmt) {
+		status = PaymentStatus{
+			State: lnrpc.Payment_SUCCEEDED,
+			Fee:   amt / 1000,
+			Htlcs: []*HtlcAttempt{{
+				Status: lnrpc.HTLCAttempt_SUCCEEDED,
+				Route:  &Route{TotalAmt: amt + amt/1000},
+			}},
+		}
+	}
+
+	statusChan := make(chan PaymentStatus, 1)
+	statusChan <- status
+	close(statusChan)
+
+	return statusChan, make(chan error), nil
+}
+
+// TestRebalance tests that failed payments are retried with half the amount,
+// that the fee budget is shared between payments, that the invoices of failed
+// payments are cancelled and that the result adds up the successful payments.
+func TestRebalance(t *testing.T) {
+	router := &mockRebalanceRouter{maxAmt: 200000}
+	invoices := &mockRebalanceInvoices{}
+	rebalancer := NewRebalancer(
+		&mockRebalanceLightning{}, router, invoices,
+	)
+
+	result, err := rebalancer.Rebalance(
+		context.Background(), &RebalanceRequest{
+			Source:      1,
+			Destination: 2,
+			Amount:      400000,
+			MaxFee:      1000,
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.Amount != 400000 || result.Payments != 3 ||
+		result.FeesPaid != 400000 || len(result.Routes) != 2 {
+
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	first := router.requests[0]
+	if first.OutgoingChanIds[0] != 1 || *first.LastHopPubkey !=
+		(route.Vertex{2}) || !first.AllowSelfPayment ||
+		first.MaxFee != 1000 {
+
+		t.Fatalf("unexpected payment request: %+v", first)
+	}
+
+	// The second payment moves half the amount, so it gets half of the
+	// budget.
+	if router.requests[1].MaxFee != 500 {
+		t.Fatalf("unexpected fee limit: %v", router.requests[1].MaxFee)
+	}
+
+	// Only the invoice of the first payment, which failed, is cancelled.
+	if len(invoices.cancelled) != 1 ||
+		invoices.cancelled[0] != (lntypes.Hash{1}) {
+
+		t.Fatalf("unexpected cancelled invoices: %v",
+			invoices.cancelled)
+	}
+
+	// With a budget of a single satoshi, the share of the halved payment
+	// is below a satoshi, so the rebalance stops after the first failure.
+	router.requests = nil
+	result, err = rebalancer.Rebalance(
+		context.Background(), &RebalanceRequest{
+			Source:      1,
+			Destination: 2,
+			Amount:      400000,
+			MaxFee:      1,
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Amount != 0 || result.Payments != 1 ||
+		len(router.requests) != 1 {
+
+		t.Fatalf("expected exhausted budget: %+v", result)
+	}
+
+	// Rebalancing more than the source channel holds fails right away.
+	_, err = rebalancer.Rebalance(
+		context.Background(), &RebalanceRequest{
+			Source:      1,
+			Destination: 2,
+			Amount:      600000,
+			MaxFee:      1000,
+		},
+	)
+	if err == nil {
+		t.Fatalf("expected error for insufficient balance")
+	}
+}
@@ -0,0 +1,2213 @@
+package lndclient
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/chainrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/invoicesrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/signrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/verrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/walletrpc"
+	"google.golang.org/grpc"
+)
+
+// restLightningClient is an implementation of the lnrpc.LightningClient
+// interface on top of lnd's REST proxy.
+type restLightningClient struct {
+	conn *restConn
+}
+
+// A compile time check to ensure that restLightningClient implements the
+// lnrpc.LightningClient interface.
+var _ lnrpc.LightningClient = (*restLightningClient)(nil)
+
+// WalletBalance is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) WalletBalance(ctx context.Context,
+	in *lnrpc.WalletBalanceRequest, _ ...grpc.CallOption) (
+	*lnrpc.WalletBalanceResponse, error) {
+
+	resp := &lnrpc.WalletBalanceResponse{}
+	err := r.conn.call(
+		ctx, http.MethodGet, "/v1/balance/blockchain", false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ChannelBalance is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) ChannelBalance(ctx context.Context,
+	in *lnrpc.ChannelBalanceRequest, _ ...grpc.CallOption) (
+	*lnrpc.ChannelBalanceResponse, error) {
+
+	resp := &lnrpc.ChannelBalanceResponse{}
+	err := r.conn.call(
+		ctx, http.MethodGet, "/v1/balance/channels", false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// GetTransactions is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) GetTransactions(ctx context.Context,
+	in *lnrpc.GetTransactionsRequest, _ ...grpc.CallOption) (
+	*lnrpc.TransactionDetails, error) {
+
+	resp := &lnrpc.TransactionDetails{}
+	err := r.conn.call(
+		ctx, http.MethodGet, "/v1/transactions", false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// EstimateFee is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) EstimateFee(ctx context.Context,
+	in *lnrpc.EstimateFeeRequest, _ ...grpc.CallOption) (
+	*lnrpc.EstimateFeeResponse, error) {
+
+	resp := &lnrpc.EstimateFeeResponse{}
+	err := r.conn.call(
+		ctx, http.MethodGet, "/v1/transactions/fee", false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// SendCoins is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) SendCoins(ctx context.Context,
+	in *lnrpc.SendCoinsRequest, _ ...grpc.CallOption) (
+	*lnrpc.SendCoinsResponse, error) {
+
+	resp := &lnrpc.SendCoinsResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v1/transactions", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ListUnspent is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) ListUnspent(ctx context.Context,
+	in *lnrpc.ListUnspentRequest, _ ...grpc.CallOption) (
+	*lnrpc.ListUnspentResponse, error) {
+
+	resp := &lnrpc.ListUnspentResponse{}
+	err := r.conn.call(
+		ctx, http.MethodGet, "/v1/utxos", false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// SubscribeTransactions is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) SubscribeTransactions(ctx context.Context,
+	in *lnrpc.GetTransactionsRequest, _ ...grpc.CallOption) (
+	lnrpc.Lightning_SubscribeTransactionsClient, error) {
+
+	stream, err := r.conn.stream(
+		ctx, http.MethodGet, "/v1/transactions/subscribe", false, in,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &restLightningSubscribeTransactionsStream{stream}, nil
+}
+
+// SendMany is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) SendMany(ctx context.Context,
+	in *lnrpc.SendManyRequest, _ ...grpc.CallOption) (
+	*lnrpc.SendManyResponse, error) {
+
+	resp := &lnrpc.SendManyResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v1/transactions/many", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// NewAddress is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) NewAddress(ctx context.Context,
+	in *lnrpc.NewAddressRequest, _ ...grpc.CallOption) (
+	*lnrpc.NewAddressResponse, error) {
+
+	resp := &lnrpc.NewAddressResponse{}
+	err := r.conn.call(
+		ctx, http.MethodGet, "/v1/newaddress", false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// SignMessage is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) SignMessage(ctx context.Context,
+	in *lnrpc.SignMessageRequest, _ ...grpc.CallOption) (
+	*lnrpc.SignMessageResponse, error) {
+
+	resp := &lnrpc.SignMessageResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v1/signmessage", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// VerifyMessage is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) VerifyMessage(ctx context.Context,
+	in *lnrpc.VerifyMessageRequest, _ ...grpc.CallOption) (
+	*lnrpc.VerifyMessageResponse, error) {
+
+	resp := &lnrpc.VerifyMessageResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v1/verifymessage", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ConnectPeer is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) ConnectPeer(ctx context.Context,
+	in *lnrpc.ConnectPeerRequest, _ ...grpc.CallOption) (
+	*lnrpc.ConnectPeerResponse, error) {
+
+	resp := &lnrpc.ConnectPeerResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v1/peers", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// DisconnectPeer is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) DisconnectPeer(ctx context.Context,
+	in *lnrpc.DisconnectPeerRequest, _ ...grpc.CallOption) (
+	*lnrpc.DisconnectPeerResponse, error) {
+
+	resp := &lnrpc.DisconnectPeerResponse{}
+	err := r.conn.call(
+		ctx, http.MethodDelete, "/v1/peers/{pub_key}", false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ListPeers is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) ListPeers(ctx context.Context,
+	in *lnrpc.ListPeersRequest, _ ...grpc.CallOption) (
+	*lnrpc.ListPeersResponse, error) {
+
+	resp := &lnrpc.ListPeersResponse{}
+	err := r.conn.call(
+		ctx, http.MethodGet, "/v1/peers", false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// SubscribePeerEvents is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) SubscribePeerEvents(ctx context.Context,
+	in *lnrpc.PeerEventSubscription, _ ...grpc.CallOption) (
+	lnrpc.Lightning_SubscribePeerEventsClient, error) {
+
+	stream, err := r.conn.stream(
+		ctx, http.MethodGet, "/v1/peers/subscribe", false, in,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &restLightningSubscribePeerEventsStream{stream}, nil
+}
+
+// GetInfo is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) GetInfo(ctx context.Context,
+	in *lnrpc.GetInfoRequest, _ ...grpc.CallOption) (
+	*lnrpc.GetInfoResponse, error) {
+
+	resp := &lnrpc.GetInfoResponse{}
+	err := r.conn.call(
+		ctx, http.MethodGet, "/v1/getinfo", false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// GetRecoveryInfo is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) GetRecoveryInfo(ctx context.Context,
+	in *lnrpc.GetRecoveryInfoRequest, _ ...grpc.CallOption) (
+	*lnrpc.GetRecoveryInfoResponse, error) {
+
+	resp := &lnrpc.GetRecoveryInfoResponse{}
+	err := r.conn.call(
+		ctx, http.MethodGet, "/v1/getrecoveryinfo", false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// PendingChannels is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) PendingChannels(ctx context.Context,
+	in *lnrpc.PendingChannelsRequest, _ ...grpc.CallOption) (
+	*lnrpc.PendingChannelsResponse, error) {
+
+	resp := &lnrpc.PendingChannelsResponse{}
+	err := r.conn.call(
+		ctx, http.MethodGet, "/v1/channels/pending", false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ListChannels is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) ListChannels(ctx context.Context,
+	in *lnrpc.ListChannelsRequest, _ ...grpc.CallOption) (
+	*lnrpc.ListChannelsResponse, error) {
+
+	resp := &lnrpc.ListChannelsResponse{}
+	err := r.conn.call(
+		ctx, http.MethodGet, "/v1/channels", false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// SubscribeChannelEvents is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) SubscribeChannelEvents(ctx context.Context,
+	in *lnrpc.ChannelEventSubscription, _ ...grpc.CallOption) (
+	lnrpc.Lightning_SubscribeChannelEventsClient, error) {
+
+	stream, err := r.conn.stream(
+		ctx, http.MethodGet, "/v1/channels/subscribe", false, in,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &restLightningSubscribeChannelEventsStream{stream}, nil
+}
+
+// ClosedChannels is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) ClosedChannels(ctx context.Context,
+	in *lnrpc.ClosedChannelsRequest, _ ...grpc.CallOption) (
+	*lnrpc.ClosedChannelsResponse, error) {
+
+	resp := &lnrpc.ClosedChannelsResponse{}
+	err := r.conn.call(
+		ctx, http.MethodGet, "/v1/channels/closed", false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// OpenChannelSync is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) OpenChannelSync(ctx context.Context,
+	in *lnrpc.OpenChannelRequest, _ ...grpc.CallOption) (
+	*lnrpc.ChannelPoint, error) {
+
+	resp := &lnrpc.ChannelPoint{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v1/channels", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// OpenChannel is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) OpenChannel(ctx context.Context,
+	in *lnrpc.OpenChannelRequest, _ ...grpc.CallOption) (
+	lnrpc.Lightning_OpenChannelClient, error) {
+
+	stream, err := r.conn.stream(
+		ctx, http.MethodPost, "/v1/channels/stream", true, in,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &restLightningOpenChannelStream{stream}, nil
+}
+
+// FundingStateStep is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) FundingStateStep(ctx context.Context,
+	in *lnrpc.FundingTransitionMsg, _ ...grpc.CallOption) (
+	*lnrpc.FundingStateStepResp, error) {
+
+	resp := &lnrpc.FundingStateStepResp{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v1/funding/step", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ChannelAcceptor is part of the lnrpc.LightningClient interface. It is not
+// available through lnd's REST proxy.
+func (r *restLightningClient) ChannelAcceptor(_ context.Context,
+	_ ...grpc.CallOption) (lnrpc.Lightning_ChannelAcceptorClient, error) {
+
+	return nil, errRestUnsupported("ChannelAcceptor")
+}
+
+// CloseChannel is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) CloseChannel(ctx context.Context,
+	in *lnrpc.CloseChannelRequest, _ ...grpc.CallOption) (
+	lnrpc.Lightning_CloseChannelClient, error) {
+
+	chanPoint, err := restChannelPoint(in.ChannelPoint)
+	if err != nil {
+		return nil, err
+	}
+	req := proto.Clone(in).(*lnrpc.CloseChannelRequest)
+	req.ChannelPoint = chanPoint
+
+	stream, err := r.conn.stream(
+		ctx, http.MethodDelete, "/v1/channels/"+
+			"{channel_point.funding_txid_str}/"+
+			"{channel_point.output_index}", false, req,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &restLightningCloseChannelStream{stream}, nil
+}
+
+// AbandonChannel is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) AbandonChannel(ctx context.Context,
+	in *lnrpc.AbandonChannelRequest, _ ...grpc.CallOption) (
+	*lnrpc.AbandonChannelResponse, error) {
+
+	chanPoint, err := restChannelPoint(in.ChannelPoint)
+	if err != nil {
+		return nil, err
+	}
+	req := proto.Clone(in).(*lnrpc.AbandonChannelRequest)
+	req.ChannelPoint = chanPoint
+
+	resp := &lnrpc.AbandonChannelResponse{}
+	err = r.conn.call(
+		ctx, http.MethodDelete, "/v1/channels/abandon/"+
+			"{channel_point.funding_txid_str}/"+
+			"{channel_point.output_index}", false, req, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// SendPayment is part of the lnrpc.LightningClient interface. It is not
+// available through lnd's REST proxy.
+func (r *restLightningClient) SendPayment(_ context.Context,
+	_ ...grpc.CallOption) (lnrpc.Lightning_SendPaymentClient, error) {
+
+	return nil, errRestUnsupported("SendPayment")
+}
+
+// SendPaymentSync is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) SendPaymentSync(ctx context.Context,
+	in *lnrpc.SendRequest, _ ...grpc.CallOption) (
+	*lnrpc.SendResponse, error) {
+
+	resp := &lnrpc.SendResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v1/channels/transactions", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// SendToRoute is part of the lnrpc.LightningClient interface. It is not
+// available through lnd's REST proxy.
+func (r *restLightningClient) SendToRoute(_ context.Context,
+	_ ...grpc.CallOption) (lnrpc.Lightning_SendToRouteClient, error) {
+
+	return nil, errRestUnsupported("SendToRoute")
+}
+
+// SendToRouteSync is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) SendToRouteSync(ctx context.Context,
+	in *lnrpc.SendToRouteRequest, _ ...grpc.CallOption) (
+	*lnrpc.SendResponse, error) {
+
+	resp := &lnrpc.SendResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost,
+		"/v1/channels/transactions/route",
+		true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// AddInvoice is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) AddInvoice(ctx context.Context,
+	in *lnrpc.Invoice, _ ...grpc.CallOption) (
+	*lnrpc.AddInvoiceResponse, error) {
+
+	resp := &lnrpc.AddInvoiceResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v1/invoices", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ListInvoices is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) ListInvoices(ctx context.Context,
+	in *lnrpc.ListInvoiceRequest, _ ...grpc.CallOption) (
+	*lnrpc.ListInvoiceResponse, error) {
+
+	resp := &lnrpc.ListInvoiceResponse{}
+	err := r.conn.call(
+		ctx, http.MethodGet, "/v1/invoices", false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// LookupInvoice is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) LookupInvoice(ctx context.Context,
+	in *lnrpc.PaymentHash, _ ...grpc.CallOption) (*lnrpc.Invoice, error) {
+
+	// The proxy expects the hash as hex encoded path parameter.
+	req := proto.Clone(in).(*lnrpc.PaymentHash)
+	if req.RHashStr == "" {
+		req.RHashStr = hex.EncodeToString(req.RHash)
+		req.RHash = nil
+	}
+
+	resp := &lnrpc.Invoice{}
+	err := r.conn.call(
+		ctx, http.MethodGet, "/v1/invoice/{r_hash_str}", false, req,
+		resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// SubscribeInvoices is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) SubscribeInvoices(ctx context.Context,
+	in *lnrpc.InvoiceSubscription, _ ...grpc.CallOption) (
+	lnrpc.Lightning_SubscribeInvoicesClient, error) {
+
+	stream, err := r.conn.stream(
+		ctx, http.MethodGet, "/v1/invoices/subscribe", false, in,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &restLightningSubscribeInvoicesStream{stream}, nil
+}
+
+// DecodePayReq is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) DecodePayReq(ctx context.Context,
+	in *lnrpc.PayReqString, _ ...grpc.CallOption) (*lnrpc.PayReq, error) {
+
+	resp := &lnrpc.PayReq{}
+	err := r.conn.call(
+		ctx, http.MethodGet, "/v1/payreq/{pay_req}", false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ListPayments is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) ListPayments(ctx context.Context,
+	in *lnrpc.ListPaymentsRequest, _ ...grpc.CallOption) (
+	*lnrpc.ListPaymentsResponse, error) {
+
+	resp := &lnrpc.ListPaymentsResponse{}
+	err := r.conn.call(
+		ctx, http.MethodGet, "/v1/payments", false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// DeleteAllPayments is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) DeleteAllPayments(ctx context.Context,
+	in *lnrpc.DeleteAllPaymentsRequest, _ ...grpc.CallOption) (
+	*lnrpc.DeleteAllPaymentsResponse, error) {
+
+	resp := &lnrpc.DeleteAllPaymentsResponse{}
+	err := r.conn.call(
+		ctx, http.MethodDelete, "/v1/payments", false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// DescribeGraph is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) DescribeGraph(ctx context.Context,
+	in *lnrpc.ChannelGraphRequest, _ ...grpc.CallOption) (
+	*lnrpc.ChannelGraph, error) {
+
+	resp := &lnrpc.ChannelGraph{}
+	err := r.conn.call(
+		ctx, http.MethodGet, "/v1/graph", false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// GetNodeMetrics is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) GetNodeMetrics(ctx context.Context,
+	in *lnrpc.NodeMetricsRequest, _ ...grpc.CallOption) (
+	*lnrpc.NodeMetricsResponse, error) {
+
+	resp := &lnrpc.NodeMetricsResponse{}
+	err := r.conn.call(
+		ctx, http.MethodGet, "/v1/graph/nodemetrics", false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// GetChanInfo is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) GetChanInfo(ctx context.Context,
+	in *lnrpc.ChanInfoRequest, _ ...grpc.CallOption) (
+	*lnrpc.ChannelEdge, error) {
+
+	resp := &lnrpc.ChannelEdge{}
+	err := r.conn.call(
+		ctx, http.MethodGet, "/v1/graph/edge/{chan_id}", false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// GetNodeInfo is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) GetNodeInfo(ctx context.Context,
+	in *lnrpc.NodeInfoRequest, _ ...grpc.CallOption) (
+	*lnrpc.NodeInfo, error) {
+
+	resp := &lnrpc.NodeInfo{}
+	err := r.conn.call(
+		ctx, http.MethodGet, "/v1/graph/node/{pub_key}", false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// QueryRoutes is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) QueryRoutes(ctx context.Context,
+	in *lnrpc.QueryRoutesRequest, _ ...grpc.CallOption) (
+	*lnrpc.QueryRoutesResponse, error) {
+
+	resp := &lnrpc.QueryRoutesResponse{}
+	err := r.conn.call(
+		ctx, http.MethodGet,
+		"/v1/graph/routes/{pub_key}/{amt}",
+		false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// GetNetworkInfo is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) GetNetworkInfo(ctx context.Context,
+	in *lnrpc.NetworkInfoRequest, _ ...grpc.CallOption) (
+	*lnrpc.NetworkInfo, error) {
+
+	resp := &lnrpc.NetworkInfo{}
+	err := r.conn.call(
+		ctx, http.MethodGet, "/v1/graph/info", false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// StopDaemon is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) StopDaemon(ctx context.Context,
+	in *lnrpc.StopRequest, _ ...grpc.CallOption) (
+	*lnrpc.StopResponse, error) {
+
+	resp := &lnrpc.StopResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v1/stop", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// SubscribeChannelGraph is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) SubscribeChannelGraph(ctx context.Context,
+	in *lnrpc.GraphTopologySubscription, _ ...grpc.CallOption) (
+	lnrpc.Lightning_SubscribeChannelGraphClient, error) {
+
+	stream, err := r.conn.stream(
+		ctx, http.MethodGet, "/v1/graph/subscribe", false, in,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &restLightningSubscribeChannelGraphStream{stream}, nil
+}
+
+// DebugLevel is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) DebugLevel(ctx context.Context,
+	in *lnrpc.DebugLevelRequest, _ ...grpc.CallOption) (
+	*lnrpc.DebugLevelResponse, error) {
+
+	resp := &lnrpc.DebugLevelResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v1/debuglevel", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// FeeReport is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) FeeReport(ctx context.Context,
+	in *lnrpc.FeeReportRequest, _ ...grpc.CallOption) (
+	*lnrpc.FeeReportResponse, error) {
+
+	resp := &lnrpc.FeeReportResponse{}
+	err := r.conn.call(
+		ctx, http.MethodGet, "/v1/fees", false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// UpdateChannelPolicy is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) UpdateChannelPolicy(ctx context.Context,
+	in *lnrpc.PolicyUpdateRequest, _ ...grpc.CallOption) (
+	*lnrpc.PolicyUpdateResponse, error) {
+
+	// Inbound fees are passed as raw fields, which can't be encoded as
+	// JSON, so they would silently be dropped.
+	if len(in.ProtoReflect().GetUnknown()) != 0 {
+		return nil, errRestUnsupported("UpdateChannelPolicy with " +
+			"inbound fees")
+	}
+
+	resp := &lnrpc.PolicyUpdateResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v1/chanpolicy", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ForwardingHistory is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) ForwardingHistory(ctx context.Context,
+	in *lnrpc.ForwardingHistoryRequest, _ ...grpc.CallOption) (
+	*lnrpc.ForwardingHistoryResponse, error) {
+
+	resp := &lnrpc.ForwardingHistoryResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v1/switch", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ExportChannelBackup is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) ExportChannelBackup(ctx context.Context,
+	in *lnrpc.ExportChannelBackupRequest, _ ...grpc.CallOption) (
+	*lnrpc.ChannelBackup, error) {
+
+	chanPoint, err := restChannelPoint(in.ChanPoint)
+	if err != nil {
+		return nil, err
+	}
+	req := proto.Clone(in).(*lnrpc.ExportChannelBackupRequest)
+	req.ChanPoint = chanPoint
+
+	resp := &lnrpc.ChannelBackup{}
+	err = r.conn.call(
+		ctx, http.MethodGet, "/v1/channels/backup/"+
+			"{chan_point.funding_txid_str}/"+
+			"{chan_point.output_index}", false, req, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ExportAllChannelBackups is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) ExportAllChannelBackups(ctx context.Context,
+	in *lnrpc.ChanBackupExportRequest, _ ...grpc.CallOption) (
+	*lnrpc.ChanBackupSnapshot, error) {
+
+	resp := &lnrpc.ChanBackupSnapshot{}
+	err := r.conn.call(
+		ctx, http.MethodGet, "/v1/channels/backup", false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// VerifyChanBackup is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) VerifyChanBackup(ctx context.Context,
+	in *lnrpc.ChanBackupSnapshot, _ ...grpc.CallOption) (
+	*lnrpc.VerifyChanBackupResponse, error) {
+
+	resp := &lnrpc.VerifyChanBackupResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v1/channels/backup/verify", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// RestoreChannelBackups is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) RestoreChannelBackups(ctx context.Context,
+	in *lnrpc.RestoreChanBackupRequest, _ ...grpc.CallOption) (
+	*lnrpc.RestoreBackupResponse, error) {
+
+	resp := &lnrpc.RestoreBackupResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v1/channels/backup/restore", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// SubscribeChannelBackups is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) SubscribeChannelBackups(ctx context.Context,
+	in *lnrpc.ChannelBackupSubscription, _ ...grpc.CallOption) (
+	lnrpc.Lightning_SubscribeChannelBackupsClient, error) {
+
+	stream, err := r.conn.stream(
+		ctx, http.MethodGet, "/v1/channels/backup/subscribe", false, in,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &restLightningSubscribeChannelBackupsStream{stream}, nil
+}
+
+// BakeMacaroon is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) BakeMacaroon(ctx context.Context,
+	in *lnrpc.BakeMacaroonRequest, _ ...grpc.CallOption) (
+	*lnrpc.BakeMacaroonResponse, error) {
+
+	resp := &lnrpc.BakeMacaroonResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v1/macaroon", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// BatchOpenChannel is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) BatchOpenChannel(ctx context.Context,
+	in *lnrpc.BatchOpenChannelRequest, _ ...grpc.CallOption) (
+	*lnrpc.BatchOpenChannelResponse, error) {
+
+	resp := &lnrpc.BatchOpenChannelResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v1/channels/batch", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// DeletePayment is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) DeletePayment(ctx context.Context,
+	in *lnrpc.DeletePaymentRequest, _ ...grpc.CallOption) (
+	*lnrpc.DeletePaymentResponse, error) {
+
+	resp := &lnrpc.DeletePaymentResponse{}
+	err := r.conn.call(
+		ctx, http.MethodDelete, "/v1/payment", false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ListMacaroonIDs is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) ListMacaroonIDs(ctx context.Context,
+	in *lnrpc.ListMacaroonIDsRequest, _ ...grpc.CallOption) (
+	*lnrpc.ListMacaroonIDsResponse, error) {
+
+	resp := &lnrpc.ListMacaroonIDsResponse{}
+	err := r.conn.call(
+		ctx, http.MethodGet, "/v1/macaroon/ids", false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// DeleteMacaroonID is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) DeleteMacaroonID(ctx context.Context,
+	in *lnrpc.DeleteMacaroonIDRequest, _ ...grpc.CallOption) (
+	*lnrpc.DeleteMacaroonIDResponse, error) {
+
+	resp := &lnrpc.DeleteMacaroonIDResponse{}
+	err := r.conn.call(
+		ctx, http.MethodDelete, "/v1/macaroon/{root_key_id}", false,
+		in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ListPermissions is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) ListPermissions(ctx context.Context,
+	in *lnrpc.ListPermissionsRequest, _ ...grpc.CallOption) (
+	*lnrpc.ListPermissionsResponse, error) {
+
+	resp := &lnrpc.ListPermissionsResponse{}
+	err := r.conn.call(
+		ctx, http.MethodGet, "/v1/macaroon/permissions", false,
+		in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// CheckMacaroonPermissions is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) CheckMacaroonPermissions(ctx context.Context,
+	in *lnrpc.CheckMacPermRequest, _ ...grpc.CallOption) (
+	*lnrpc.CheckMacPermResponse, error) {
+
+	resp := &lnrpc.CheckMacPermResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v1/macaroon/checkpermissions", true,
+		in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// SendCustomMessage is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) SendCustomMessage(ctx context.Context,
+	in *lnrpc.SendCustomMessageRequest, _ ...grpc.CallOption) (
+	*lnrpc.SendCustomMessageResponse, error) {
+
+	resp := &lnrpc.SendCustomMessageResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v1/custommessage", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// RegisterRPCMiddleware is part of the lnrpc.LightningClient interface. It
+// is not available through lnd's REST proxy.
+func (r *restLightningClient) RegisterRPCMiddleware(_ context.Context,
+	_ ...grpc.CallOption) (lnrpc.Lightning_RegisterRPCMiddlewareClient,
+	error) {
+
+	return nil, errRestUnsupported("RegisterRPCMiddleware")
+}
+
+// SubscribeCustomMessages is part of the lnrpc.LightningClient interface.
+func (r *restLightningClient) SubscribeCustomMessages(ctx context.Context,
+	in *lnrpc.SubscribeCustomMessagesRequest, _ ...grpc.CallOption) (
+	lnrpc.Lightning_SubscribeCustomMessagesClient, error) {
+
+	stream, err := r.conn.stream(
+		ctx, http.MethodGet, "/v1/custommessage/subscribe", false, in,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &restLightningSubscribeCustomMessagesStream{stream}, nil
+}
+
+// restChainNotifierClient is an implementation of the
+// chainrpc.ChainNotifierClient interface on top of lnd's REST proxy.
+type restChainNotifierClient struct {
+	conn *restConn
+}
+
+// A compile time check to ensure that restChainNotifierClient implements the
+// chainrpc.ChainNotifierClient interface.
+var _ chainrpc.ChainNotifierClient = (*restChainNotifierClient)(nil)
+
+// RegisterConfirmationsNtfn is part of the chainrpc.ChainNotifierClient
+// interface.
+func (r *restChainNotifierClient) RegisterConfirmationsNtfn(ctx context.Context,
+	in *chainrpc.ConfRequest, _ ...grpc.CallOption) (
+	chainrpc.ChainNotifier_RegisterConfirmationsNtfnClient, error) {
+
+	stream, err := r.conn.stream(
+		ctx, http.MethodPost,
+		"/v2/chainnotifier/register/confirmations",
+		true, in,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &restChainNotifierRegisterConfirmationsNtfnStream{stream}, nil
+}
+
+// RegisterSpendNtfn is part of the chainrpc.ChainNotifierClient interface.
+func (r *restChainNotifierClient) RegisterSpendNtfn(ctx context.Context,
+	in *chainrpc.SpendRequest, _ ...grpc.CallOption) (
+	chainrpc.ChainNotifier_RegisterSpendNtfnClient, error) {
+
+	stream, err := r.conn.stream(
+		ctx, http.MethodPost, "/v2/chainnotifier/register/spends", true, in,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &restChainNotifierRegisterSpendNtfnStream{stream}, nil
+}
+
+// RegisterBlockEpochNtfn is part of the chainrpc.ChainNotifierClient interface.
+func (r *restChainNotifierClient) RegisterBlockEpochNtfn(ctx context.Context,
+	in *chainrpc.BlockEpoch, _ ...grpc.CallOption) (
+	chainrpc.ChainNotifier_RegisterBlockEpochNtfnClient, error) {
+
+	stream, err := r.conn.stream(
+		ctx, http.MethodPost, "/v2/chainnotifier/register/blocks", true, in,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &restChainNotifierRegisterBlockEpochNtfnStream{stream}, nil
+}
+
+// restSignerClient is an implementation of the signrpc.SignerClient interface
+// on top of lnd's REST proxy.
+type restSignerClient struct {
+	conn *restConn
+}
+
+// A compile time check to ensure that restSignerClient implements the
+// signrpc.SignerClient interface.
+var _ signrpc.SignerClient = (*restSignerClient)(nil)
+
+// SignOutputRaw is part of the signrpc.SignerClient interface.
+func (r *restSignerClient) SignOutputRaw(ctx context.Context,
+	in *signrpc.SignReq, _ ...grpc.CallOption) (*signrpc.SignResp, error) {
+
+	resp := &signrpc.SignResp{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v2/signer/signraw", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ComputeInputScript is part of the signrpc.SignerClient interface.
+func (r *restSignerClient) ComputeInputScript(ctx context.Context,
+	in *signrpc.SignReq, _ ...grpc.CallOption) (
+	*signrpc.InputScriptResp, error) {
+
+	resp := &signrpc.InputScriptResp{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v2/signer/inputscript", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// SignMessage is part of the signrpc.SignerClient interface.
+func (r *restSignerClient) SignMessage(ctx context.Context,
+	in *signrpc.SignMessageReq, _ ...grpc.CallOption) (
+	*signrpc.SignMessageResp, error) {
+
+	resp := &signrpc.SignMessageResp{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v2/signer/signmessage", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// VerifyMessage is part of the signrpc.SignerClient interface.
+func (r *restSignerClient) VerifyMessage(ctx context.Context,
+	in *signrpc.VerifyMessageReq, _ ...grpc.CallOption) (
+	*signrpc.VerifyMessageResp, error) {
+
+	resp := &signrpc.VerifyMessageResp{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v2/signer/verifymessage", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// DeriveSharedKey is part of the signrpc.SignerClient interface.
+func (r *restSignerClient) DeriveSharedKey(ctx context.Context,
+	in *signrpc.SharedKeyRequest, _ ...grpc.CallOption) (
+	*signrpc.SharedKeyResponse, error) {
+
+	resp := &signrpc.SharedKeyResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v2/signer/sharedkey", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// restWalletKitClient is an implementation of the walletrpc.WalletKitClient
+// interface on top of lnd's REST proxy.
+type restWalletKitClient struct {
+	conn *restConn
+}
+
+// A compile time check to ensure that restWalletKitClient implements the
+// walletrpc.WalletKitClient interface.
+var _ walletrpc.WalletKitClient = (*restWalletKitClient)(nil)
+
+// ListUnspent is part of the walletrpc.WalletKitClient interface.
+func (r *restWalletKitClient) ListUnspent(ctx context.Context,
+	in *walletrpc.ListUnspentRequest, _ ...grpc.CallOption) (
+	*walletrpc.ListUnspentResponse, error) {
+
+	resp := &walletrpc.ListUnspentResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v2/wallet/utxos", false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// LeaseOutput is part of the walletrpc.WalletKitClient interface.
+func (r *restWalletKitClient) LeaseOutput(ctx context.Context,
+	in *walletrpc.LeaseOutputRequest, _ ...grpc.CallOption) (
+	*walletrpc.LeaseOutputResponse, error) {
+
+	resp := &walletrpc.LeaseOutputResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v2/wallet/utxos/lease", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ReleaseOutput is part of the walletrpc.WalletKitClient interface.
+func (r *restWalletKitClient) ReleaseOutput(ctx context.Context,
+	in *walletrpc.ReleaseOutputRequest, _ ...grpc.CallOption) (
+	*walletrpc.ReleaseOutputResponse, error) {
+
+	resp := &walletrpc.ReleaseOutputResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v2/wallet/utxos/release", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// DeriveNextKey is part of the walletrpc.WalletKitClient interface.
+func (r *restWalletKitClient) DeriveNextKey(ctx context.Context,
+	in *walletrpc.KeyReq, _ ...grpc.CallOption) (
+	*signrpc.KeyDescriptor, error) {
+
+	resp := &signrpc.KeyDescriptor{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v2/wallet/key/next", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// DeriveKey is part of the walletrpc.WalletKitClient interface.
+func (r *restWalletKitClient) DeriveKey(ctx context.Context,
+	in *signrpc.KeyLocator, _ ...grpc.CallOption) (
+	*signrpc.KeyDescriptor, error) {
+
+	resp := &signrpc.KeyDescriptor{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v2/wallet/key", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// NextAddr is part of the walletrpc.WalletKitClient interface.
+func (r *restWalletKitClient) NextAddr(ctx context.Context,
+	in *walletrpc.AddrRequest, _ ...grpc.CallOption) (
+	*walletrpc.AddrResponse, error) {
+
+	resp := &walletrpc.AddrResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v2/wallet/address/next", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// PublishTransaction is part of the walletrpc.WalletKitClient interface.
+func (r *restWalletKitClient) PublishTransaction(ctx context.Context,
+	in *walletrpc.Transaction, _ ...grpc.CallOption) (
+	*walletrpc.PublishResponse, error) {
+
+	resp := &walletrpc.PublishResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v2/wallet/tx", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// SendOutputs is part of the walletrpc.WalletKitClient interface.
+func (r *restWalletKitClient) SendOutputs(ctx context.Context,
+	in *walletrpc.SendOutputsRequest, _ ...grpc.CallOption) (
+	*walletrpc.SendOutputsResponse, error) {
+
+	resp := &walletrpc.SendOutputsResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v2/wallet/send", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// EstimateFee is part of the walletrpc.WalletKitClient interface.
+func (r *restWalletKitClient) EstimateFee(ctx context.Context,
+	in *walletrpc.EstimateFeeRequest, _ ...grpc.CallOption) (
+	*walletrpc.EstimateFeeResponse, error) {
+
+	resp := &walletrpc.EstimateFeeResponse{}
+	err := r.conn.call(
+		ctx, http.MethodGet,
+		"/v2/wallet/estimatefee/{conf_target}",
+		false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// PendingSweeps is part of the walletrpc.WalletKitClient interface.
+func (r *restWalletKitClient) PendingSweeps(ctx context.Context,
+	in *walletrpc.PendingSweepsRequest, _ ...grpc.CallOption) (
+	*walletrpc.PendingSweepsResponse, error) {
+
+	resp := &walletrpc.PendingSweepsResponse{}
+	err := r.conn.call(
+		ctx, http.MethodGet, "/v2/wallet/sweeps/pending", false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// BumpFee is part of the walletrpc.WalletKitClient interface.
+func (r *restWalletKitClient) BumpFee(ctx context.Context,
+	in *walletrpc.BumpFeeRequest, _ ...grpc.CallOption) (
+	*walletrpc.BumpFeeResponse, error) {
+
+	resp := &walletrpc.BumpFeeResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v2/wallet/bumpfee", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ListSweeps is part of the walletrpc.WalletKitClient interface.
+func (r *restWalletKitClient) ListSweeps(ctx context.Context,
+	in *walletrpc.ListSweepsRequest, _ ...grpc.CallOption) (
+	*walletrpc.ListSweepsResponse, error) {
+
+	resp := &walletrpc.ListSweepsResponse{}
+	err := r.conn.call(
+		ctx, http.MethodGet, "/v2/wallet/sweeps", false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// LabelTransaction is part of the walletrpc.WalletKitClient interface.
+func (r *restWalletKitClient) LabelTransaction(ctx context.Context,
+	in *walletrpc.LabelTransactionRequest, _ ...grpc.CallOption) (
+	*walletrpc.LabelTransactionResponse, error) {
+
+	resp := &walletrpc.LabelTransactionResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v2/wallet/tx/label", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ListLeases is part of the walletrpc.WalletKitClient interface.
+func (r *restWalletKitClient) ListLeases(ctx context.Context,
+	in *walletrpc.ListLeasesRequest, _ ...grpc.CallOption) (
+	*walletrpc.ListLeasesResponse, error) {
+
+	resp := &walletrpc.ListLeasesResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v2/wallet/utxos/leases", false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ListAccounts is part of the walletrpc.WalletKitClient interface.
+func (r *restWalletKitClient) ListAccounts(ctx context.Context,
+	in *walletrpc.ListAccountsRequest, _ ...grpc.CallOption) (
+	*walletrpc.ListAccountsResponse, error) {
+
+	resp := &walletrpc.ListAccountsResponse{}
+	err := r.conn.call(
+		ctx, http.MethodGet, "/v2/wallet/accounts", false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ImportAccount is part of the walletrpc.WalletKitClient interface.
+func (r *restWalletKitClient) ImportAccount(ctx context.Context,
+	in *walletrpc.ImportAccountRequest, _ ...grpc.CallOption) (
+	*walletrpc.ImportAccountResponse, error) {
+
+	resp := &walletrpc.ImportAccountResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v2/wallet/accounts/import", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ImportPublicKey is part of the walletrpc.WalletKitClient interface.
+func (r *restWalletKitClient) ImportPublicKey(ctx context.Context,
+	in *walletrpc.ImportPublicKeyRequest, _ ...grpc.CallOption) (
+	*walletrpc.ImportPublicKeyResponse, error) {
+
+	resp := &walletrpc.ImportPublicKeyResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v2/wallet/key/import", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// FundPsbt is part of the walletrpc.WalletKitClient interface.
+func (r *restWalletKitClient) FundPsbt(ctx context.Context,
+	in *walletrpc.FundPsbtRequest, _ ...grpc.CallOption) (
+	*walletrpc.FundPsbtResponse, error) {
+
+	resp := &walletrpc.FundPsbtResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v2/wallet/psbt/fund", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// SignPsbt is part of the walletrpc.WalletKitClient interface.
+func (r *restWalletKitClient) SignPsbt(ctx context.Context,
+	in *walletrpc.SignPsbtRequest, _ ...grpc.CallOption) (
+	*walletrpc.SignPsbtResponse, error) {
+
+	resp := &walletrpc.SignPsbtResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v2/wallet/psbt/sign", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// FinalizePsbt is part of the walletrpc.WalletKitClient interface.
+func (r *restWalletKitClient) FinalizePsbt(ctx context.Context,
+	in *walletrpc.FinalizePsbtRequest, _ ...grpc.CallOption) (
+	*walletrpc.FinalizePsbtResponse, error) {
+
+	resp := &walletrpc.FinalizePsbtResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v2/wallet/psbt/finalize", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// restInvoicesClient is an implementation of the invoicesrpc.InvoicesClient
+// interface on top of lnd's REST proxy.
+type restInvoicesClient struct {
+	conn *restConn
+}
+
+// A compile time check to ensure that restInvoicesClient implements the
+// invoicesrpc.InvoicesClient interface.
+var _ invoicesrpc.InvoicesClient = (*restInvoicesClient)(nil)
+
+// SubscribeSingleInvoice is part of the invoicesrpc.InvoicesClient interface.
+func (r *restInvoicesClient) SubscribeSingleInvoice(ctx context.Context,
+	in *invoicesrpc.SubscribeSingleInvoiceRequest, _ ...grpc.CallOption) (
+	invoicesrpc.Invoices_SubscribeSingleInvoiceClient, error) {
+
+	stream, err := r.conn.stream(
+		ctx, http.MethodGet, "/v2/invoices/subscribe/{r_hash}", false, in,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &restInvoicesSubscribeSingleInvoiceStream{stream}, nil
+}
+
+// CancelInvoice is part of the invoicesrpc.InvoicesClient interface.
+func (r *restInvoicesClient) CancelInvoice(ctx context.Context,
+	in *invoicesrpc.CancelInvoiceMsg, _ ...grpc.CallOption) (
+	*invoicesrpc.CancelInvoiceResp, error) {
+
+	resp := &invoicesrpc.CancelInvoiceResp{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v2/invoices/cancel", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// AddHoldInvoice is part of the invoicesrpc.InvoicesClient interface.
+func (r *restInvoicesClient) AddHoldInvoice(ctx context.Context,
+	in *invoicesrpc.AddHoldInvoiceRequest, _ ...grpc.CallOption) (
+	*invoicesrpc.AddHoldInvoiceResp, error) {
+
+	resp := &invoicesrpc.AddHoldInvoiceResp{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v2/invoices/hodl", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// SettleInvoice is part of the invoicesrpc.InvoicesClient interface.
+func (r *restInvoicesClient) SettleInvoice(ctx context.Context,
+	in *invoicesrpc.SettleInvoiceMsg, _ ...grpc.CallOption) (
+	*invoicesrpc.SettleInvoiceResp, error) {
+
+	resp := &invoicesrpc.SettleInvoiceResp{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v2/invoices/settle", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// LookupInvoiceV2 is part of the invoicesrpc.InvoicesClient interface.
+func (r *restInvoicesClient) LookupInvoiceV2(ctx context.Context,
+	in *invoicesrpc.LookupInvoiceMsg, _ ...grpc.CallOption) (
+	*lnrpc.Invoice, error) {
+
+	resp := &lnrpc.Invoice{}
+	err := r.conn.call(
+		ctx, http.MethodGet, "/v2/invoices/lookup", false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// restRouterClient is an implementation of the routerrpc.RouterClient interface
+// on top of lnd's REST proxy.
+type restRouterClient struct {
+	conn *restConn
+}
+
+// A compile time check to ensure that restRouterClient implements the
+// routerrpc.RouterClient interface.
+var _ routerrpc.RouterClient = (*restRouterClient)(nil)
+
+// SendPaymentV2 is part of the routerrpc.RouterClient interface.
+func (r *restRouterClient) SendPaymentV2(ctx context.Context,
+	in *routerrpc.SendPaymentRequest, _ ...grpc.CallOption) (
+	routerrpc.Router_SendPaymentV2Client, error) {
+
+	stream, err := r.conn.stream(
+		ctx, http.MethodPost, "/v2/router/send", true, in,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &restRouterSendPaymentV2Stream{stream}, nil
+}
+
+// TrackPaymentV2 is part of the routerrpc.RouterClient interface.
+func (r *restRouterClient) TrackPaymentV2(ctx context.Context,
+	in *routerrpc.TrackPaymentRequest, _ ...grpc.CallOption) (
+	routerrpc.Router_TrackPaymentV2Client, error) {
+
+	stream, err := r.conn.stream(
+		ctx, http.MethodGet, "/v2/router/track/{payment_hash}", false, in,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &restRouterTrackPaymentV2Stream{stream}, nil
+}
+
+// EstimateRouteFee is part of the routerrpc.RouterClient interface.
+func (r *restRouterClient) EstimateRouteFee(ctx context.Context,
+	in *routerrpc.RouteFeeRequest, _ ...grpc.CallOption) (
+	*routerrpc.RouteFeeResponse, error) {
+
+	resp := &routerrpc.RouteFeeResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v2/router/route/estimatefee", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// SendToRoute is part of the routerrpc.RouterClient interface. It is not
+// available through lnd's REST proxy.
+func (r *restRouterClient) SendToRoute(_ context.Context,
+	_ *routerrpc.SendToRouteRequest, _ ...grpc.CallOption) (
+	*routerrpc.SendToRouteResponse, error) {
+
+	return nil, errRestUnsupported("SendToRoute")
+}
+
+// SendToRouteV2 is part of the routerrpc.RouterClient interface.
+func (r *restRouterClient) SendToRouteV2(ctx context.Context,
+	in *routerrpc.SendToRouteRequest, _ ...grpc.CallOption) (
+	*lnrpc.HTLCAttempt, error) {
+
+	resp := &lnrpc.HTLCAttempt{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v2/router/route/send", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ResetMissionControl is part of the routerrpc.RouterClient interface.
+func (r *restRouterClient) ResetMissionControl(ctx context.Context,
+	in *routerrpc.ResetMissionControlRequest, _ ...grpc.CallOption) (
+	*routerrpc.ResetMissionControlResponse, error) {
+
+	resp := &routerrpc.ResetMissionControlResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v2/router/mc/reset", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// QueryMissionControl is part of the routerrpc.RouterClient interface.
+func (r *restRouterClient) QueryMissionControl(ctx context.Context,
+	in *routerrpc.QueryMissionControlRequest, _ ...grpc.CallOption) (
+	*routerrpc.QueryMissionControlResponse, error) {
+
+	resp := &routerrpc.QueryMissionControlResponse{}
+	err := r.conn.call(
+		ctx, http.MethodGet, "/v2/router/mc", false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// QueryProbability is part of the routerrpc.RouterClient interface.
+func (r *restRouterClient) QueryProbability(ctx context.Context,
+	in *routerrpc.QueryProbabilityRequest, _ ...grpc.CallOption) (
+	*routerrpc.QueryProbabilityResponse, error) {
+
+	resp := &routerrpc.QueryProbabilityResponse{}
+	err := r.conn.call(
+		ctx, http.MethodGet,
+		"/v2/router/mc/probability/{from_node}/{to_node}/{amt_msat}",
+		false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// BuildRoute is part of the routerrpc.RouterClient interface.
+func (r *restRouterClient) BuildRoute(ctx context.Context,
+	in *routerrpc.BuildRouteRequest, _ ...grpc.CallOption) (
+	*routerrpc.BuildRouteResponse, error) {
+
+	resp := &routerrpc.BuildRouteResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v2/router/route", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// SubscribeHtlcEvents is part of the routerrpc.RouterClient interface.
+func (r *restRouterClient) SubscribeHtlcEvents(ctx context.Context,
+	in *routerrpc.SubscribeHtlcEventsRequest, _ ...grpc.CallOption) (
+	routerrpc.Router_SubscribeHtlcEventsClient, error) {
+
+	stream, err := r.conn.stream(
+		ctx, http.MethodGet, "/v2/router/htlcevents", false, in,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &restRouterSubscribeHtlcEventsStream{stream}, nil
+}
+
+// SendPayment is part of the routerrpc.RouterClient interface. It is not
+// available through lnd's REST proxy.
+func (r *restRouterClient) SendPayment(_ context.Context,
+	_ *routerrpc.SendPaymentRequest, _ ...grpc.CallOption) (
+	routerrpc.Router_SendPaymentClient, error) {
+
+	return nil, errRestUnsupported("SendPayment")
+}
+
+// TrackPayment is part of the routerrpc.RouterClient interface. It is not
+// available through lnd's REST proxy.
+func (r *restRouterClient) TrackPayment(_ context.Context,
+	_ *routerrpc.TrackPaymentRequest, _ ...grpc.CallOption) (
+	routerrpc.Router_TrackPaymentClient, error) {
+
+	return nil, errRestUnsupported("TrackPayment")
+}
+
+// HtlcInterceptor is part of the routerrpc.RouterClient interface. It is not
+// available through lnd's REST proxy.
+func (r *restRouterClient) HtlcInterceptor(_ context.Context,
+	_ ...grpc.CallOption) (routerrpc.Router_HtlcInterceptorClient, error) {
+
+	return nil, errRestUnsupported("HtlcInterceptor")
+}
+
+// XImportMissionControl is part of the routerrpc.RouterClient interface.
+func (r *restRouterClient) XImportMissionControl(ctx context.Context,
+	in *routerrpc.XImportMissionControlRequest, _ ...grpc.CallOption) (
+	*routerrpc.XImportMissionControlResponse, error) {
+
+	resp := &routerrpc.XImportMissionControlResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v2/router/x/importhistory", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// GetMissionControlConfig is part of the routerrpc.RouterClient interface.
+func (r *restRouterClient) GetMissionControlConfig(ctx context.Context,
+	in *routerrpc.GetMissionControlConfigRequest, _ ...grpc.CallOption) (
+	*routerrpc.GetMissionControlConfigResponse, error) {
+
+	resp := &routerrpc.GetMissionControlConfigResponse{}
+	err := r.conn.call(
+		ctx, http.MethodGet, "/v2/router/mccfg", false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// SetMissionControlConfig is part of the routerrpc.RouterClient interface.
+func (r *restRouterClient) SetMissionControlConfig(ctx context.Context,
+	in *routerrpc.SetMissionControlConfigRequest, _ ...grpc.CallOption) (
+	*routerrpc.SetMissionControlConfigResponse, error) {
+
+	resp := &routerrpc.SetMissionControlConfigResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v2/router/mccfg", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// UpdateChanStatus is part of the routerrpc.RouterClient interface.
+func (r *restRouterClient) UpdateChanStatus(ctx context.Context,
+	in *routerrpc.UpdateChanStatusRequest, _ ...grpc.CallOption) (
+	*routerrpc.UpdateChanStatusResponse, error) {
+
+	resp := &routerrpc.UpdateChanStatusResponse{}
+	err := r.conn.call(
+		ctx, http.MethodPost, "/v2/router/updatechanstatus", true, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// restVersionerClient is an implementation of the verrpc.VersionerClient
+// interface on top of lnd's REST proxy.
+type restVersionerClient struct {
+	conn *restConn
+}
+
+// A compile time check to ensure that restVersionerClient implements the
+// verrpc.VersionerClient interface.
+var _ verrpc.VersionerClient = (*restVersionerClient)(nil)
+
+// GetVersion is part of the verrpc.VersionerClient interface.
+func (r *restVersionerClient) GetVersion(ctx context.Context,
+	in *verrpc.VersionRequest, _ ...grpc.CallOption) (
+	*verrpc.Version, error) {
+
+	resp := &verrpc.Version{}
+	err := r.conn.call(
+		ctx, http.MethodGet, "/v2/versioner/version", false, in, resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// restLightningSubscribeTransactionsStream is a
+// lnrpc.Lightning_SubscribeTransactionsClient that reads its messages from
+// lnd's REST proxy.
+type restLightningSubscribeTransactionsStream struct {
+	*restStream
+}
+
+// Recv returns the next message of the stream.
+func (s *restLightningSubscribeTransactionsStream) Recv() (
+	*lnrpc.Transaction, error) {
+
+	resp := &lnrpc.Transaction{}
+	if err := s.RecvMsg(resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// restLightningSubscribePeerEventsStream is a
+// lnrpc.Lightning_SubscribePeerEventsClient that reads its messages from lnd's
+// REST proxy.
+type restLightningSubscribePeerEventsStream struct {
+	*restStream
+}
+
+// Recv returns the next message of the stream.
+func (s *restLightningSubscribePeerEventsStream) Recv() (
+	*lnrpc.PeerEvent, error) {
+
+	resp := &lnrpc.PeerEvent{}
+	if err := s.RecvMsg(resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// restLightningSubscribeChannelEventsStream is a
+// lnrpc.Lightning_SubscribeChannelEventsClient that reads its messages from
+// lnd's REST proxy.
+type restLightningSubscribeChannelEventsStream struct {
+	*restStream
+}
+
+// Recv returns the next message of the stream.
+func (s *restLightningSubscribeChannelEventsStream) Recv() (
+	*lnrpc.ChannelEventUpdate, error) {
+
+	resp := &lnrpc.ChannelEventUpdate{}
+	if err := s.RecvMsg(resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// restLightningOpenChannelStream is a lnrpc.Lightning_OpenChannelClient that
+// reads its messages from lnd's REST proxy.
+type restLightningOpenChannelStream struct {
+	*restStream
+}
+
+// Recv returns the next message of the stream.
+func (s *restLightningOpenChannelStream) Recv() (
+	*lnrpc.OpenStatusUpdate, error) {
+
+	resp := &lnrpc.OpenStatusUpdate{}
+	if err := s.RecvMsg(resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// restLightningCloseChannelStream is a lnrpc.Lightning_CloseChannelClient that
+// reads its messages from lnd's REST proxy.
+type restLightningCloseChannelStream struct {
+	*restStream
+}
+
+// Recv returns the next message of the stream.
+func (s *restLightningCloseChannelStream) Recv() (
+	*lnrpc.CloseStatusUpdate, error) {
+
+	resp := &lnrpc.CloseStatusUpdate{}
+	if err := s.RecvMsg(resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// restLightningSubscribeInvoicesStream is a
+// lnrpc.Lightning_SubscribeInvoicesClient that reads its messages from lnd's
+// REST proxy.
+type restLightningSubscribeInvoicesStream struct {
+	*restStream
+}
+
+// Recv returns the next message of the stream.
+func (s *restLightningSubscribeInvoicesStream) Recv() (*lnrpc.Invoice, error) {
+	resp := &lnrpc.Invoice{}
+	if err := s.RecvMsg(resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// restLightningSubscribeChannelGraphStream is a
+// lnrpc.Lightning_SubscribeChannelGraphClient that reads its messages from
+// lnd's REST proxy.
+type restLightningSubscribeChannelGraphStream struct {
+	*restStream
+}
+
+// Recv returns the next message of the stream.
+func (s *restLightningSubscribeChannelGraphStream) Recv() (
+	*lnrpc.GraphTopologyUpdate, error) {
+
+	resp := &lnrpc.GraphTopologyUpdate{}
+	if err := s.RecvMsg(resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// restLightningSubscribeChannelBackupsStream is a
+// lnrpc.Lightning_SubscribeChannelBackupsClient that reads its messages from
+// lnd's REST proxy.
+type restLightningSubscribeChannelBackupsStream struct {
+	*restStream
+}
+
+// Recv returns the next message of the stream.
+func (s *restLightningSubscribeChannelBackupsStream) Recv() (
+	*lnrpc.ChanBackupSnapshot, error) {
+
+	resp := &lnrpc.ChanBackupSnapshot{}
+	if err := s.RecvMsg(resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// restChainNotifierRegisterConfirmationsNtfnStream is a
+// chainrpc.ChainNotifier_RegisterConfirmationsNtfnClient that reads its
+// messages from lnd's REST proxy.
+type restChainNotifierRegisterConfirmationsNtfnStream struct {
+	*restStream
+}
+
+// Recv returns the next message of the stream.
+func (s *restChainNotifierRegisterConfirmationsNtfnStream) Recv() (
+	*chainrpc.ConfEvent, error) {
+
+	resp := &chainrpc.ConfEvent{}
+	if err := s.RecvMsg(resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// restChainNotifierRegisterSpendNtfnStream is a
+// chainrpc.ChainNotifier_RegisterSpendNtfnClient that reads its messages from
+// lnd's REST proxy.
+type restChainNotifierRegisterSpendNtfnStream struct {
+	*restStream
+}
+
+// Recv returns the next message of the stream.
+func (s *restChainNotifierRegisterSpendNtfnStream) Recv() (
+	*chainrpc.SpendEvent, error) {
+
+	resp := &chainrpc.SpendEvent{}
+	if err := s.RecvMsg(resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// restChainNotifierRegisterBlockEpochNtfnStream is a
+// chainrpc.ChainNotifier_RegisterBlockEpochNtfnClient that reads its messages
+// from lnd's REST proxy.
+type restChainNotifierRegisterBlockEpochNtfnStream struct {
+	*restStream
+}
+
+// Recv returns the next message of the stream.
+func (s *restChainNotifierRegisterBlockEpochNtfnStream) Recv() (
+	*chainrpc.BlockEpoch, error) {
+
+	resp := &chainrpc.BlockEpoch{}
+	if err := s.RecvMsg(resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// restInvoicesSubscribeSingleInvoiceStream is a
+// invoicesrpc.Invoices_SubscribeSingleInvoiceClient that reads its messages
+// from lnd's REST proxy.
+type restInvoicesSubscribeSingleInvoiceStream struct {
+	*restStream
+}
+
+// Recv returns the next message of the stream.
+func (s *restInvoicesSubscribeSingleInvoiceStream) Recv() (
+	*lnrpc.Invoice, error) {
+
+	resp := &lnrpc.Invoice{}
+	if err := s.RecvMsg(resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// restRouterSendPaymentV2Stream is a routerrpc.Router_SendPaymentV2Client that
+// reads its messages from lnd's REST proxy.
+type restRouterSendPaymentV2Stream struct {
+	*restStream
+}
+
+// Recv returns the next message of the stream.
+func (s *restRouterSendPaymentV2Stream) Recv() (*lnrpc.Payment, error) {
+	resp := &lnrpc.Payment{}
+	if err := s.RecvMsg(resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// restRouterTrackPaymentV2Stream is a routerrpc.Router_TrackPaymentV2Client
+// that reads its messages from lnd's REST proxy.
+type restRouterTrackPaymentV2Stream struct {
+	*restStream
+}
+
+// Recv returns the next message of the stream.
+func (s *restRouterTrackPaymentV2Stream) Recv() (*lnrpc.Payment, error) {
+	resp := &lnrpc.Payment{}
+	if err := s.RecvMsg(resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// restRouterSubscribeHtlcEventsStream is a
+// routerrpc.Router_SubscribeHtlcEventsClient that reads its messages from lnd's
+// REST proxy.
+type restRouterSubscribeHtlcEventsStream struct {
+	*restStream
+}
+
+// Recv returns the next message of the stream.
+func (s *restRouterSubscribeHtlcEventsStream) Recv() (
+	*routerrpc.HtlcEvent, error) {
+
+	resp := &routerrpc.HtlcEvent{}
+	if err := s.RecvMsg(resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// restLightningSubscribeCustomMessagesStream is a
+// lnrpc.Lightning_SubscribeCustomMessagesClient on top of a REST stream.
+type restLightningSubscribeCustomMessagesStream struct {
+	*restStream
+}
+
+// Recv returns the next message of the stream.
+func (s *restLightningSubscribeCustomMessagesStream) Recv() (
+	*lnrpc.CustomMessage, error) {
+
+	resp := &lnrpc.CustomMessage{}
+	if err := s.RecvMsg(resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
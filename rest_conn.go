@@ -0,0 +1,424 @@
+package lndclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// restMarshaler is the marshaler we use to encode requests for lnd's
+	// REST proxy. It uses the same settings as lnd's proxy itself.
+	restMarshaler = &jsonpb.Marshaler{
+		OrigName:     true,
+		EmitDefaults: true,
+	}
+
+	// restUnmarshaler is the unmarshaler we use to decode responses from
+	// lnd's REST proxy. Unknown fields are allowed so we can talk to newer
+	// versions of lnd that added fields to their responses.
+	restUnmarshaler = &jsonpb.Unmarshaler{
+		AllowUnknownFields: true,
+	}
+
+	// restPathParam matches a single parameter in a REST path template,
+	// for example {chan_id} in /v1/graph/edge/{chan_id}.
+	restPathParam = regexp.MustCompile(`{([^}]+)}`)
+
+	// restMetadataPrefix is the HTTP header prefix that lnd's REST proxy
+	// translates into gRPC metadata, which is how macaroons are passed.
+	restMetadataPrefix = "Grpc-Metadata-"
+)
+
+// errRestUnsupported returns the error for an RPC that is not available
+// through lnd's REST proxy, which is true for all client side and
+// bidirectional streaming RPCs.
+func errRestUnsupported(method string) error {
+	return status.Errorf(codes.Unimplemented, "%v is not available "+
+		"through lnd's REST proxy", method)
+}
+
+// restConn is a connection to lnd's REST proxy. All RPC calls are translated
+// into HTTP requests against the proxy, using the same JSON mapping lnd's
+// proxy uses to translate them into gRPC calls.
+type restConn struct {
+	baseURL string
+	client  *http.Client
+}
+
+// newRestConn creates a new connection to lnd's REST proxy using the TLS
+// certificate and dialer of the given configuration.
+func newRestConn(cfg *LndServicesConfig) (*restConn, error) {
+	tlsPath := cfg.TLSPath
+	if tlsPath == "" {
+		tlsPath = defaultTLSCertPath
+	}
+
+	certBytes, err := ioutil.ReadFile(tlsPath)
+	if err != nil {
+		return nil, err
+	}
+	certPool := x509.NewCertPool()
+	if !certPool.AppendCertsFromPEM(certBytes) {
+		return nil, fmt.Errorf("unable to parse TLS certificate %v",
+			tlsPath)
+	}
+
+	// The host of the URL always needs a port, otherwise the default HTTPS
+	// port would be used instead of lnd's default REST port.
+	host := cfg.LndAddress
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, defaultRESTPort)
+	}
+
+	transport := &http.Transport{
+		TLSClientConfig: &tls.Config{
+			RootCAs: certPool,
+		},
+		DialContext: func(ctx context.Context, _,
+			addr string) (net.Conn, error) {
+
+			return cfg.Dialer(ctx, addr)
+		},
+	}
+
+	return &restConn{
+		baseURL: "https://" + host,
+		client: &http.Client{
+			Transport: transport,
+		},
+	}, nil
+}
+
+// Close closes all idle connections to lnd's REST proxy.
+func (c *restConn) Close() error {
+	c.client.CloseIdleConnections()
+
+	return nil
+}
+
+// call executes a unary RPC through lnd's REST proxy and decodes the result
+// into resp.
+func (c *restConn) call(ctx context.Context, method, pathTemplate string,
+	hasBody bool, req, resp proto.Message) error {
+
+	httpResp, err := c.do(ctx, method, pathTemplate, hasBody, req)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	return restUnmarshaler.Unmarshal(httpResp.Body, resp)
+}
+
+// stream starts a server side streaming RPC through lnd's REST proxy. The
+// messages can then be read from the returned stream.
+func (c *restConn) stream(ctx context.Context, method, pathTemplate string,
+	hasBody bool, req proto.Message) (*restStream, error) {
+
+	httpResp, err := c.do(ctx, method, pathTemplate, hasBody, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &restStream{
+		ctx:     ctx,
+		body:    httpResp.Body,
+		decoder: json.NewDecoder(httpResp.Body),
+	}, nil
+}
+
+// do sends the given request to lnd's REST proxy and returns the response if
+// the proxy reported success. Any error reported by the proxy is converted
+// back into a gRPC status error.
+func (c *restConn) do(ctx context.Context, method, pathTemplate string,
+	hasBody bool, req proto.Message) (*http.Response, error) {
+
+	httpReq, err := c.newRequest(ctx, method, pathTemplate, hasBody, req)
+	if err != nil {
+		return nil, err
+	}
+
+	httpResp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, restContextErr(ctx, err)
+	}
+
+	if httpResp.StatusCode != http.StatusOK {
+		defer httpResp.Body.Close()
+
+		return nil, restStatusErr(httpResp)
+	}
+
+	return httpResp, nil
+}
+
+// newRequest creates the HTTP request for an RPC. The request message is
+// either sent as the body or, for methods without a body, encoded into the
+// path and query parameters. Any gRPC metadata of the context, which includes
+// the macaroon, is added as headers.
+func (c *restConn) newRequest(ctx context.Context, method,
+	pathTemplate string, hasBody bool, req proto.Message) (*http.Request,
+	error) {
+
+	reqJSON, err := restMarshaler.MarshalToString(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var body io.Reader
+	query := make(url.Values)
+	if hasBody {
+		body = strings.NewReader(reqJSON)
+	} else {
+		fields := make(map[string]interface{})
+		decoder := json.NewDecoder(strings.NewReader(reqJSON))
+		decoder.UseNumber()
+		if err := decoder.Decode(&fields); err != nil {
+			return nil, err
+		}
+		flattenRestQuery("", fields, query)
+	}
+
+	// Fill in all path parameters. Their values are removed from the query
+	// as the proxy doesn't allow a field to be set twice.
+	var missing []string
+	path := restPathParam.ReplaceAllStringFunc(pathTemplate,
+		func(param string) string {
+			name := param[1 : len(param)-1]
+			values, ok := query[name]
+			if !ok || len(values) != 1 {
+				missing = append(missing, name)
+				return param
+			}
+			query.Del(name)
+
+			// Bytes are base64 encoded, which needs to be the URL
+			// safe variant when used in a path.
+			value := strings.NewReplacer("+", "-", "/", "_").Replace(
+				values[0],
+			)
+			return url.PathEscape(value)
+		},
+	)
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing REST path parameters %v",
+			missing)
+	}
+
+	reqURL := c.baseURL + path
+	if len(query) > 0 {
+		reqURL += "?" + query.Encode()
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, reqURL, body)
+	if err != nil {
+		return nil, err
+	}
+	if hasBody {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+
+	md, _ := metadata.FromOutgoingContext(ctx)
+	for key, values := range md {
+		for _, value := range values {
+			httpReq.Header.Add(restMetadataPrefix+key, value)
+		}
+	}
+
+	return httpReq, nil
+}
+
+// flattenRestQuery adds all scalar fields of a JSON encoded message to the
+// query values, using the dot notation for nested messages that lnd's REST
+// proxy expects. Repeated scalars are added as repeated values. Fields that
+// can't be represented in a query, like repeated messages, are skipped.
+func flattenRestQuery(prefix string, value interface{}, query url.Values) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			flattenRestQuery(prefix+key+".", field, query)
+		}
+
+	case []interface{}:
+		for _, elem := range v {
+			switch elem.(type) {
+			case map[string]interface{}, []interface{}, nil:
+				continue
+			}
+			flattenRestQuery(prefix, elem, query)
+		}
+
+	case nil:
+
+	default:
+		query.Add(strings.TrimSuffix(prefix, "."), fmt.Sprintf("%v", v))
+	}
+}
+
+// restError is the error body of a failed unary call to lnd's REST proxy.
+type restError struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+	Code    int32  `json:"code"`
+}
+
+// restStatusErr converts the error body of a failed request to lnd's REST
+// proxy into a gRPC status error.
+func restStatusErr(httpResp *http.Response) error {
+	var restErr restError
+	err := json.NewDecoder(httpResp.Body).Decode(&restErr)
+	if err != nil || restErr.Code == 0 {
+		return status.Errorf(codes.Unknown, "REST proxy returned %v",
+			httpResp.Status)
+	}
+
+	msg := restErr.Message
+	if msg == "" {
+		msg = restErr.Error
+	}
+
+	return status.Error(codes.Code(restErr.Code), msg)
+}
+
+// restContextErr converts an error of an HTTP request to the gRPC status error
+// gRPC would have returned, if the error was caused by the context being
+// canceled or timing out.
+func restContextErr(ctx context.Context, err error) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return status.Error(codes.Canceled, ctx.Err().Error())
+
+	case context.DeadlineExceeded:
+		return status.Error(codes.DeadlineExceeded, ctx.Err().Error())
+
+	default:
+		return err
+	}
+}
+
+// restStreamChunk is a single message of a server side stream of lnd's REST
+// proxy. Either the result or the error is set.
+type restStreamChunk struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		GrpcCode int32  `json:"grpc_code"`
+		Message  string `json:"message"`
+	} `json:"error"`
+}
+
+// restStream is a server side stream of lnd's REST proxy. It implements the
+// grpc.ClientStream interface so it can be used in place of gRPC streams.
+type restStream struct {
+	ctx     context.Context
+	body    io.ReadCloser
+	decoder *json.Decoder
+}
+
+// Header returns the header metadata of the stream, which isn't available
+// through lnd's REST proxy.
+func (s *restStream) Header() (metadata.MD, error) {
+	return metadata.MD{}, nil
+}
+
+// Trailer returns the trailer metadata of the stream, which isn't available
+// through lnd's REST proxy.
+func (s *restStream) Trailer() metadata.MD {
+	return metadata.MD{}
+}
+
+// CloseSend closes the sending side of the stream. As there are no client
+// side streams through lnd's REST proxy, this is a no-op.
+func (s *restStream) CloseSend() error {
+	return nil
+}
+
+// Context returns the context of the stream.
+func (s *restStream) Context() context.Context {
+	return s.ctx
+}
+
+// SendMsg is not supported as there are no client side streams through lnd's
+// REST proxy.
+func (s *restStream) SendMsg(_ interface{}) error {
+	return errRestUnsupported("SendMsg")
+}
+
+// RecvMsg reads the next message of the stream into m, which must be a
+// proto.Message. If the stream ended, io.EOF is returned.
+func (s *restStream) RecvMsg(m interface{}) error {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return fmt.Errorf("unexpected message type %T", m)
+	}
+
+	var chunk restStreamChunk
+	if err := s.decoder.Decode(&chunk); err != nil {
+		s.body.Close()
+
+		if err == io.EOF {
+			return io.EOF
+		}
+		return restContextErr(s.ctx, err)
+	}
+
+	if chunk.Error != nil {
+		s.body.Close()
+
+		return status.Error(
+			codes.Code(chunk.Error.GrpcCode), chunk.Error.Message,
+		)
+	}
+
+	if chunk.Result == nil {
+		return errors.New("REST stream message without result")
+	}
+
+	return restUnmarshaler.Unmarshal(bytes.NewReader(chunk.Result), msg)
+}
+
+// restChannelPoint returns a copy of the channel point that uses the string
+// encoding of the funding txid, which lnd's REST proxy needs when the channel
+// point is part of a path.
+func restChannelPoint(chanPoint *lnrpc.ChannelPoint) (*lnrpc.ChannelPoint,
+	error) {
+
+	if chanPoint == nil {
+		return nil, errors.New("channel point required")
+	}
+
+	txidStr := chanPoint.GetFundingTxidStr()
+	if txidStr == "" {
+		txid, err := chainhash.NewHash(chanPoint.GetFundingTxidBytes())
+		if err != nil {
+			return nil, err
+		}
+		txidStr = txid.String()
+	}
+
+	return &lnrpc.ChannelPoint{
+		FundingTxid: &lnrpc.ChannelPoint_FundingTxidStr{
+			FundingTxidStr: txidStr,
+		},
+		OutputIndex: chanPoint.OutputIndex,
+	}, nil
+}
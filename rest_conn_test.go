@@ -0,0 +1,195 @@
+package lndclient
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newTestRestConn starts a TLS test server with the given handler and returns
+// a REST connection to it.
+func newTestRestConn(t *testing.T, handler http.HandlerFunc) (*restConn,
+	func()) {
+
+	server := httptest.NewTLSServer(handler)
+
+	tempDir, err := ioutil.TempDir("", "lndclient")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	tlsPath := filepath.Join(tempDir, "tls.cert")
+	certPem := pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: server.Certificate().Raw,
+	})
+	if err := ioutil.WriteFile(tlsPath, certPem, 0600); err != nil {
+		t.Fatalf("unable to write cert: %v", err)
+	}
+
+	var dialer net.Dialer
+	conn, err := newRestConn(&LndServicesConfig{
+		LndAddress: server.Listener.Addr().String(),
+		TLSPath:    tlsPath,
+		Dialer: func(ctx context.Context, addr string) (net.Conn,
+			error) {
+
+			return dialer.DialContext(ctx, "tcp", addr)
+		},
+	})
+	if err != nil {
+		t.Fatalf("unable to create REST connection: %v", err)
+	}
+
+	return conn, func() {
+		server.Close()
+		os.RemoveAll(tempDir)
+	}
+}
+
+// TestRestUnaryCall tests that unary calls are mapped to the correct REST
+// paths with query parameters and macaroon headers, and that errors are
+// converted back into gRPC status errors.
+func TestRestUnaryCall(t *testing.T) {
+	conn, cleanup := newTestRestConn(t, func(w http.ResponseWriter,
+		r *http.Request) {
+
+		if r.Header.Get("Grpc-Metadata-macaroon") != "abcd" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"no macaroon","code":16}`)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v1/graph/edge/123":
+			fmt.Fprint(w, `{"channel_id":"123","capacity":"500",`+
+				`"unknown_field":true}`)
+
+		case "/v1/graph/node/02aa":
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":"not found","message":`+
+				`"unable to find node","code":5}`)
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer cleanup()
+
+	client := &restLightningClient{conn: conn}
+	mac := serializedMacaroon("abcd")
+	ctx := mac.WithMacaroonAuth(context.Background())
+
+	edge, err := client.GetChanInfo(ctx, &lnrpc.ChanInfoRequest{
+		ChanId: 123,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if edge.ChannelId != 123 || edge.Capacity != 500 {
+		t.Fatalf("unexpected channel edge: %v", edge)
+	}
+
+	// Errors must be returned as status errors with the original code.
+	_, err = client.GetNodeInfo(ctx, &lnrpc.NodeInfoRequest{
+		PubKey: "02aa",
+	})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+
+	_, err = client.GetChanInfo(context.Background(),
+		&lnrpc.ChanInfoRequest{ChanId: 123},
+	)
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected unauthenticated error, got %v", err)
+	}
+
+	// Bidirectional streams are not available at all.
+	_, err = client.ChannelAcceptor(ctx)
+	if status.Code(err) != codes.Unimplemented {
+		t.Fatalf("expected unimplemented error, got %v", err)
+	}
+}
+
+// TestRestStream tests that server side streams are read message by message
+// until either an error or the end of the stream is reached.
+func TestRestStream(t *testing.T) {
+	conn, cleanup := newTestRestConn(t, func(w http.ResponseWriter,
+		r *http.Request) {
+
+		if r.URL.Path != "/v1/invoices/subscribe" ||
+			r.URL.Query().Get("add_index") != "7" {
+
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		fmt.Fprintln(w, `{"result":{"memo":"first","add_index":"8"}}`)
+		fmt.Fprintln(w, `{"result":{"memo":"second","add_index":"9"}}`)
+		fmt.Fprintln(w, `{"error":{"grpc_code":14,"message":"gone"}}`)
+	})
+	defer cleanup()
+
+	client := &restLightningClient{conn: conn}
+	stream, err := client.SubscribeInvoices(
+		context.Background(), &lnrpc.InvoiceSubscription{
+			AddIndex: 7,
+		},
+	)
+	if err != nil {
+		t.Fatalf("unable to subscribe: %v", err)
+	}
+
+	for _, memo := range []string{"first", "second"} {
+		invoice, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if invoice.Memo != memo {
+			t.Fatalf("expected memo %v, got %v", memo, invoice.Memo)
+		}
+	}
+
+	_, err = stream.Recv()
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected unavailable error, got %v", err)
+	}
+}
+
+// TestRestStreamEOF tests that the end of a stream is reported as io.EOF, just
+// like gRPC does.
+func TestRestStreamEOF(t *testing.T) {
+	conn, cleanup := newTestRestConn(t, func(w http.ResponseWriter,
+		r *http.Request) {
+
+		fmt.Fprintln(w, `{"result":{"node_updates":[]}}`)
+	})
+	defer cleanup()
+
+	client := &restLightningClient{conn: conn}
+	stream, err := client.SubscribeChannelGraph(
+		context.Background(), &lnrpc.GraphTopologySubscription{},
+	)
+	if err != nil {
+		t.Fatalf("unable to subscribe: %v", err)
+	}
+
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+}
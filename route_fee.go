@@ -0,0 +1,150 @@
+package lndclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/lightningnetwork/lnd/zpay32"
+)
+
+// RouteFeeRequest holds the parameters of a route fee estimate. Either the
+// destination and amount or an invoice must be set.
+type RouteFeeRequest struct {
+	// Dest is the destination to estimate the fee for.
+	Dest route.Vertex
+
+	// Amt is the amount to estimate the fee for. For invoices without an
+	// amount, it is the amount that is probed.
+	Amt btcutil.Amount
+
+	// Invoice is an encoded payment request to estimate the fee for. lnd
+	// v0.11 only estimates fees for a destination and amount, so invoices
+	// are estimated by sending a probe payment along a route to the
+	// invoice's destination, using its route hints and final CLTV delta.
+	Invoice string
+
+	// MaxFee is the fee limit of the probe payment. If zero, the amount of
+	// the payment is used.
+	MaxFee btcutil.Amount
+
+	// Timeout is the time lnd may spend on the probe payment. If zero, one
+	// minute is used.
+	Timeout time.Duration
+}
+
+// RouteFeeEstimate is the expected cost of a payment.
+type RouteFeeEstimate struct {
+	// RoutingFee is the fee that is paid to the nodes along the route.
+	RoutingFee lnwire.MilliSatoshi
+
+	// TimeLockDelay is the number of blocks that our funds may be locked
+	// up for in the worst case.
+	TimeLockDelay int64
+}
+
+// EstimateRouteFee returns the expected routing fee and time lock delay of a
+// payment. If the request holds an invoice, a probe payment with a random
+// payment hash is sent to its destination and the estimate is taken from the
+// route that reached it. Otherwise lnd estimates the fee to the destination
+// without sending a payment.
+func (r *routerClient) EstimateRouteFee(ctx context.Context,
+	req RouteFeeRequest) (*RouteFeeEstimate, error) {
+
+	if req.Invoice != "" {
+		return r.probeRouteFee(ctx, req)
+	}
+
+	rpcCtx, cancel := context.WithTimeout(ctx, rpcTimeout)
+	defer cancel()
+
+	rpcCtx = r.routerKitMac.WithMacaroonAuth(rpcCtx)
+	resp, err := r.client.EstimateRouteFee(
+		rpcCtx, &routerrpc.RouteFeeRequest{
+			Dest:   req.Dest[:],
+			AmtSat: int64(req.Amt),
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RouteFeeEstimate{
+		RoutingFee:    lnwire.MilliSatoshi(resp.RoutingFeeMsat),
+		TimeLockDelay: resp.TimeLockDelay,
+	}, nil
+}
+
+// probeRouteFee estimates the fee of paying an invoice with a probe payment.
+func (r *routerClient) probeRouteFee(ctx context.Context,
+	req RouteFeeRequest) (*RouteFeeEstimate, error) {
+
+	invoice, err := zpay32.Decode(req.Invoice, r.params)
+	if err != nil {
+		return nil, fmt.Errorf("invalid invoice: %v", err)
+	}
+
+	amt := req.Amt
+	if invoice.MilliSat != nil {
+		amt = invoice.MilliSat.ToSatoshis()
+	}
+	if amt == 0 {
+		return nil, errors.New("amount required for invoice without " +
+			"amount")
+	}
+
+	maxFee := req.MaxFee
+	if maxFee == 0 {
+		maxFee = amt
+	}
+
+	timeout := req.Timeout
+	if timeout == 0 {
+		timeout = defaultProbeTimeout
+	}
+
+	success, status, err := sendProbe(ctx, r, SendPaymentRequest{
+		Target:         route.NewVertex(invoice.Destination),
+		Amount:         amt,
+		MaxFee:         maxFee,
+		Timeout:        timeout,
+		FinalCLTVDelta: uint16(invoice.MinFinalCLTVExpiry()),
+		RouteHints:     invoice.RouteHints,
+		MaxParts:       1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !success {
+		return nil, fmt.Errorf("probe failed: %v", status.FailureReason)
+	}
+
+	// Find the attempt that reached the destination, which reports the
+	// height at which it received the HTLC.
+	for _, attempt := range status.Htlcs {
+		if attempt.Route == nil || attempt.Failure == nil {
+			continue
+		}
+
+		if attempt.Failure.Code !=
+			lnrpc.Failure_INCORRECT_OR_UNKNOWN_PAYMENT_DETAILS {
+
+			continue
+		}
+
+		return &RouteFeeEstimate{
+			RoutingFee: attempt.Route.TotalFees,
+			TimeLockDelay: int64(attempt.Route.TotalTimeLock) -
+				int64(attempt.Failure.Height),
+		}, nil
+	}
+
+	return nil, errors.New("probe succeeded without route to destination")
+}
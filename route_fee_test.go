@@ -0,0 +1,73 @@
+package lndclient
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+)
+
+// TestEstimateRouteFeeInvoice tests that invoice fee estimates are taken from
+// the probe route that reached the destination.
+func TestEstimateRouteFeeInvoice(t *testing.T) {
+	amt := lnwire.MilliSatoshi(100000000)
+	preimage := lntypes.Preimage{1}
+	invoice := newTestInvoice(t, preimage, &amt)
+	pubKey := route.Vertex{2}.String()
+
+	mock := &mockPaymentRouter{
+		sendStream: &mockPaymentStream{
+			updates: []*lnrpc.Payment{{
+				Status: lnrpc.Payment_FAILED,
+				FailureReason: lnrpc.
+					PaymentFailureReason_FAILURE_REASON_INCORRECT_PAYMENT_DETAILS,
+				Htlcs: []*lnrpc.HTLCAttempt{{
+					Status: lnrpc.HTLCAttempt_FAILED,
+					Route: &lnrpc.Route{
+						TotalTimeLock: 740,
+						TotalAmtMsat:  100001000,
+						TotalFeesMsat: 1000,
+						Hops: []*lnrpc.Hop{{
+							ChanId: 1,
+							PubKey: pubKey,
+						}, {
+							ChanId: 2,
+							PubKey: pubKey,
+						}},
+					},
+					Failure: &lnrpc.Failure{
+						Code:               lnrpc.Failure_INCORRECT_OR_UNKNOWN_PAYMENT_DETAILS,
+						FailureSourceIndex: 2,
+						Height:             600,
+					},
+				}},
+			}},
+		},
+	}
+	client := newRouterClient(mock, &chaincfg.RegressionNetParams, "")
+
+	estimate, err := client.EstimateRouteFee(
+		context.Background(), RouteFeeRequest{Invoice: invoice},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if estimate.RoutingFee != 1000 || estimate.TimeLockDelay != 140 {
+		t.Fatalf("unexpected estimate: %+v", estimate)
+	}
+
+	// The probe must not use the payment hash of the invoice.
+	if mock.sendReq.PaymentRequest != "" || mock.sendReq.Amt != 100000 {
+		t.Fatalf("unexpected probe request: %v", mock.sendReq)
+	}
+	hash := preimage.Hash()
+	if bytes.Equal(hash[:], mock.sendReq.PaymentHash) {
+		t.Fatalf("probe used the invoice payment hash")
+	}
+}
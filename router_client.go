@@ -7,16 +7,17 @@ import (
 	"io"
 	"time"
 
+	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcutil"
 	"github.com/lightningnetwork/lnd/channeldb"
 	"github.com/lightningnetwork/lnd/lnrpc"
 	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/verrpc"
 	"github.com/lightningnetwork/lnd/lntypes"
 	"github.com/lightningnetwork/lnd/lnwire"
 	"github.com/lightningnetwork/lnd/record"
 	"github.com/lightningnetwork/lnd/routing/route"
 	"github.com/lightningnetwork/lnd/zpay32"
-	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -35,8 +36,63 @@ type RouterClient interface {
 
 	// SubscribeHtlcEvents subscribes to a stream of htlc events from the
 	// router.
-	SubscribeHtlcEvents(ctx context.Context) (<-chan *routerrpc.HtlcEvent,
+	SubscribeHtlcEvents(ctx context.Context) (<-chan *HtlcEvent,
 		<-chan error, error)
+
+	// BuildRoute builds a route through the hops provided, using the
+	// current channel policies to calculate fees and timelocks.
+	BuildRoute(ctx context.Context, amt lnwire.MilliSatoshi,
+		finalCltvDelta int32, outgoingChannel *uint64,
+		hops []route.Vertex) (*Route, error)
+
+	// SendToRoute sends a single HTLC along the route provided and
+	// returns the result of the attempt.
+	SendToRoute(ctx context.Context, hash lntypes.Hash, route *Route) (
+		*HtlcAttempt, error)
+
+	// QueryMissionControl returns the history of all node pairs that
+	// mission control has recorded results for.
+	QueryMissionControl(ctx context.Context) (map[NodePair]*PairData,
+		error)
+
+	// ResetMissionControl clears all results recorded by mission control.
+	ResetMissionControl(ctx context.Context) error
+
+	// QueryProbability returns the probability that mission control
+	// assigns to sending the amount provided through the node pair.
+	QueryProbability(ctx context.Context, pair NodePair,
+		amt lnwire.MilliSatoshi) (float64, *PairData, error)
+
+	// ImportMissionControl adds the pair histories provided to mission
+	// control.
+	//
+	// NOTE: This requires lnd v0.13.0 or later.
+	ImportMissionControl(ctx context.Context,
+		pairs map[NodePair]*PairData) error
+
+	// GetMissionControlConfig returns the config of mission control.
+	//
+	// NOTE: This requires lnd v0.13.0 or later.
+	GetMissionControlConfig(ctx context.Context) (*MissionControlConfig,
+		error)
+
+	// SetMissionControlConfig changes the config of mission control.
+	//
+	// NOTE: This requires lnd v0.13.0 or later.
+	SetMissionControlConfig(ctx context.Context,
+		cfg *MissionControlConfig) error
+
+	// InterceptHtlcs registers an HTLC interceptor that holds forwarded
+	// HTLCs until the handler decides whether they are resumed, failed or
+	// settled.
+	InterceptHtlcs(ctx context.Context, timeout time.Duration,
+		defaultAction InterceptorAction,
+		handler HtlcInterceptHandler) (chan error, error)
+
+	// EstimateRouteFee returns the expected routing fee and time lock
+	// delay of a payment to a destination or of an invoice.
+	EstimateRouteFee(ctx context.Context, req RouteFeeRequest) (
+		*RouteFeeEstimate, error)
 }
 
 // PaymentStatus describe the state of a payment.
@@ -52,6 +108,9 @@ type PaymentStatus struct {
 	Value         lnwire.MilliSatoshi
 	InFlightAmt   lnwire.MilliSatoshi
 	InFlightHtlcs int
+
+	// Htlcs holds all HTLC attempts of the payment so far.
+	Htlcs []*HtlcAttempt
 }
 
 func (p PaymentStatus) String() string {
@@ -134,14 +193,19 @@ type SendPaymentRequest struct {
 // routerClient is a wrapper around the generated routerrpc proxy.
 type routerClient struct {
 	client       routerrpc.RouterClient
+	params       *chaincfg.Params
 	routerKitMac serializedMacaroon
+
+	// version is the version of the connected lnd, nil if unknown.
+	version *verrpc.Version
 }
 
-func newRouterClient(conn *grpc.ClientConn,
+func newRouterClient(client routerrpc.RouterClient, params *chaincfg.Params,
 	routerKitMac serializedMacaroon) *routerClient {
 
 	return &routerClient{
-		client:       routerrpc.NewRouterClient(conn),
+		client:       client,
+		params:       params,
 		routerKitMac: routerKitMac,
 	}
 }
@@ -317,6 +381,12 @@ func unmarshallPaymentStatus(rpcPayment *lnrpc.Payment) (
 	}
 
 	for _, htlc := range rpcPayment.Htlcs {
+		attempt, err := unmarshallHtlcAttempt(htlc)
+		if err != nil {
+			return nil, err
+		}
+		status.Htlcs = append(status.Htlcs, attempt)
+
 		if htlc.Status != lnrpc.HTLCAttempt_IN_FLIGHT {
 			continue
 		}
@@ -376,8 +446,9 @@ func marshallHopHint(hint zpay32.HopHint) (*lnrpc.HopHint, error) {
 }
 
 // SubscribeHtlcEvents subscribes to a stream of htlc events from the router.
+// Events of types that are unknown to us are skipped.
 func (r *routerClient) SubscribeHtlcEvents(ctx context.Context) (
-	<-chan *routerrpc.HtlcEvent, <-chan error, error) {
+	<-chan *HtlcEvent, <-chan error, error) {
 
 	stream, err := r.client.SubscribeHtlcEvents(
 		r.routerKitMac.WithMacaroonAuth(ctx),
@@ -390,7 +461,7 @@ func (r *routerClient) SubscribeHtlcEvents(ctx context.Context) (
 	// Buffer our error channel by 1 so we don't need to worry about the
 	// client not listening or shutting down when we send an error.
 	errChan := make(chan error, 1)
-	htlcChan := make(chan *routerrpc.HtlcEvent)
+	htlcChan := make(chan *HtlcEvent)
 
 	go func() {
 		// Close our error and htlc channel when this loop exits to
@@ -399,12 +470,19 @@ func (r *routerClient) SubscribeHtlcEvents(ctx context.Context) (
 		defer close(htlcChan)
 
 		for {
-			htlc, err := stream.Recv()
+			rpcEvent, err := stream.Recv()
 			if err != nil {
 				errChan <- err
 				return
 			}
 
+			htlc := newHtlcEvent(rpcEvent)
+			if htlc == nil {
+				log.Debugf("Skipping unknown htlc event: %v",
+					rpcEvent)
+				continue
+			}
+
 			// Send the update to into our events channel, or exit
 			// if our context has been cancelled.
 			select {
@@ -0,0 +1,451 @@
+package lndclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"github.com/lightningnetwork/lnd/zpay32"
+)
+
+// MppRecord is the record that is added to the final hop of multi-part
+// payments.
+type MppRecord struct {
+	// PaymentAddr is the payment address of the invoice.
+	PaymentAddr [32]byte
+
+	// TotalAmt is the total amount of the payment across all parts.
+	TotalAmt lnwire.MilliSatoshi
+}
+
+// Hop is a single hop of a route.
+type Hop struct {
+	// ChannelID is the channel that the HTLC is forwarded through to
+	// reach this hop.
+	ChannelID uint64
+
+	// PubKey is the public key of the node of this hop.
+	PubKey route.Vertex
+
+	// AmtToForward is the amount that this hop forwards to the next hop,
+	// or receives if it is the final hop.
+	AmtToForward lnwire.MilliSatoshi
+
+	// Fee is the fee that this hop charges for the forward.
+	Fee lnwire.MilliSatoshi
+
+	// Expiry is the expiry height of the HTLC that this hop forwards.
+	Expiry uint32
+
+	// TlvPayload indicates whether the hop receives a TLV onion payload
+	// instead of the legacy payload.
+	TlvPayload bool
+
+	// MppRecord is the multi-part payment record of the final hop.
+	MppRecord *MppRecord
+
+	// CustomRecords holds custom TLV records for this hop.
+	CustomRecords map[uint64][]byte
+}
+
+// Route is a path through the network.
+type Route struct {
+	// TotalTimeLock is the expiry height of the HTLC that we send to the
+	// first hop.
+	TotalTimeLock uint32
+
+	// TotalAmt is the amount that we send, including all fees.
+	TotalAmt lnwire.MilliSatoshi
+
+	// TotalFees is the sum of the fees of all hops.
+	TotalFees lnwire.MilliSatoshi
+
+	// Hops holds the hops of the route, starting with the first hop after
+	// our own node.
+	Hops []*Hop
+}
+
+// unmarshallRoute converts an rpc route.
+func unmarshallRoute(rpcRoute *lnrpc.Route) (*Route, error) {
+	hops := make([]*Hop, len(rpcRoute.Hops))
+	for i, rpcHop := range rpcRoute.Hops {
+		pubKey, err := route.NewVertexFromStr(rpcHop.PubKey)
+		if err != nil {
+			return nil, fmt.Errorf("hop %v: %v", i, err)
+		}
+
+		hop := &Hop{
+			ChannelID:     rpcHop.ChanId,
+			PubKey:        pubKey,
+			AmtToForward:  lnwire.MilliSatoshi(rpcHop.AmtToForwardMsat),
+			Fee:           lnwire.MilliSatoshi(rpcHop.FeeMsat),
+			Expiry:        rpcHop.Expiry,
+			TlvPayload:    rpcHop.TlvPayload,
+			CustomRecords: rpcHop.CustomRecords,
+		}
+
+		if rpcHop.MppRecord != nil {
+			hop.MppRecord = &MppRecord{
+				TotalAmt: lnwire.MilliSatoshi(
+					rpcHop.MppRecord.TotalAmtMsat,
+				),
+			}
+			copy(
+				hop.MppRecord.PaymentAddr[:],
+				rpcHop.MppRecord.PaymentAddr,
+			)
+		}
+
+		hops[i] = hop
+	}
+
+	return &Route{
+		TotalTimeLock: rpcRoute.TotalTimeLock,
+		TotalAmt:      lnwire.MilliSatoshi(rpcRoute.TotalAmtMsat),
+		TotalFees:     lnwire.MilliSatoshi(rpcRoute.TotalFeesMsat),
+		Hops:          hops,
+	}, nil
+}
+
+// marshall converts a route into its rpc counterpart.
+func (r *Route) marshall() *lnrpc.Route {
+	rpcRoute := &lnrpc.Route{
+		TotalTimeLock: r.TotalTimeLock,
+		TotalAmt:      int64(r.TotalAmt.ToSatoshis()),
+		TotalAmtMsat:  int64(r.TotalAmt),
+		TotalFees:     int64(r.TotalFees.ToSatoshis()),
+		TotalFeesMsat: int64(r.TotalFees),
+		Hops:          make([]*lnrpc.Hop, len(r.Hops)),
+	}
+
+	for i, hop := range r.Hops {
+		rpcHop := &lnrpc.Hop{
+			ChanId:           hop.ChannelID,
+			PubKey:           hop.PubKey.String(),
+			AmtToForward:     int64(hop.AmtToForward.ToSatoshis()),
+			AmtToForwardMsat: int64(hop.AmtToForward),
+			Fee:              int64(hop.Fee.ToSatoshis()),
+			FeeMsat:          int64(hop.Fee),
+			Expiry:           hop.Expiry,
+			TlvPayload:       hop.TlvPayload,
+			CustomRecords:    hop.CustomRecords,
+		}
+
+		if hop.MppRecord != nil {
+			rpcHop.MppRecord = &lnrpc.MPPRecord{
+				PaymentAddr:  hop.MppRecord.PaymentAddr[:],
+				TotalAmtMsat: int64(hop.MppRecord.TotalAmt),
+			}
+		}
+
+		rpcRoute.Hops[i] = rpcHop
+	}
+
+	return rpcRoute
+}
+
+// NodePair is a directed pair of nodes.
+type NodePair struct {
+	// From is the node that sends through the channel between the nodes.
+	From route.Vertex
+
+	// To is the node that receives through the channel between the nodes.
+	To route.Vertex
+}
+
+// String returns a human readable representation of the node pair.
+func (n NodePair) String() string {
+	return fmt.Sprintf("%v -> %v", n.From, n.To)
+}
+
+// QueryRoutesRequest holds the parameters of a route query.
+type QueryRoutesRequest struct {
+	// Target is the destination of the route.
+	Target route.Vertex
+
+	// Amt is the amount that the destination receives.
+	Amt lnwire.MilliSatoshi
+
+	// FeeLimit is the maximum total fee of the route. If zero, lnd's
+	// default limit is used.
+	FeeLimit lnwire.MilliSatoshi
+
+	// FinalCltvDelta is the CLTV delta of the final hop. If zero, lnd's
+	// default is used.
+	FinalCltvDelta int32
+
+	// CltvLimit is the maximum expiry height of the route. If zero, there
+	// is no limit.
+	CltvLimit uint32
+
+	// OutgoingChannel restricts the route to the given first channel.
+	OutgoingChannel *uint64
+
+	// LastHopPubkey restricts the route to the given last hop before the
+	// destination.
+	LastHopPubkey *route.Vertex
+
+	// IgnoredNodes are nodes that must not be part of the route.
+	IgnoredNodes []route.Vertex
+
+	// IgnoredPairs are directed node pairs that must not be part of the
+	// route.
+	IgnoredPairs []NodePair
+
+	// UseMissionControl instructs lnd to use the probabilities of past
+	// payment attempts to find the route.
+	UseMissionControl bool
+
+	// RouteHints are additional route hints to reach the destination.
+	RouteHints [][]zpay32.HopHint
+
+	// DestCustomRecords holds custom TLV records for the destination.
+	DestCustomRecords map[uint64][]byte
+}
+
+// QueryRoutesResponse holds the result of a route query.
+type QueryRoutesResponse struct {
+	// Routes holds the routes that were found.
+	Routes []*Route
+
+	// SuccessProb is the estimated success probability of the first
+	// route, if mission control was used.
+	SuccessProb float64
+}
+
+// QueryRoutes queries lnd for a route to the target of the request.
+func (s *lightningClient) QueryRoutes(ctx context.Context,
+	req *QueryRoutesRequest) (*QueryRoutesResponse, error) {
+
+	routeHints, err := marshallRouteHints(req.RouteHints)
+	if err != nil {
+		return nil, err
+	}
+
+	rpcReq := &lnrpc.QueryRoutesRequest{
+		PubKey:            req.Target.String(),
+		AmtMsat:           int64(req.Amt),
+		FinalCltvDelta:    req.FinalCltvDelta,
+		UseMissionControl: req.UseMissionControl,
+		CltvLimit:         req.CltvLimit,
+		DestCustomRecords: req.DestCustomRecords,
+		RouteHints:        routeHints,
+	}
+
+	// lnd only applies its default fee limit if none is set, a zero
+	// limit would restrict the query to routes without fees.
+	if req.FeeLimit != 0 {
+		rpcReq.FeeLimit = &lnrpc.FeeLimit{
+			Limit: &lnrpc.FeeLimit_FixedMsat{
+				FixedMsat: int64(req.FeeLimit),
+			},
+		}
+	}
+
+	if req.OutgoingChannel != nil {
+		rpcReq.OutgoingChanId = *req.OutgoingChannel
+	}
+
+	if req.LastHopPubkey != nil {
+		rpcReq.LastHopPubkey = req.LastHopPubkey[:]
+	}
+
+	for _, node := range req.IgnoredNodes {
+		node := node
+		rpcReq.IgnoredNodes = append(rpcReq.IgnoredNodes, node[:])
+	}
+
+	for _, pair := range req.IgnoredPairs {
+		pair := pair
+		rpcReq.IgnoredPairs = append(
+			rpcReq.IgnoredPairs, &lnrpc.NodePair{
+				From: pair.From[:],
+				To:   pair.To[:],
+			},
+		)
+	}
+
+	rpcCtx, cancel := context.WithTimeout(ctx, rpcTimeout)
+	defer cancel()
+
+	rpcCtx = s.adminMac.WithMacaroonAuth(rpcCtx)
+	resp, err := s.client.QueryRoutes(rpcCtx, rpcReq)
+	if err != nil {
+		return nil, err
+	}
+
+	routes := make([]*Route, len(resp.Routes))
+	for i, rpcRoute := range resp.Routes {
+		routes[i], err = unmarshallRoute(rpcRoute)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &QueryRoutesResponse{
+		Routes:      routes,
+		SuccessProb: resp.SuccessProb,
+	}, nil
+}
+
+// BuildRoute builds a route through the hops provided, using the current
+// channel policies to calculate fees and timelocks. The final hop receives the
+// amount provided. If the outgoing channel is nil, lnd picks the channel to
+// the first hop.
+func (r *routerClient) BuildRoute(ctx context.Context, amt lnwire.MilliSatoshi,
+	finalCltvDelta int32, outgoingChannel *uint64,
+	hops []route.Vertex) (*Route, error) {
+
+	if len(hops) == 0 {
+		return nil, errors.New("at least one hop required")
+	}
+
+	rpcReq := &routerrpc.BuildRouteRequest{
+		AmtMsat:        int64(amt),
+		FinalCltvDelta: finalCltvDelta,
+		HopPubkeys:     make([][]byte, len(hops)),
+	}
+	for i, hop := range hops {
+		hop := hop
+		rpcReq.HopPubkeys[i] = hop[:]
+	}
+
+	if outgoingChannel != nil {
+		rpcReq.OutgoingChanId = *outgoingChannel
+	}
+
+	rpcCtx, cancel := context.WithTimeout(ctx, rpcTimeout)
+	defer cancel()
+
+	rpcCtx = r.routerKitMac.WithMacaroonAuth(rpcCtx)
+	resp, err := r.client.BuildRoute(rpcCtx, rpcReq)
+	if err != nil {
+		return nil, err
+	}
+
+	return unmarshallRoute(resp.Route)
+}
+
+// HtlcFailure describes why an HTLC attempt failed.
+type HtlcFailure struct {
+	// Code is the failure code that was returned.
+	Code lnrpc.Failure_FailureCode
+
+	// FailureSourceIndex is the index of the node in the route that
+	// generated the failure. Index 0 is our own node, index i refers to
+	// the node of hop i-1 of the route.
+	FailureSourceIndex uint32
+
+	// HtlcAmt is the HTLC amount reported by the failing node, if any.
+	HtlcAmt lnwire.MilliSatoshi
+
+	// CltvExpiry is the expiry reported by the failing node, if any.
+	CltvExpiry uint32
+
+	// Height is the block height reported by the failing node, if any.
+	Height uint32
+}
+
+// HtlcAttempt is the result of sending an HTLC along a route.
+type HtlcAttempt struct {
+	// Status is the status of the attempt.
+	Status lnrpc.HTLCAttempt_HTLCStatus
+
+	// Route is the route the HTLC was sent along.
+	Route *Route
+
+	// AttemptTime is the time the HTLC was sent.
+	AttemptTime time.Time
+
+	// ResolveTime is the time the HTLC was settled or failed.
+	ResolveTime time.Time
+
+	// Failure describes why the attempt failed. It is only set for failed
+	// attempts.
+	Failure *HtlcFailure
+
+	// Preimage is the preimage of the payment. It is only set for
+	// successful attempts.
+	Preimage *lntypes.Preimage
+}
+
+// FailureSource returns the node that generated the failure of the attempt.
+// It returns false if the attempt didn't fail or the failure was generated by
+// our own node.
+func (h *HtlcAttempt) FailureSource() (route.Vertex, bool) {
+	if h.Failure == nil || h.Failure.FailureSourceIndex == 0 ||
+		h.Route == nil ||
+		int(h.Failure.FailureSourceIndex) > len(h.Route.Hops) {
+
+		return route.Vertex{}, false
+	}
+
+	return h.Route.Hops[h.Failure.FailureSourceIndex-1].PubKey, true
+}
+
+// unmarshallHtlcAttempt converts an rpc HTLC attempt.
+func unmarshallHtlcAttempt(rpcAttempt *lnrpc.HTLCAttempt) (*HtlcAttempt,
+	error) {
+
+	attempt := &HtlcAttempt{
+		Status:      rpcAttempt.Status,
+		AttemptTime: time.Unix(0, rpcAttempt.AttemptTimeNs),
+		ResolveTime: time.Unix(0, rpcAttempt.ResolveTimeNs),
+	}
+
+	if rpcAttempt.Route != nil {
+		var err error
+		attempt.Route, err = unmarshallRoute(rpcAttempt.Route)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if rpcAttempt.Failure != nil {
+		attempt.Failure = &HtlcFailure{
+			Code:               rpcAttempt.Failure.Code,
+			FailureSourceIndex: rpcAttempt.Failure.FailureSourceIndex,
+			HtlcAmt: lnwire.MilliSatoshi(
+				rpcAttempt.Failure.HtlcMsat,
+			),
+			CltvExpiry: rpcAttempt.Failure.CltvExpiry,
+			Height:     rpcAttempt.Failure.Height,
+		}
+	}
+
+	if len(rpcAttempt.Preimage) > 0 {
+		preimage, err := lntypes.MakePreimage(rpcAttempt.Preimage)
+		if err != nil {
+			return nil, err
+		}
+		attempt.Preimage = &preimage
+	}
+
+	return attempt, nil
+}
+
+// SendToRoute sends a single HTLC with the payment hash provided along the
+// route and waits for it to be settled or failed. A failed attempt is not an
+// error, its failure is described in the returned attempt.
+func (r *routerClient) SendToRoute(ctx context.Context, hash lntypes.Hash,
+	route *Route) (*HtlcAttempt, error) {
+
+	rpcCtx := r.routerKitMac.WithMacaroonAuth(ctx)
+	resp, err := r.client.SendToRouteV2(
+		rpcCtx, &routerrpc.SendToRouteRequest{
+			PaymentHash: hash[:],
+			Route:       route.marshall(),
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return unmarshallHtlcAttempt(resp)
+}
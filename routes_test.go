@@ -0,0 +1,165 @@
+package lndclient
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+	"github.com/lightningnetwork/lnd/lntypes"
+	"github.com/lightningnetwork/lnd/routing/route"
+	"google.golang.org/grpc"
+)
+
+// mockRouteRouter is a router that fails every route at the given source
+// index.
+type mockRouteRouter struct {
+	routerrpc.RouterClient
+
+	sendReq     *routerrpc.SendToRouteRequest
+	failureIdx  uint32
+	failureCode lnrpc.Failure_FailureCode
+}
+
+func (m *mockRouteRouter) SendToRouteV2(_ context.Context,
+	req *routerrpc.SendToRouteRequest, _ ...grpc.CallOption) (
+	*lnrpc.HTLCAttempt, error) {
+
+	m.sendReq = req
+
+	return &lnrpc.HTLCAttempt{
+		Status: lnrpc.HTLCAttempt_FAILED,
+		Route:  req.Route,
+		Failure: &lnrpc.Failure{
+			Code:               m.failureCode,
+			FailureSourceIndex: m.failureIdx,
+		},
+	}, nil
+}
+
+// TestSendToRoute tests that routes are converted to their rpc counterpart
+// without loss and that the failure source of an attempt is resolved to the
+// node of the route.
+func TestSendToRoute(t *testing.T) {
+	hop1 := route.Vertex{2, 1}
+	hop2 := route.Vertex{3, 2}
+
+	rt := &Route{
+		TotalTimeLock: 700,
+		TotalAmt:      101500,
+		TotalFees:     1500,
+		Hops: []*Hop{{
+			ChannelID:    11,
+			PubKey:       hop1,
+			AmtToForward: 100000,
+			Fee:          1500,
+			Expiry:       660,
+			TlvPayload:   true,
+		}, {
+			ChannelID:    12,
+			PubKey:       hop2,
+			AmtToForward: 100000,
+			Expiry:       660,
+			TlvPayload:   true,
+			MppRecord: &MppRecord{
+				PaymentAddr: [32]byte{9},
+				TotalAmt:    100000,
+			},
+			CustomRecords: map[uint64][]byte{65537: {1}},
+		}},
+	}
+
+	mock := &mockRouteRouter{
+		failureIdx:  2,
+		failureCode: lnrpc.Failure_INCORRECT_OR_UNKNOWN_PAYMENT_DETAILS,
+	}
+	client := newRouterClient(mock, nil, "")
+
+	attempt, err := client.SendToRoute(
+		context.Background(), lntypes.Hash{1}, rt,
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if mock.sendReq.Route.TotalAmtMsat != 101500 ||
+		mock.sendReq.Route.Hops[1].MppRecord == nil {
+
+		t.Fatalf("unexpected rpc route: %v", mock.sendReq.Route)
+	}
+
+	if !reflect.DeepEqual(attempt.Route, rt) {
+		t.Fatalf("route changed in round trip: %v", attempt.Route)
+	}
+
+	if attempt.Status != lnrpc.HTLCAttempt_FAILED ||
+		attempt.Failure.Code != mock.failureCode {
+
+		t.Fatalf("unexpected attempt: %v", attempt)
+	}
+
+	source, ok := attempt.FailureSource()
+	if !ok || source != hop2 {
+		t.Fatalf("expected failure source %v, got %v", hop2, source)
+	}
+
+	// A failure at our own node has no source in the route.
+	mock.failureIdx = 0
+	attempt, err = client.SendToRoute(
+		context.Background(), lntypes.Hash{1}, rt,
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := attempt.FailureSource(); ok {
+		t.Fatalf("expected no failure source")
+	}
+}
+
+// mockQueryRoutesLightning is a lightning client that records route queries
+// and finds no routes.
+type mockQueryRoutesLightning struct {
+	lnrpc.LightningClient
+
+	req *lnrpc.QueryRoutesRequest
+}
+
+func (m *mockQueryRoutesLightning) QueryRoutes(_ context.Context,
+	req *lnrpc.QueryRoutesRequest, _ ...grpc.CallOption) (
+	*lnrpc.QueryRoutesResponse, error) {
+
+	m.req = req
+	return &lnrpc.QueryRoutesResponse{}, nil
+}
+
+// TestQueryRoutesFeeLimit tests that a fee limit is only passed to lnd if it
+// is set, so that lnd applies its default limit otherwise.
+func TestQueryRoutesFeeLimit(t *testing.T) {
+	mock := &mockQueryRoutesLightning{}
+	client := newLightningClient(mock, nil, nil, "")
+
+	_, err := client.QueryRoutes(
+		context.Background(), &QueryRoutesRequest{Amt: 1000},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mock.req.FeeLimit != nil {
+		t.Fatalf("expected no fee limit, got %v", mock.req.FeeLimit)
+	}
+
+	_, err = client.QueryRoutes(
+		context.Background(), &QueryRoutesRequest{
+			Amt:      1000,
+			FeeLimit: 10,
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mock.req.FeeLimit.GetFixedMsat() != 10 {
+		t.Fatalf("expected fee limit of 10 msat, got %v",
+			mock.req.FeeLimit)
+	}
+}
@@ -9,7 +9,6 @@ import (
 	"github.com/lightningnetwork/lnd/input"
 	"github.com/lightningnetwork/lnd/keychain"
 	"github.com/lightningnetwork/lnd/lnrpc/signrpc"
-	"google.golang.org/grpc"
 )
 
 // SignerClient exposes sign functionality.
@@ -112,11 +111,11 @@ type signerClient struct {
 	signerMac serializedMacaroon
 }
 
-func newSignerClient(conn *grpc.ClientConn,
+func newSignerClient(client signrpc.SignerClient,
 	signerMac serializedMacaroon) *signerClient {
 
 	return &signerClient{
-		client:    signrpc.NewSignerClient(conn),
+		client:    client,
 		signerMac: signerMac,
 	}
 }
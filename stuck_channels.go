@@ -0,0 +1,289 @@
+package lndclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	// defaultStuckThreshold is the default number of blocks a channel may
+	// be pending open before it is considered stuck. lnd itself forgets
+	// pending channels that it didn't initiate after the same number of
+	// blocks.
+	defaultStuckThreshold = 2016
+
+	// estimatedBlockInterval is the time a block takes on average, which
+	// is used to estimate the height a funding transaction was published
+	// at.
+	estimatedBlockInterval = 10 * time.Minute
+)
+
+// FundingStatus is the state of the funding transaction of a pending channel
+// as seen by our wallet.
+type FundingStatus uint8
+
+const (
+	// FundingUnknown indicates that the funding transaction isn't known
+	// to our wallet, which is the case for channels that our peer funded
+	// or for transactions that were evicted from our wallet.
+	FundingUnknown FundingStatus = iota
+
+	// FundingUnconfirmed indicates that the funding transaction is known
+	// to our wallet, but not confirmed.
+	FundingUnconfirmed
+
+	// FundingConfirmed indicates that the funding transaction confirmed
+	// and the channel is waiting for the required number of
+	// confirmations.
+	FundingConfirmed
+)
+
+// String returns a string representation of the funding status.
+func (f FundingStatus) String() string {
+	switch f {
+	case FundingUnknown:
+		return "unknown"
+
+	case FundingUnconfirmed:
+		return "unconfirmed"
+
+	case FundingConfirmed:
+		return "confirmed"
+
+	default:
+		return fmt.Sprintf("unknown status: %d", f)
+	}
+}
+
+// StuckChannel is a channel that is pending open for longer than the
+// threshold of the detector.
+type StuckChannel struct {
+	// Channel is the pending channel.
+	Channel PendingChannel
+
+	// FundingStatus is the state of the funding transaction.
+	FundingStatus FundingStatus
+
+	// FundingTx is the funding transaction, if it is known to our wallet.
+	FundingTx *Transaction
+
+	// PendingSince is the height since which the channel is known to be
+	// pending. For funding transactions in our wallet it is estimated from
+	// the time the wallet first saw them.
+	PendingSince uint32
+
+	// PendingBlocks is the number of blocks the channel is pending.
+	PendingBlocks uint32
+
+	// Backup is the channel backup that was exported before the channel
+	// was abandoned.
+	Backup []byte
+
+	// Abandoned indicates that the channel was abandoned.
+	Abandoned bool
+
+	// AbandonErr holds the error of a failed attempt to abandon the
+	// channel.
+	AbandonErr error
+}
+
+// StuckChannelConfig holds the configuration of a stuck channel detector.
+type StuckChannelConfig struct {
+	// Lnd is the client used to look up and abandon channels.
+	Lnd LightningClient
+
+	// Threshold is the number of blocks a channel may be pending open
+	// before it is considered stuck. If zero, 2016 blocks are used.
+	Threshold uint32
+
+	// Abandon enables abandoning stuck channels. Channels are only
+	// abandoned after their backup was saved.
+	Abandon bool
+
+	// SaveBackup stores the backup of a channel before it is abandoned.
+	// It is required if Abandon is set. If it fails, the channel isn't
+	// abandoned.
+	SaveBackup func(channelPoint wire.OutPoint, backup []byte) error
+}
+
+// StuckChannelDetector finds channels that are pending open for longer than a
+// threshold, which happens if their funding transaction never confirms. lnd
+// doesn't report when a channel started pending, so the detector remembers the
+// height it first saw each channel at and estimates the height funding
+// transactions in our wallet were published at from their timestamp.
+type StuckChannelDetector struct {
+	cfg StuckChannelConfig
+
+	firstSeen map[wire.OutPoint]uint32
+	mtx       sync.Mutex
+}
+
+// NewStuckChannelDetector creates a new stuck channel detector.
+func NewStuckChannelDetector(cfg StuckChannelConfig) (*StuckChannelDetector,
+	error) {
+
+	if cfg.Abandon && cfg.SaveBackup == nil {
+		return nil, errors.New("backup required to abandon channels")
+	}
+
+	if cfg.Threshold == 0 {
+		cfg.Threshold = defaultStuckThreshold
+	}
+
+	return &StuckChannelDetector{
+		cfg:       cfg,
+		firstSeen: make(map[wire.OutPoint]uint32),
+	}, nil
+}
+
+// Check returns all channels that are pending open for longer than the
+// threshold and whose funding transaction didn't confirm. If abandoning is
+// enabled, the backup of every stuck channel is saved and the channel is
+// abandoned. Failures to abandon a channel are reported per channel.
+func (d *StuckChannelDetector) Check(ctx context.Context) ([]*StuckChannel,
+	error) {
+
+	info, err := d.cfg.Lnd.GetInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	height := info.BlockHeight
+
+	pending, err := d.cfg.Lnd.PendingChannels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Look up funding transactions in our wallet, including unconfirmed
+	// ones.
+	txs, err := d.cfg.Lnd.ListTransactions(ctx, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	walletTxs := make(map[string]*Transaction, len(txs))
+	for i := range txs {
+		walletTxs[txs[i].TxHash] = &txs[i]
+	}
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	var (
+		stuck      []*StuckChannel
+		stillKnown = make(map[wire.OutPoint]bool)
+	)
+	for _, channel := range pending.PendingOpen {
+		point := *channel.ChannelPoint
+		stillKnown[point] = true
+
+		since, ok := d.firstSeen[point]
+		if !ok || since > height {
+			since = height
+			d.firstSeen[point] = since
+		}
+
+		stuckChan := &StuckChannel{
+			Channel:       channel,
+			FundingStatus: FundingUnknown,
+		}
+
+		if tx, ok := walletTxs[point.Hash.String()]; ok {
+			stuckChan.FundingTx = tx
+			stuckChan.FundingStatus = FundingUnconfirmed
+			if tx.Confirmations > 0 {
+				stuckChan.FundingStatus = FundingConfirmed
+			}
+
+			published := estimateHeight(height, tx.Timestamp)
+			if published < since {
+				since = published
+			}
+		}
+
+		// Channels with a confirmed funding transaction are waiting
+		// for confirmations, but not stuck.
+		if stuckChan.FundingStatus == FundingConfirmed {
+			continue
+		}
+
+		stuckChan.PendingSince = since
+		stuckChan.PendingBlocks = height - since
+		if stuckChan.PendingBlocks < d.cfg.Threshold {
+			continue
+		}
+
+		log.Warnf("Channel %v pending for %v blocks, funding tx %v",
+			point, stuckChan.PendingBlocks, stuckChan.FundingStatus)
+
+		stuck = append(stuck, stuckChan)
+	}
+
+	// Forget channels that are no longer pending.
+	for point := range d.firstSeen {
+		if !stillKnown[point] {
+			delete(d.firstSeen, point)
+		}
+	}
+
+	if !d.cfg.Abandon {
+		return stuck, nil
+	}
+
+	for _, channel := range stuck {
+		channel.AbandonErr = d.abandon(ctx, channel)
+		if channel.AbandonErr != nil {
+			log.Errorf("Unable to abandon channel %v: %v",
+				channel.Channel.ChannelPoint,
+				channel.AbandonErr)
+			continue
+		}
+
+		channel.Abandoned = true
+		delete(d.firstSeen, *channel.Channel.ChannelPoint)
+	}
+
+	return stuck, nil
+}
+
+// abandon saves the backup of a stuck channel and abandons it.
+func (d *StuckChannelDetector) abandon(ctx context.Context,
+	channel *StuckChannel) error {
+
+	point := *channel.Channel.ChannelPoint
+
+	backup, err := d.cfg.Lnd.ChannelBackup(ctx, point)
+	if err != nil {
+		return fmt.Errorf("backup export failed: %v", err)
+	}
+	channel.Backup = backup
+
+	if err := d.cfg.SaveBackup(point, backup); err != nil {
+		return fmt.Errorf("backup save failed: %v", err)
+	}
+
+	log.Infof("Abandoning channel %v", point)
+
+	return d.cfg.Lnd.AbandonChannel(ctx, point)
+}
+
+// estimateHeight estimates the height at the time provided from the current
+// height, assuming ten minute blocks.
+func estimateHeight(height uint32, timestamp time.Time) uint32 {
+	elapsed := time.Since(timestamp)
+	if elapsed < 0 {
+		return height
+	}
+
+	blocks := uint32(elapsed / estimatedBlockInterval)
+	if blocks > height {
+		return 0
+	}
+
+	return height - blocks
+}
@@ -0,0 +1,96 @@
+package lndclient
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// TestStuckChannelDetector tests that channels are flagged once they are
+// pending beyond the threshold, that channels with confirmed funding are never
+// flagged and that stuck channels are only abandoned after their backup was
+// saved.
+func TestStuckChannelDetector(t *testing.T) {
+	unknown := wire.OutPoint{Hash: chainhash.Hash{1}}
+	unconfirmed := wire.OutPoint{Hash: chainhash.Hash{2}}
+	confirmed := wire.OutPoint{Hash: chainhash.Hash{3}}
+
+	lnd := &mockLightning{
+		info: Info{BlockHeight: 1000},
+		pending: []PendingChannel{
+			{ChannelPoint: &unknown},
+			{ChannelPoint: &unconfirmed},
+			{ChannelPoint: &confirmed},
+		},
+		txs: []Transaction{{
+			TxHash:    unconfirmed.Hash.String(),
+			Timestamp: time.Now().Add(-20 * 24 * time.Hour),
+		}, {
+			TxHash:        confirmed.Hash.String(),
+			Timestamp:     time.Now().Add(-20 * 24 * time.Hour),
+			Confirmations: 1,
+		}},
+	}
+
+	saved := make(map[wire.OutPoint][]byte)
+	detector, err := NewStuckChannelDetector(StuckChannelConfig{
+		Lnd:       lnd,
+		Threshold: 144,
+		Abandon:   true,
+		SaveBackup: func(point wire.OutPoint, backup []byte) error {
+			saved[point] = backup
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("unable to create detector: %v", err)
+	}
+
+	// The unconfirmed funding transaction was published 20 days ago, so
+	// it is flagged right away. The channel without a known funding
+	// transaction is only seen for the first time.
+	stuck, err := detector.Check(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(stuck) != 1 || *stuck[0].Channel.ChannelPoint != unconfirmed ||
+		stuck[0].FundingStatus != FundingUnconfirmed ||
+		!stuck[0].Abandoned {
+
+		t.Fatalf("unexpected stuck channels: %v", stuck)
+	}
+	if _, ok := saved[unconfirmed]; !ok {
+		t.Fatalf("backup not saved before abandoning")
+	}
+
+	// After the threshold passed, the channel without a known funding
+	// transaction is flagged too.
+	lnd.pending = lnd.pending[:1]
+	lnd.info.BlockHeight += 144
+	stuck, err = detector.Check(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(stuck) != 1 || *stuck[0].Channel.ChannelPoint != unknown ||
+		stuck[0].PendingBlocks != 144 || !stuck[0].Abandoned {
+
+		t.Fatalf("unexpected stuck channels: %v", stuck)
+	}
+
+	if len(lnd.abandoned) != 2 {
+		t.Fatalf("expected 2 abandoned channels, got %v",
+			lnd.abandoned)
+	}
+
+	// Abandoning requires a way to save backups.
+	_, err = NewStuckChannelDetector(StuckChannelConfig{
+		Lnd:     lnd,
+		Abandon: true,
+	})
+	if err == nil {
+		t.Fatalf("expected error without backup")
+	}
+}
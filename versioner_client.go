@@ -6,7 +6,6 @@ import (
 	"strings"
 
 	"github.com/lightningnetwork/lnd/lnrpc/verrpc"
-	"google.golang.org/grpc"
 )
 
 // VersionerClient exposes the version of lnd.
@@ -21,11 +20,11 @@ type versionerClient struct {
 	readonlyMac serializedMacaroon
 }
 
-func newVersionerClient(conn *grpc.ClientConn,
+func newVersionerClient(client verrpc.VersionerClient,
 	readonlyMac serializedMacaroon) *versionerClient {
 
 	return &versionerClient{
-		client:      verrpc.NewVersionerClient(conn),
+		client:      client,
 		readonlyMac: readonlyMac,
 	}
 }
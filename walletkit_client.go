@@ -17,7 +17,6 @@ import (
 	"github.com/lightningnetwork/lnd/lnrpc/walletrpc"
 	"github.com/lightningnetwork/lnd/lnwallet"
 	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
-	"google.golang.org/grpc"
 )
 
 // WalletKitClient exposes wallet functionality.
@@ -81,11 +80,11 @@ type walletKitClient struct {
 // WalletKitClient interface.
 var _ WalletKitClient = (*walletKitClient)(nil)
 
-func newWalletKitClient(conn *grpc.ClientConn,
+func newWalletKitClient(client walletrpc.WalletKitClient,
 	walletKitMac serializedMacaroon) *walletKitClient {
 
 	return &walletKitClient{
-		client:       walletrpc.NewWalletKitClient(conn),
+		client:       client,
 		walletKitMac: walletKitMac,
 	}
 }